@@ -67,6 +67,36 @@ func ParseAddress(addr string) (*Address, error) {
 	return res, nil
 }
 
+// AddressFromAccount returns an Address for the given account public address as found in
+// transactions and unsigned transaction destinations.
+func AddressFromAccount(acc *zanobase.AccountPublicAddr) *Address {
+	typ := PublicAddress
+	if acc.Flags&1 == 1 {
+		typ = PublicAuditAddress
+	}
+	return &Address{
+		Type:     typ,
+		Flags:    acc.Flags,
+		SpendKey: slices.Clone(acc.SpendKey[:]),
+		ViewKey:  slices.Clone(acc.ViewKey[:]),
+	}
+}
+
+// Account returns the account public address for addr, as used in transactions and
+// unsigned transaction destinations. The payment id is not part of it.
+func (addr *Address) Account() *zanobase.AccountPublicAddr {
+	acc := &zanobase.AccountPublicAddr{Flags: addr.Flags}
+	copy(acc.SpendKey[:], addr.SpendKey)
+	copy(acc.ViewKey[:], addr.ViewKey)
+	return acc
+}
+
+// SameKeys returns true if both addresses share the same spend and view keys, regardless
+// of their type or payment id.
+func (addr *Address) SameKeys(other *Address) bool {
+	return bytes.Equal(addr.SpendKey, other.SpendKey) && bytes.Equal(addr.ViewKey, other.ViewKey)
+}
+
 func (addr *Address) Debug() string {
 	return fmt.Sprintf("type=%s spendKey=%x viewKey=%x flags=%x paymentId=%x", addr.Type, addr.SpendKey, addr.ViewKey, addr.Flags, addr.PaymentId)
 }
@@ -0,0 +1,277 @@
+package zanolib
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+	"golang.org/x/crypto/sha3"
+)
+
+// chachaCrypt is crypto::chacha_crypt: chacha8 with a key derived from the keccak hash of
+// key and a zero iv. It both encrypts and decrypts.
+func chachaCrypt(buf, key []byte) ([]byte, error) {
+	code, err := zanocrypto.ChaCha8GenerateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return zanocrypto.ChaCha8(code, make([]byte, 8), buf)
+}
+
+// derivationHash returns the first 4 bytes of the hash of derivation, used in
+// tx_crypto_checksum to check a derivation is the right one
+func derivationHash(derivation []byte) uint32 {
+	return binary.LittleEndian.Uint32(hsum(sha3.NewLegacyKeccak256, derivation))
+}
+
+// cryptAttachments returns a copy of list where comments, payer, receiver and service
+// attachments with an encrypted body are encrypted (or decrypted) using derivation. It also
+// returns true if any entry was processed. Other entries are kept as is.
+//
+// See encrypt_attach_visitor & decrypt_attach_visitor in src/currency_core/currency_format_utils.cpp
+func cryptAttachments(list []*zanobase.Variant, derivation []byte, encrypt bool) ([]*zanobase.Variant, bool, error) {
+	res := make([]*zanobase.Variant, 0, len(list))
+	var crypted bool
+
+	for _, v := range list {
+		var val any
+		var err error
+		isCrypted := true
+		switch o := v.Value.(type) {
+		case *zanobase.TxComment:
+			c := &zanobase.TxComment{}
+			var buf []byte
+			buf, err = cryptWith(derivation, []byte(o.Comment))
+			c.Comment = string(buf)
+			val = c
+		case *zanobase.TxPayerOld:
+			c := &zanobase.TxPayerOld{}
+			c.Addr, err = cryptAddressOld(derivation, o.Addr)
+			val = c
+		case *zanobase.TxReceiverOld:
+			c := &zanobase.TxReceiverOld{}
+			c.Addr, err = cryptAddressOld(derivation, o.Addr)
+			val = c
+		case *zanobase.TxPayer:
+			c := &zanobase.TxPayer{}
+			c.Addr, err = cryptAddress(derivation, o.Addr)
+			val = c
+		case *zanobase.TxReceiver:
+			c := &zanobase.TxReceiver{}
+			c.Addr, err = cryptAddress(derivation, o.Addr)
+			val = c
+		case *zanobase.TxServiceAttachment:
+			if o.Flags&(zanobase.TxServiceAttachmentEncryptBody|zanobase.TxServiceAttachmentDeflateBody) == 0 {
+				res = append(res, v)
+				continue
+			}
+			val, err = cryptServiceAttachment(derivation, o, encrypt)
+			isCrypted = o.Flags&zanobase.TxServiceAttachmentEncryptBody != 0
+		default:
+			res = append(res, v)
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		crypted = crypted || isCrypted
+		res = append(res, &zanobase.Variant{Tag: v.Tag, Value: val})
+	}
+	return res, crypted, nil
+}
+
+func cryptWith(derivation, buf []byte) ([]byte, error) {
+	if derivation == nil {
+		return nil, errors.New("cannot encrypt attachments without a crypt address")
+	}
+	return chachaCrypt(buf, derivation)
+}
+
+func cryptAddress(derivation []byte, addr zanobase.AccountPublicAddr) (zanobase.AccountPublicAddr, error) {
+	// account_public_address is encrypted as a packed structure
+	buf, err := cryptWith(derivation, slices.Concat(addr.SpendKey[:], addr.ViewKey[:], []byte{addr.Flags}))
+	if err != nil {
+		return addr, err
+	}
+	var res zanobase.AccountPublicAddr
+	copy(res.SpendKey[:], buf[:32])
+	copy(res.ViewKey[:], buf[32:64])
+	res.Flags = buf[64]
+	return res, nil
+}
+
+func cryptAddressOld(derivation []byte, addr zanobase.AccountPublicAddrOld) (zanobase.AccountPublicAddrOld, error) {
+	buf, err := cryptWith(derivation, slices.Concat(addr.SpendKey[:], addr.ViewKey[:]))
+	if err != nil {
+		return addr, err
+	}
+	var res zanobase.AccountPublicAddrOld
+	copy(res.SpendKey[:], buf[:32])
+	copy(res.ViewKey[:], buf[32:])
+	return res, nil
+}
+
+// ErrIsolateAuditableUnsupported is returned when a service attachment has the
+// TxServiceAttachmentEncryptBodyIsolateAuditable flag. Such bodies are encrypted with a key
+// hidden from auditable wallets, which is not implemented.
+var ErrIsolateAuditableUnsupported = errors.New("service attachments isolated from auditable wallets are not supported")
+
+func cryptServiceAttachment(derivation []byte, sa *zanobase.TxServiceAttachment, encrypt bool) (*zanobase.TxServiceAttachment, error) {
+	if sa.Flags&zanobase.TxServiceAttachmentEncryptBodyIsolateAuditable != 0 {
+		return nil, ErrIsolateAuditableUnsupported
+	}
+	if sa.Flags&zanobase.TxServiceAttachmentEncryptAddProof != 0 {
+		return nil, fmt.Errorf("unsupported service attachment flags %d", sa.Flags)
+	}
+	res := *sa
+	var err error
+	if encrypt && sa.Flags&zanobase.TxServiceAttachmentDeflateBody != 0 {
+		buf := &bytes.Buffer{}
+		z := zlib.NewWriter(buf)
+		if _, err = z.Write(sa.Body); err != nil {
+			return nil, fmt.Errorf("while deflating service attachment body: %w", err)
+		}
+		if err = z.Close(); err != nil {
+			return nil, fmt.Errorf("while deflating service attachment body: %w", err)
+		}
+		res.Body = buf.Bytes()
+	}
+	if sa.Flags&zanobase.TxServiceAttachmentEncryptBody != 0 {
+		res.Body, err = cryptWith(derivation, res.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !encrypt && sa.Flags&zanobase.TxServiceAttachmentDeflateBody != 0 {
+		z, err := zlib.NewReader(bytes.NewReader(res.Body))
+		if err != nil {
+			return nil, fmt.Errorf("while inflating service attachment body: %w", err)
+		}
+		res.Body, err = io.ReadAll(io.LimitReader(z, 1024*1024))
+		if err != nil {
+			return nil, fmt.Errorf("while inflating service attachment body: %w", err)
+		}
+	}
+	return &res, nil
+}
+
+// encryptAttachments sets tx attachments based on ftp's, encrypting them and the entries
+// already in tx extra for ftp.CryptAddress with the transaction key. If anything was
+// encrypted, a tx_crypto_checksum is added so the sender can decrypt the data too, and the
+// extra_attachment_info is added to extra.
+//
+// See encrypt_attachments in src/currency_core/currency_format_utils.cpp
+func (w *Wallet) encryptAttachments(tx *zanobase.Transaction, ftp *FinalizeTxParam, txKey *edwards25519.Scalar, res *FinalizedTx) error {
+	var derivation []byte
+	if ftp.CryptAddress != nil && !ftp.CryptAddress.ViewKey.IsZero() {
+		viewKey, err := new(edwards25519.Point).SetBytes(ftp.CryptAddress.ViewKey[:])
+		if err != nil {
+			return fmt.Errorf("invalid crypt address: %w", err)
+		}
+		d, err := zanocrypto.GenerateKeyDerivation(viewKey, txKey)
+		if err != nil {
+			return err
+		}
+		derivation = d.Bytes()
+		copy(res.Derivation[:], derivation)
+	}
+
+	extra, extraCrypted, err := cryptAttachments(tx.Extra, derivation, true)
+	if err != nil {
+		return err
+	}
+	attachment, crypted, err := cryptAttachments(ftp.Attachments, derivation, true)
+	if err != nil {
+		return err
+	}
+	if crypted || extraCrypted {
+		// put the derivation, encrypted with our view key, so we can decrypt it later
+		enc, err := chachaCrypt(derivation, w.ViewPrivKey.Bytes())
+		if err != nil {
+			return err
+		}
+		chs := &zanobase.TxCryptoChecksum{DerivationHash: derivationHash(derivation)}
+		copy(chs.EncryptedKeyDerivation[:], enc)
+		if crypted {
+			attachment = append(attachment, zanobase.VariantFor(chs))
+		}
+		if extraCrypted {
+			extra = append(extra, zanobase.VariantFor(chs))
+		}
+	}
+	tx.Extra = extra
+	if len(attachment) == 0 {
+		return nil
+	}
+	tx.Attachment = attachment
+
+	// add_attachments_info_to_extra
+	buf := &bytes.Buffer{}
+	if err := zanobase.Serialize(buf, attachment); err != nil {
+		return err
+	}
+	eai := &zanobase.ExtraAttachmentInfo{
+		Size: uint64(buf.Len()),
+		Hash: zanobase.Value256(hsum(sha3.NewLegacyKeccak256, buf.Bytes())),
+		Cnt:  uint64(len(attachment)),
+	}
+	tx.Extra = append(tx.Extra, zanobase.VariantFor(eai))
+	return nil
+}
+
+// DecryptAttachments returns copies of tx extra and attachments with comments, payer,
+// receiver and service attachments decrypted. It works if w is either the recipient (using
+// the view key and the transaction public key) or the sender (using the derivation stored
+// in the tx_crypto_checksum) of tx. tx itself is not modified.
+//
+// If tx has no encrypted data, its extra and attachments are returned as is.
+func (w *Wallet) DecryptAttachments(tx *zanobase.Transaction) (extra, attachment []*zanobase.Variant, err error) {
+	var chs *zanobase.TxCryptoChecksum
+	for _, v := range slices.Concat(tx.Extra, tx.Attachment) {
+		if o, ok := v.Value.(*zanobase.TxCryptoChecksum); ok {
+			chs = o
+		}
+	}
+	if chs == nil {
+		return tx.Extra, tx.Attachment, nil
+	}
+
+	var derivation []byte
+	if txPub, err := txPubKey(tx); err == nil {
+		// as recipient: 8*v*R
+		if pub, err := new(edwards25519.Point).SetBytes(txPub[:]); err == nil {
+			d, err := zanocrypto.GenerateKeyDerivation(pub, w.ViewPrivKey)
+			if err == nil && derivationHash(d.Bytes()) == chs.DerivationHash {
+				derivation = d.Bytes()
+			}
+		}
+	}
+	if derivation == nil {
+		// as sender
+		d, err := chachaCrypt(chs.EncryptedKeyDerivation[:], w.ViewPrivKey.Bytes())
+		if err != nil {
+			return nil, nil, err
+		}
+		if derivationHash(d) != chs.DerivationHash {
+			return nil, nil, errors.New("transaction attachments are not encrypted for this wallet")
+		}
+		derivation = d
+	}
+
+	extra, _, err = cryptAttachments(tx.Extra, derivation, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	attachment, _, err = cryptAttachments(tx.Attachment, derivation, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return extra, attachment, nil
+}
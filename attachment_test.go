@@ -0,0 +1,182 @@
+package zanolib_test
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+func TestSignAttachments(t *testing.T) {
+	w := testWallet(t)
+	recipient := must(zanolib.LoadSpendSecret(make([]byte, 32), 0))
+	payer := *testAccount(w.Address())[0]
+
+	ftp := testSignableFTP(t, w)
+	ftp.CryptAddress = testAccount(recipient.Address())[0]
+	ftp.Attachments = []*zanobase.Variant{
+		zanobase.VariantFor(&zanobase.TxComment{Comment: "invoice #1234"}),
+		zanobase.VariantFor(&zanobase.TxPayer{Addr: payer}),
+	}
+
+	res, err := w.Sign(rand.Reader, ftp, nil)
+	if err != nil {
+		t.Fatalf("failed to sign: %s", err)
+	}
+	tx := res.Tx
+	if len(tx.Attachment) != 3 || tx.Attachment[2].Tag != zanobase.TagTxCryptoChecksum {
+		t.Fatalf("expected encrypted attachments followed by a checksum, got %d attachments", len(tx.Attachment))
+	}
+	if zanobase.VariantAs[*zanobase.TxComment](tx.Attachment[0]).Comment == "invoice #1234" {
+		t.Errorf("comment was not encrypted")
+	}
+	if zanobase.VariantAs[*zanobase.TxComment](ftp.Attachments[0]).Comment != "invoice #1234" {
+		t.Errorf("ftp attachments were modified")
+	}
+	var eai *zanobase.ExtraAttachmentInfo
+	for _, e := range tx.Extra {
+		if e.Tag == zanobase.TagExtraAttachmentInfo {
+			eai = e.Value.(*zanobase.ExtraAttachmentInfo)
+		}
+	}
+	if eai == nil || eai.Cnt != 3 {
+		t.Errorf("missing or invalid extra_attachment_info: %+v", eai)
+	}
+
+	// both recipient and sender can decrypt
+	for name, dw := range map[string]*zanolib.Wallet{"recipient": recipient, "sender": w} {
+		_, att, err := dw.DecryptAttachments(tx)
+		if err != nil {
+			t.Errorf("%s: failed to decrypt: %s", name, err)
+			continue
+		}
+		if c := zanobase.VariantAs[*zanobase.TxComment](att[0]).Comment; c != "invoice #1234" {
+			t.Errorf("%s: decrypted comment = %q", name, c)
+		}
+		if p := zanobase.VariantAs[*zanobase.TxPayer](att[1]).Addr; p != payer {
+			t.Errorf("%s: decrypted payer does not match", name)
+		}
+	}
+
+	other := must(zanolib.LoadSpendSecret([]byte{31: 0, 0: 0x42}, 0))
+	if _, _, err := other.DecryptAttachments(tx); err == nil {
+		t.Errorf("unrelated wallet should not be able to decrypt attachments")
+	}
+}
+
+func TestSignServiceAttachment(t *testing.T) {
+	w := testWallet(t)
+	recipient := must(zanolib.LoadSpendSecret(make([]byte, 32), 0))
+
+	ftp := testSignableFTP(t, w)
+	ftp.CryptAddress = testAccount(recipient.Address())[0]
+	sa := &zanobase.TxServiceAttachment{ServiceId: "test", Body: []byte("some service data"), Flags: zanobase.TxServiceAttachmentEncryptBody | zanobase.TxServiceAttachmentDeflateBody}
+	ftp.Attachments = []*zanobase.Variant{zanobase.VariantFor(sa)}
+
+	tx := must(w.Sign(rand.Reader, ftp, nil)).Tx
+	_, att, err := recipient.DecryptAttachments(tx)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %s", err)
+	}
+	if b := zanobase.VariantAs[*zanobase.TxServiceAttachment](att[0]).Body; string(b) != "some service data" {
+		t.Errorf("decrypted service attachment body = %q", b)
+	}
+
+	sa.Flags |= zanobase.TxServiceAttachmentEncryptBodyIsolateAuditable
+	if _, err := w.Sign(rand.Reader, ftp, nil); !errors.Is(err, zanolib.ErrIsolateAuditableUnsupported) {
+		t.Errorf("expected ErrIsolateAuditableUnsupported, got %v", err)
+	}
+}
+
+func TestSignDetails(t *testing.T) {
+	w := testWallet(t)
+	recipient := must(zanolib.LoadSpendSecret(make([]byte, 32), 0))
+
+	ftp := testSignableFTP(t, w)
+	ftp.CryptAddress = testAccount(recipient.Address())[0]
+	ftp.Extra = []*zanobase.Variant{zanobase.VariantFor(&zanobase.TxComment{Comment: "hello"})}
+	ftp.UnlockTime = 1000
+	ftp.ExpirationTime = 1700000000
+	ftp.TxOutsAttr = 1
+
+	res := must(w.Sign(rand.Reader, ftp, nil))
+	tx := res.Tx
+
+	found := make(map[zanobase.Tag]*zanobase.Variant)
+	for _, e := range tx.Extra {
+		found[e.Tag] = e
+	}
+	if e := found[zanobase.TagUnlockTime]; e == nil || e.Value.(*zanobase.EtcTxDetailsUnlockTime).V != 1000 {
+		t.Errorf("missing unlock time")
+	}
+	if e := found[zanobase.TagExpirationTime]; e == nil || e.Value.(*zanobase.EtcTxDetailsExpirationTime).V != 1700000000 {
+		t.Errorf("missing expiration time")
+	}
+	if found[zanobase.TagTxCryptoChecksum] == nil {
+		t.Errorf("missing crypto checksum in extra")
+	}
+	if c := found[zanobase.TagTxComment]; c == nil || c.Value.(*zanobase.TxComment).Comment == "hello" {
+		t.Errorf("extra comment missing or not encrypted")
+	}
+	for _, v := range tx.Vout {
+		if v.Value.(*zanobase.TxOutZarcanium).MixAttr != 1 {
+			t.Errorf("outputs attribute not applied")
+		}
+	}
+
+	extra, _, err := recipient.DecryptAttachments(tx)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %s", err)
+	}
+	if zanobase.VariantAs[*zanobase.TxComment](extra[0]).Comment != "hello" {
+		t.Errorf("failed to decrypt extra comment")
+	}
+
+	if size := must(ftp.EstimateSize()); size != len(must(tx.Bytes())) {
+		t.Errorf("estimated size %d, actual size %d", size, len(must(tx.Bytes())))
+	}
+}
+
+func TestSignUnlockTimePerOutput(t *testing.T) {
+	w := testWallet(t)
+
+	ftp := testSignableFTP(t, w)
+	ftp.PreparedDestinations[1].UnlockTime = 1700000000
+	tx := must(w.Sign(rand.Reader, ftp, nil)).Tx
+
+	var ut2 *zanobase.EtcTxDetailsUnlockTime2
+	for _, e := range tx.Extra {
+		if e.Tag == zanobase.TagUnlockTime {
+			t.Errorf("unexpected transaction unlock time")
+		}
+		if e.Tag == zanobase.TagUnlockTime2 {
+			ut2 = e.Value.(*zanobase.EtcTxDetailsUnlockTime2)
+		}
+	}
+	if ut2 == nil || len(ut2.UnlockTimeArray) != len(tx.Vout) || ut2.UnlockTimeArray[0] != 0 || ut2.UnlockTimeArray[1] != 1700000000 {
+		t.Errorf("invalid per output unlock times: %v", ut2)
+	}
+	if size := must(ftp.EstimateSize()); size != len(must(tx.Bytes())) {
+		t.Errorf("estimated size %d, actual size %d", size, len(must(tx.Bytes())))
+	}
+
+	ftp.UnlockTime = 1000
+	if _, err := w.Sign(rand.Reader, ftp, nil); err == nil {
+		t.Errorf("transaction and destination unlock times should not be combined")
+	}
+}
+
+func TestSignDefaultAssetId(t *testing.T) {
+	w := testWallet(t)
+
+	ftp := testSignableFTP(t, w)
+	for _, dst := range ftp.PreparedDestinations {
+		dst.AssetId = nil
+	}
+	tx := must(w.Sign(rand.Reader, ftp, nil)).Tx
+	if total := must(w.ScanTransaction(tx)).Total(zanolib.NativeCoinAssetId); total != 390 {
+		t.Errorf("expected 390 native coins back, got %d", total)
+	}
+}
@@ -0,0 +1,190 @@
+package zanolib
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+// WatchOnly returns true if w has no spend secret key, as is the case for wallets loaded
+// from a tracking seed
+func (w *Wallet) WatchOnly() bool {
+	return w.SpendPrivKey == nil
+}
+
+// TrackingSeed returns the tracking seed of an auditable wallet, in the same format as
+// simplewallet's tracking_seed command: "<audit address>:<view secret key>[:<creation
+// timestamp>]". The creation time is omitted if zero. Anyone holding the tracking seed
+// can see all incoming and outgoing transfers of the wallet, but cannot spend.
+func (w *Wallet) TrackingSeed(created time.Time) (string, error) {
+	if w.Flags&1 == 0 {
+		return "", errors.New("tracking seeds are only available for auditable wallets")
+	}
+	res := w.Address().String() + ":" + hex.EncodeToString(w.ViewPrivKey.Bytes())
+	if !created.IsZero() {
+		res += ":" + strconv.FormatInt(created.Unix(), 10)
+	}
+	return res, nil
+}
+
+// LoadTrackingSeed returns a watch-only Wallet for the given tracking seed, as well as the
+// wallet creation time if found in the seed. The returned wallet can scan transactions but
+// cannot sign.
+func LoadTrackingSeed(seed string) (*Wallet, time.Time, error) {
+	parts := strings.Split(strings.TrimSpace(seed), ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return nil, time.Time{}, errors.New("invalid tracking seed")
+	}
+	addr, err := ParseAddress(parts[0])
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid tracking seed address: %w", err)
+	}
+	if !addr.Type.Auditable() || addr.Flags&1 == 0 {
+		return nil, time.Time{}, errors.New("tracking seed address is not an audit address")
+	}
+	buf, err := hex.DecodeString(parts[1])
+	if err != nil || len(buf) != 32 {
+		return nil, time.Time{}, errors.New("invalid tracking seed view key")
+	}
+	viewPriv, err := new(edwards25519.Scalar).SetCanonicalBytes(buf)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid tracking seed view key: %w", err)
+	}
+	spendPub, err := new(edwards25519.Point).SetBytes(addr.SpendKey)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	res := &Wallet{
+		SpendPubKey: spendPub,
+		ViewPrivKey: viewPriv,
+		ViewPubKey:  zanocrypto.PubFromPriv(viewPriv),
+		Flags:       addr.Flags,
+	}
+	if !addr.SameKeys(res.Address()) {
+		return nil, time.Time{}, errors.New("tracking seed view key does not match address")
+	}
+	var created time.Time
+	if len(parts) == 3 {
+		ts, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("invalid tracking seed timestamp: %w", err)
+		}
+		if ts != 0 {
+			created = time.Unix(ts, 0)
+		}
+	}
+	return res, created, nil
+}
+
+// TrackedOutput is an output received by a wallet followed by a Tracker
+type TrackedOutput struct {
+	*ReceivedOutput
+	TxId        zanobase.Value256  `json:"txid"`
+	GlobalIndex uint64             `json:"global_index"`
+	SpentIn     *zanobase.Value256 `json:"spent_in,omitempty"` // id of the spending transaction
+}
+
+// Tracker follows the incoming and outgoing transfers of a wallet, typically a watch-only
+// auditable wallet loaded with LoadTrackingSeed.
+//
+// Outputs sent to auditable addresses are marked as not mixable, so the transactions
+// spending them must reference them alone. This allows the tracker to detect spends
+// without knowing the key images, which require the spend secret key. Spends of outputs
+// of non auditable wallets will not be detected.
+type Tracker struct {
+	Wallet  *Wallet
+	Outputs []*TrackedOutput
+}
+
+// NewTracker returns a Tracker for w
+func NewTracker(w *Wallet) *Tracker {
+	return &Tracker{Wallet: w}
+}
+
+// Process scans tx, in blockchain order, and returns the outputs received and spent by it.
+// globalIndexes are the global output indexes of tx as returned by the daemon (see
+// zanorpc Client.GetOutputIndexes), they are needed to match later spends.
+func (t *Tracker) Process(tx *zanobase.Transaction, globalIndexes []uint64) (received, spent []*TrackedOutput, err error) {
+	txId, err := tx.Hash()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, vin := range tx.Vin {
+		in, ok := vin.Value.(*zanobase.TxInZcInput)
+		if !ok || len(in.KeyOffsets) != 1 {
+			continue
+		}
+		out := t.find(in.KeyOffsets[0])
+		if out == nil || out.SpentIn != nil {
+			continue
+		}
+		out.SpentIn = (*zanobase.Value256)(txId)
+		spent = append(spent, out)
+	}
+
+	scan, err := t.Wallet.ScanTransaction(tx)
+	if err != nil {
+		if errors.Is(err, errNoTxPubKey) {
+			// nothing can be sent to the wallet without a public key
+			return nil, spent, nil
+		}
+		return nil, spent, err
+	}
+	for _, out := range scan.Outputs {
+		if out.Index >= uint64(len(globalIndexes)) {
+			return nil, spent, errors.New("missing global output indexes")
+		}
+		if t.known(scan.TxId, out.Index) {
+			continue
+		}
+		tracked := &TrackedOutput{ReceivedOutput: out, TxId: scan.TxId, GlobalIndex: globalIndexes[out.Index]}
+		t.Outputs = append(t.Outputs, tracked)
+		received = append(received, tracked)
+	}
+	return received, spent, nil
+}
+
+// find returns the tracked output referenced by an input key offset
+func (t *Tracker) find(ref *zanobase.Payload) *TrackedOutput {
+	for _, out := range t.Outputs {
+		switch v := ref.Value.(type) {
+		case uint64:
+			if out.GlobalIndex == v {
+				return out
+			}
+		case *zanobase.RefById:
+			if out.TxId == v.Hash && out.Index == uint64(v.N) {
+				return out
+			}
+		}
+	}
+	return nil
+}
+
+func (t *Tracker) known(txId zanobase.Value256, index uint64) bool {
+	for _, out := range t.Outputs {
+		if out.TxId == txId && out.Index == index {
+			return true
+		}
+	}
+	return false
+}
+
+// Balance returns the total amount of unspent outputs for the given asset id
+func (t *Tracker) Balance(assetId string) uint64 {
+	var res uint64
+	for _, out := range t.Outputs {
+		if out.SpentIn == nil && out.AssetId == assetId {
+			res += out.Amount
+		}
+	}
+	return res
+}
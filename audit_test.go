@@ -0,0 +1,109 @@
+package zanolib_test
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+func TestTrackingSeed(t *testing.T) {
+	secret := make([]byte, 32)
+	secret[0] = 9
+	w := must(zanolib.LoadSpendSecret(secret, 1))
+	created := time.Unix(1700000000, 0)
+
+	seed := must(w.TrackingSeed(created))
+	watcher, ts, err := zanolib.LoadTrackingSeed(seed)
+	if err != nil {
+		t.Fatalf("failed to load tracking seed: %s", err)
+	}
+	if !ts.Equal(created) {
+		t.Errorf("creation time = %s, expected %s", ts, created)
+	}
+	if watcher.Address().String() != w.Address().String() || watcher.Address().Type != zanolib.PublicAuditAddress {
+		t.Errorf("unexpected watcher address %s", watcher.Address())
+	}
+	if !watcher.WatchOnly() || w.WatchOnly() {
+		t.Errorf("invalid watch-only status")
+	}
+	if _, err := watcher.Sign(rand.Reader, testSignableFTP(t, watcher), nil); err == nil {
+		t.Errorf("watch-only wallet signed a transaction")
+	}
+
+	if _, ts, err := zanolib.LoadTrackingSeed(must(w.TrackingSeed(time.Time{}))); err != nil || !ts.IsZero() {
+		t.Errorf("failed to load tracking seed without timestamp: %v", err)
+	}
+	if _, err := testWallet(t).TrackingSeed(created); err == nil {
+		t.Errorf("tracking seed created for a non auditable wallet")
+	}
+	other := must(zanolib.LoadSpendSecret(make([]byte, 32), 1))
+	bad := w.Address().String() + ":" + hex.EncodeToString(other.ViewPrivKey.Bytes())
+	if _, _, err := zanolib.LoadTrackingSeed(bad); err == nil {
+		t.Errorf("tracking seed with mismatched view key accepted")
+	}
+}
+
+func TestTracker(t *testing.T) {
+	secret := make([]byte, 32)
+	secret[0] = 9
+	watcher, _, err := zanolib.LoadTrackingSeed(must(must(zanolib.LoadSpendSecret(secret, 1)).TrackingSeed(time.Time{})))
+	if err != nil {
+		t.Fatalf("failed to load tracking seed: %s", err)
+	}
+	tracker := zanolib.NewTracker(watcher)
+
+	w := testWallet(t)
+	ftp := testSignableFTP(t, w)
+	ftp.PreparedDestinations[0].Addr = testAccount(watcher.Address())
+	tx := must(w.Sign(rand.Reader, ftp, nil)).Tx
+	if mix := tx.Vout[0].Value.(*zanobase.TxOutZarcanium).MixAttr; mix != 1 {
+		t.Errorf("output to audit address has mix attr %d", mix)
+	}
+
+	received, spent, err := tracker.Process(tx, []uint64{100, 101})
+	if err != nil {
+		t.Fatalf("failed to process: %s", err)
+	}
+	if len(received) != 1 || len(spent) != 0 || received[0].Amount != 600 || received[0].GlobalIndex != 100 {
+		t.Fatalf("unexpected received outputs %+v", received)
+	}
+	// processing the same transaction twice has no effect
+	if received, _, _ := tracker.Process(tx, []uint64{100, 101}); len(received) != 0 {
+		t.Errorf("transaction was processed twice")
+	}
+	if b := tracker.Balance(zanolib.NativeCoinAssetId); b != 600 {
+		t.Errorf("balance = %d", b)
+	}
+
+	// a spend references the output alone, by global index or by id
+	txId := must(tx.Hash())
+	refs := []*zanobase.Variant{
+		zanobase.VariantFor(uint64(100)),
+		zanobase.VariantFor(&zanobase.RefById{Hash: zanobase.Value256(txId), N: 0}),
+	}
+	for _, ref := range refs {
+		tracker.Outputs[0].SpentIn = nil
+		spend := &zanobase.Transaction{
+			Version: 2,
+			Vin: []*zanobase.Variant{zanobase.VariantFor(&zanobase.TxInZcInput{
+				KeyOffsets: []*zanobase.Variant{ref},
+				KeyImage:   &zanobase.Point{watcher.SpendPubKey},
+			})},
+		}
+		_, spent, err := tracker.Process(spend, nil)
+		if err != nil {
+			t.Fatalf("failed to process spend: %s", err)
+		}
+		spendId := must(spend.Hash())
+		if len(spent) != 1 || spent[0].SpentIn == nil || *spent[0].SpentIn != zanobase.Value256(spendId) {
+			t.Errorf("spend not detected: %+v", spent)
+		}
+		if b := tracker.Balance(zanolib.NativeCoinAssetId); b != 0 {
+			t.Errorf("balance after spend = %d", b)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ModChain/zanolib"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// keystore is a spend secret encrypted with a password, stored as json
+type keystore struct {
+	Version    int    `json:"version"`
+	Address    string `json:"address"` // also used as additional data
+	Flags      uint8  `json:"flags"`
+	N          int    `json:"scrypt_n"`
+	R          int    `json:"scrypt_r"`
+	P          int    `json:"scrypt_p"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+const (
+	keystoreVersion = 1
+	keystoreR       = 8
+	keystoreP       = 1
+
+	// limits on the scrypt parameters read from a keystore, so a crafted file cannot make
+	// key derivation use unbounded memory or time
+	keystoreMaxMem = 1 << 30 // 128 * N * R bytes
+	keystoreMaxP   = 16
+)
+
+// keystoreN is the scrypt cost parameter used for new keystores
+var keystoreN = 1 << 17
+
+func (k *keystore) key(password []byte, salt []byte) ([]byte, error) {
+	if k.N <= 1 || k.R <= 0 || k.P <= 0 || k.P > keystoreMaxP || k.R > keystoreMaxMem/128/k.N {
+		return nil, fmt.Errorf("invalid keystore scrypt parameters N=%d R=%d P=%d", k.N, k.R, k.P)
+	}
+	return scrypt.Key(password, salt, k.N, k.R, k.P, chacha20poly1305.KeySize)
+}
+
+// newKeystore encrypts the spend secret of w with password
+func newKeystore(w *zanolib.Wallet, password []byte) (*keystore, error) {
+	res := &keystore{
+		Version: keystoreVersion,
+		Address: w.Address().String(),
+		Flags:   w.Flags,
+		N:       keystoreN,
+		R:       keystoreR,
+		P:       keystoreP,
+	}
+	salt := make([]byte, 32)
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key, err := res.key(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	res.Salt = hex.EncodeToString(salt)
+	res.Nonce = hex.EncodeToString(nonce)
+	res.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonce, w.SpendPrivKey.Bytes(), []byte(res.Address)))
+	return res, nil
+}
+
+// loadKeystore reads a keystore file and decrypts it with password
+func loadKeystore(fn string, password []byte) (*zanolib.Wallet, error) {
+	buf, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var k keystore
+	if err := json.Unmarshal(buf, &k); err != nil {
+		return nil, fmt.Errorf("invalid keystore: %w", err)
+	}
+	if k.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", k.Version)
+	}
+	salt, err := hex.DecodeString(k.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore salt: %w", err)
+	}
+	nonce, err := hex.DecodeString(k.Nonce)
+	if err != nil || len(nonce) != chacha20poly1305.NonceSizeX {
+		return nil, errors.New("invalid keystore nonce")
+	}
+	ciphertext, err := hex.DecodeString(k.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext: %w", err)
+	}
+	key, err := k.key(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := aead.Open(nil, nonce, ciphertext, []byte(k.Address))
+	if err != nil {
+		return nil, errors.New("failed to decrypt keystore: invalid password")
+	}
+	w, err := zanolib.LoadSpendSecret(secret, k.Flags)
+	if err != nil {
+		return nil, err
+	}
+	if w.Address().String() != k.Address {
+		return nil, errors.New("keystore address does not match its secret")
+	}
+	return w, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileLedger is a zanolib.SpendLedger stored as json in a file, so daily limits apply
+// across runs. Records older than 24 hours are discarded when new records are added.
+type fileLedger struct {
+	fn string
+}
+
+type ledgerRecord struct {
+	AssetId string    `json:"asset_id"`
+	Amount  uint64    `json:"amount"`
+	At      time.Time `json:"at"`
+}
+
+func (l *fileLedger) load() ([]*ledgerRecord, error) {
+	buf, err := os.ReadFile(l.fn)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var res []*ledgerRecord
+	if err := json.Unmarshal(buf, &res); err != nil {
+		return nil, errors.New("invalid ledger file")
+	}
+	return res, nil
+}
+
+func (l *fileLedger) Spent(assetId string, since time.Time) (uint64, error) {
+	records, err := l.load()
+	if err != nil {
+		return 0, err
+	}
+	var res uint64
+	for _, r := range records {
+		if r.AssetId == assetId && r.At.After(since) {
+			res += r.Amount
+		}
+	}
+	return res, nil
+}
+
+func (l *fileLedger) Record(assetId string, amount uint64, at time.Time) error {
+	records, err := l.load()
+	if err != nil {
+		return err
+	}
+	limit := at.Add(-24 * time.Hour)
+	keep := records[:0]
+	for _, r := range records {
+		if r.At.After(limit) {
+			keep = append(keep, r)
+		}
+	}
+	keep = append(keep, &ledgerRecord{AssetId: assetId, Amount: amount, At: at})
+	buf, err := json.MarshalIndent(keep, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so an interrupted write does not lose records
+	tmp, err := os.CreateTemp(filepath.Dir(l.fn), ".ledger-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.fn)
+}
@@ -0,0 +1,413 @@
+// Command zanosign signs unsigned transactions produced by a view-only simplewallet,
+// typically on an offline machine.
+//
+// Usage:
+//
+//	zanosign [flags] show <unsigned file>
+//	zanosign [flags] sign <unsigned file> [<signed file>]
+//	zanosign [flags] decode <signed file>
+//	zanosign [flags] address
+//	zanosign [flags] keystore <keystore file>
+//
+// The wallet is loaded from a spend secret (-secret, hex or raw 32 bytes) or from a
+// keystore created with the keystore command (-keystore). Seed phrases are not
+// supported. Unless a policy file is given with -policy, the transaction summary is
+// displayed and confirmation is asked before signing. Policies with a daily limit need a
+// ledger file (-ledger), where the amounts sent by signed transactions are recorded.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ModChain/zanolib"
+)
+
+type cli struct {
+	in  *bufio.Reader
+	out io.Writer
+	tty *os.File // stdin if it is a terminal
+
+	secret       string
+	keystore     string
+	passwordFile string
+	auditable    bool
+	policy       string
+	ledger       string
+	yes          bool
+	json         bool
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "zanosign: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	c := &cli{in: bufio.NewReader(stdin), out: stdout}
+	if f, ok := stdin.(*os.File); ok && isTerminal(f) {
+		c.tty = f
+	}
+	fs := flag.NewFlagSet("zanosign", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	fs.StringVar(&c.secret, "secret", "", "file containing the spend secret, as hex or raw bytes")
+	fs.StringVar(&c.keystore, "keystore", "", "keystore file containing the encrypted spend secret")
+	fs.StringVar(&c.passwordFile, "password-file", "", "file containing the keystore password, asked on stdin if not set")
+	fs.BoolVar(&c.auditable, "auditable", false, "the secret belongs to an auditable wallet")
+	fs.StringVar(&c.policy, "policy", "", "json policy file to apply instead of asking for confirmation")
+	fs.StringVar(&c.ledger, "ledger", "", "file recording amounts sent, required by policies with a daily limit")
+	fs.BoolVar(&c.yes, "yes", false, "sign without asking for confirmation")
+	fs.BoolVar(&c.json, "json", false, "output decoded data as json")
+	fs.Usage = func() {
+		fmt.Fprintf(stdout, "usage: zanosign [flags] show|sign|decode|address|keystore [args]\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		return errors.New("missing command")
+	}
+
+	switch args[0] {
+	case "show":
+		if len(args) != 2 {
+			return errors.New("usage: zanosign show <unsigned file>")
+		}
+		return c.show(args[1])
+	case "sign":
+		if len(args) != 2 && len(args) != 3 {
+			return errors.New("usage: zanosign sign <unsigned file> [<signed file>]")
+		}
+		out := signedName(args[1])
+		if len(args) == 3 {
+			out = args[2]
+		}
+		return c.sign(args[1], out)
+	case "decode":
+		if len(args) != 2 {
+			return errors.New("usage: zanosign decode <signed file>")
+		}
+		return c.decode(args[1])
+	case "address":
+		w, err := c.wallet()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.out, w.Address())
+		return nil
+	case "keystore":
+		if len(args) != 2 {
+			return errors.New("usage: zanosign -secret <secret file> keystore <keystore file>")
+		}
+		return c.createKeystore(args[1])
+	}
+	return fmt.Errorf("unknown command %s", args[0])
+}
+
+// signedName returns the default name of the signed file for an unsigned file
+func signedName(fn string) string {
+	if strings.Contains(fn, "unsigned") {
+		return strings.Replace(fn, "unsigned", "signed", 1)
+	}
+	return fn + ".signed"
+}
+
+// wallet loads the wallet from the secret or keystore file
+func (c *cli) wallet() (*zanolib.Wallet, error) {
+	var flags uint8
+	if c.auditable {
+		flags = 1
+	}
+	switch {
+	case c.secret != "" && c.keystore != "":
+		return nil, errors.New("-secret and -keystore cannot be used together")
+	case c.secret != "":
+		secret, err := readSecret(c.secret)
+		if err != nil {
+			return nil, err
+		}
+		return zanolib.LoadSpendSecret(secret, flags)
+	case c.keystore != "":
+		password, err := c.password("Keystore password: ")
+		if err != nil {
+			return nil, err
+		}
+		return loadKeystore(c.keystore, password)
+	}
+	return nil, errors.New("either -secret or -keystore is required")
+}
+
+// readSecret reads a spend secret file, as hex or raw bytes
+func readSecret(fn string) ([]byte, error) {
+	buf, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) == 32 {
+		return buf, nil
+	}
+	secret, err := hex.DecodeString(string(bytes.TrimSpace(buf)))
+	if err != nil || len(secret) != 32 {
+		return nil, errors.New("secret file must contain 32 bytes, raw or as hex")
+	}
+	return secret, nil
+}
+
+func (c *cli) password(prompt string) ([]byte, error) {
+	if c.passwordFile != "" {
+		buf, err := os.ReadFile(c.passwordFile)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.TrimRight(buf, "\r\n"), nil
+	}
+	fmt.Fprint(c.out, prompt)
+	if c.tty != nil {
+		restore, err := disableEcho(c.tty)
+		if err != nil {
+			return nil, err
+		}
+		defer fmt.Fprintln(c.out)
+		defer restore()
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(line), nil
+}
+
+func (c *cli) readLine() (string, error) {
+	line, err := c.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (c *cli) parseFTP(w *zanolib.Wallet, fn string) (*zanolib.FinalizeTxParam, error) {
+	buf, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	ftp, err := w.ParseFTP(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse unsigned transaction: %w", err)
+	}
+	return ftp, nil
+}
+
+func (c *cli) show(fn string) error {
+	w, err := c.wallet()
+	if err != nil {
+		return err
+	}
+	ftp, err := c.parseFTP(w, fn)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(ftp)
+	}
+	fmt.Fprintf(c.out, "Unsigned transaction\n%s", ftp.Summary(w))
+	if c.policy != "" {
+		p, err := c.loadPolicy()
+		if err != nil {
+			return err
+		}
+		decision, err := p.Evaluate(w, ftp, time.Now())
+		if err != nil {
+			return err
+		}
+		c.printDecision(decision)
+	}
+	return nil
+}
+
+func (c *cli) sign(fn, out string) error {
+	w, err := c.wallet()
+	if err != nil {
+		return err
+	}
+	ftp, err := c.parseFTP(w, fn)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Unsigned transaction\n%s", ftp.Summary(w))
+
+	var policy *zanolib.Policy
+	switch {
+	case c.policy != "":
+		policy, err = c.loadPolicy()
+		if err != nil {
+			return err
+		}
+		decision, err := policy.Evaluate(w, ftp, time.Now())
+		if err != nil {
+			return err
+		}
+		c.printDecision(decision)
+		if !decision.Allowed {
+			return errors.New("transaction denied by policy")
+		}
+	case !c.yes:
+		fmt.Fprint(c.out, "Sign this transaction? [y/N] ")
+		answer, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("aborted")
+		}
+	}
+
+	finalized, err := w.Sign(rand.Reader, ftp, nil)
+	if err != nil {
+		return fmt.Errorf("failed to sign: %w", err)
+	}
+	buf, err := w.Encrypt(finalized)
+	if err != nil {
+		return err
+	}
+	txId, err := finalized.Tx.Hash()
+	if err != nil {
+		return err
+	}
+	if policy != nil {
+		// recorded before writing, so a failure never leaves a signed transaction unaccounted for
+		if err := policy.Record(w, ftp, time.Now()); err != nil {
+			return fmt.Errorf("failed to record in ledger: %w", err)
+		}
+	}
+	if err := os.WriteFile(out, buf, 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Signed transaction %x written to %s\n", txId, out)
+	return nil
+}
+
+func (c *cli) decode(fn string) error {
+	w, err := c.wallet()
+	if err != nil {
+		return err
+	}
+	buf, err := os.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	finalized, err := w.ParseFinalized(buf)
+	if err != nil {
+		return fmt.Errorf("failed to parse signed transaction: %w", err)
+	}
+	if c.json {
+		return c.printJSON(finalized)
+	}
+	txBytes, err := finalized.Tx.Bytes()
+	if err != nil {
+		return err
+	}
+	txId, err := finalized.Tx.Hash()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Transaction id: %x\nSize: %d bytes\n", txId, len(txBytes))
+	if fee, ok := finalized.Tx.GetFee(); ok {
+		fmt.Fprintf(c.out, "Fee in transaction: %d\n", fee)
+	}
+	if finalized.FTP != nil {
+		fmt.Fprint(c.out, finalized.FTP.Summary(w))
+	}
+	return nil
+}
+
+func (c *cli) createKeystore(fn string) error {
+	if _, err := os.Stat(fn); err == nil {
+		return fmt.Errorf("%s already exists", fn)
+	}
+	if c.secret == "" {
+		return errors.New("-secret is required to create a keystore")
+	}
+	w, err := c.wallet()
+	if err != nil {
+		return err
+	}
+	password, err := c.password("New keystore password: ")
+	if err != nil {
+		return err
+	}
+	if c.passwordFile == "" {
+		confirm, err := c.password("Confirm password: ")
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(password, confirm) {
+			return errors.New("passwords do not match")
+		}
+	}
+	if len(password) == 0 {
+		return errors.New("empty password")
+	}
+	k, err := newKeystore(w, password)
+	if err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(fn, buf, 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Keystore for %s written to %s\n", k.Address, fn)
+	return nil
+}
+
+// loadPolicy loads the policy file, with the ledger file if one is given
+func (c *cli) loadPolicy() (*zanolib.Policy, error) {
+	buf, err := os.ReadFile(c.policy)
+	if err != nil {
+		return nil, err
+	}
+	p := new(zanolib.Policy)
+	if err := json.Unmarshal(buf, p); err != nil {
+		return nil, fmt.Errorf("invalid policy file: %w", err)
+	}
+	if c.ledger != "" {
+		p.Ledger = &fileLedger{fn: c.ledger}
+	} else if len(p.DailyLimit) > 0 {
+		return nil, errors.New("policy has a daily limit, a ledger file must be given with -ledger")
+	}
+	return p, nil
+}
+
+func (c *cli) printDecision(d *zanolib.PolicyDecision) {
+	if d.Allowed {
+		fmt.Fprintln(c.out, "Policy: allowed")
+		return
+	}
+	fmt.Fprintln(c.out, "Policy: denied")
+	for _, r := range d.Reasons {
+		fmt.Fprintf(c.out, "  %s\n", r)
+	}
+}
+
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+// testUnsigned returns an encrypted unsigned transaction spending an output of w
+func testUnsigned(t *testing.T, w *zanolib.Wallet, amount uint64) []byte {
+	txKey := new(edwards25519.Point).ScalarBaseMult(zanocrypto.ScalarInt(7))
+	derivation, err := zanocrypto.GenerateKeyDerivation(txKey, w.ViewPrivKey)
+	if err != nil {
+		t.Fatalf("failed to derive: %s", err)
+	}
+	stealth, err := zanocrypto.DerivePublicKey(derivation.Bytes(), 0, w.SpendPubKey)
+	if err != nil {
+		t.Fatalf("failed to derive: %s", err)
+	}
+	blindedAssetId := new(edwards25519.Point).Add(zanocrypto.NativeCoinAssetIdPt, new(edwards25519.Point).ScalarMult(zanocrypto.ScalarInt(6), zanocrypto.C_point_X))
+	commitment := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(zanocrypto.ScalarInt(1000), blindedAssetId, zanocrypto.ScalarInt(5))
+	div8 := func(p *edwards25519.Point) zanobase.Value256 {
+		return zanobase.Value256(new(edwards25519.Point).ScalarMult(zanocrypto.Sc1div8, p).Bytes())
+	}
+	other, err := zanolib.ParseAddress("ZxD5aoLDPTdcaRx4uCpyW4XiLfEXejepAVz8cSY2fwHNEiJNu6NmpBBDLGTJzCsUvn3acCVDVDPMV8yQXdPooAp338Se7AxeH")
+	if err != nil {
+		t.Fatalf("failed to parse address: %s", err)
+	}
+
+	b := &zanolib.TxBuilder{
+		Wallet: w,
+		Outputs: []*zanolib.OwnedOutput{{
+			GlobalIndex:         1234,
+			TxPubKey:            zanobase.Value256(txKey.Bytes()),
+			Amount:              1000,
+			AssetId:             zanolib.NativeCoinAssetId,
+			StealthAddress:      zanobase.Value256(stealth.Bytes()),
+			ConcealingPoint:     div8(txKey),
+			AmountCommitment:    div8(commitment),
+			BlindedAssetId:      div8(blindedAssetId),
+			AmountBlindingMask:  &zanobase.Scalar{zanocrypto.ScalarInt(5)},
+			AssetIdBlindingMask: &zanobase.Scalar{zanocrypto.ScalarInt(6)},
+		}},
+		RingSize:     1,
+		Destinations: []*zanolib.Destination{{Address: other, Amount: amount}},
+		Fee:          10,
+	}
+	ftp, err := b.Build()
+	if err != nil {
+		t.Fatalf("failed to build: %s", err)
+	}
+	buf, err := w.Encrypt(ftp)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %s", err)
+	}
+	return buf
+}
+
+func writeFile(t *testing.T, fn string, data []byte) string {
+	if err := os.WriteFile(fn, data, 0600); err != nil {
+		t.Fatalf("failed to write %s: %s", fn, err)
+	}
+	return fn
+}
+
+func TestSign(t *testing.T) {
+	dir := t.TempDir()
+	secret := make([]byte, 32)
+	secret[0] = 1
+	w, err := zanolib.LoadSpendSecret(secret, 0)
+	if err != nil {
+		t.Fatalf("failed to load wallet: %s", err)
+	}
+	secretFile := writeFile(t, filepath.Join(dir, "secret"), []byte(hex.EncodeToString(secret)+"\n"))
+	unsigned := writeFile(t, filepath.Join(dir, "zano_tx_unsigned"), testUnsigned(t, w, 600))
+
+	out := &bytes.Buffer{}
+	if err := run([]string{"-secret", secretFile, "show", unsigned}, nil, out); err != nil {
+		t.Fatalf("show failed: %s", err)
+	}
+	if !strings.Contains(out.String(), "ZxD5aoLDPTdc") || !strings.Contains(out.String(), "[change]") {
+		t.Errorf("unexpected summary:\n%s", out)
+	}
+
+	// refusing confirmation does not sign
+	out.Reset()
+	if err := run([]string{"-secret", secretFile, "sign", unsigned}, strings.NewReader("n\n"), out); err == nil {
+		t.Errorf("sign should abort without confirmation")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "zano_tx_signed")); err == nil {
+		t.Errorf("signed file written without confirmation")
+	}
+
+	out.Reset()
+	if err := run([]string{"-secret", secretFile, "sign", unsigned}, strings.NewReader("y\n"), out); err != nil {
+		t.Fatalf("sign failed: %s", err)
+	}
+	signed, err := os.ReadFile(filepath.Join(dir, "zano_tx_signed"))
+	if err != nil {
+		t.Fatalf("signed file not written: %s", err)
+	}
+	finalized, err := w.ParseFinalized(signed)
+	if err != nil {
+		t.Fatalf("failed to parse signed file: %s", err)
+	}
+
+	out.Reset()
+	if err := run([]string{"-secret", secretFile, "decode", filepath.Join(dir, "zano_tx_signed")}, nil, out); err != nil {
+		t.Fatalf("decode failed: %s", err)
+	}
+	txId, _ := finalized.Tx.Hash()
+	if !strings.Contains(out.String(), hex.EncodeToString(txId)) {
+		t.Errorf("decode output does not contain the transaction id:\n%s", out)
+	}
+}
+
+func TestSignPolicy(t *testing.T) {
+	dir := t.TempDir()
+	secret := make([]byte, 32)
+	secret[0] = 2
+	w, err := zanolib.LoadSpendSecret(secret, 0)
+	if err != nil {
+		t.Fatalf("failed to load wallet: %s", err)
+	}
+	secretFile := writeFile(t, filepath.Join(dir, "secret"), secret)
+	policy := writeFile(t, filepath.Join(dir, "policy.json"), []byte(`{"max_amount":{"`+zanolib.NativeCoinAssetId+`":700}}`))
+
+	small := writeFile(t, filepath.Join(dir, "small"), testUnsigned(t, w, 600))
+	if err := run([]string{"-secret", secretFile, "-policy", policy, "sign", small}, nil, &bytes.Buffer{}); err != nil {
+		t.Errorf("transaction within policy was not signed: %s", err)
+	}
+	if _, err := os.Stat(small + ".signed"); err != nil {
+		t.Errorf("signed file not written: %s", err)
+	}
+
+	large := writeFile(t, filepath.Join(dir, "large"), testUnsigned(t, w, 800))
+	out := &bytes.Buffer{}
+	if err := run([]string{"-secret", secretFile, "-policy", policy, "sign", large}, nil, out); err == nil {
+		t.Errorf("transaction exceeding policy was signed")
+	}
+	if !strings.Contains(out.String(), "Policy: denied") {
+		t.Errorf("missing policy decision:\n%s", out)
+	}
+}
+
+func TestSignDailyLimit(t *testing.T) {
+	dir := t.TempDir()
+	secret := make([]byte, 32)
+	secret[0] = 4
+	w, err := zanolib.LoadSpendSecret(secret, 0)
+	if err != nil {
+		t.Fatalf("failed to load wallet: %s", err)
+	}
+	secretFile := writeFile(t, filepath.Join(dir, "secret"), secret)
+	policy := writeFile(t, filepath.Join(dir, "policy.json"), []byte(`{"daily_limit":{"`+zanolib.NativeCoinAssetId+`":1000}}`))
+	ledger := filepath.Join(dir, "ledger.json")
+
+	first := writeFile(t, filepath.Join(dir, "first"), testUnsigned(t, w, 600))
+	if err := run([]string{"-secret", secretFile, "-policy", policy, "sign", first}, nil, &bytes.Buffer{}); err == nil {
+		t.Errorf("daily limit accepted without a ledger")
+	}
+	if err := run([]string{"-secret", secretFile, "-policy", policy, "-ledger", ledger, "sign", first}, nil, &bytes.Buffer{}); err != nil {
+		t.Fatalf("transaction within daily limit was not signed: %s", err)
+	}
+	if _, err := os.Stat(ledger); err != nil {
+		t.Errorf("ledger not written: %s", err)
+	}
+
+	// 600 more would exceed the limit over 24 hours
+	second := writeFile(t, filepath.Join(dir, "second"), testUnsigned(t, w, 600))
+	out := &bytes.Buffer{}
+	if err := run([]string{"-secret", secretFile, "-policy", policy, "-ledger", ledger, "sign", second}, nil, out); err == nil {
+		t.Errorf("transaction exceeding daily limit was signed")
+	}
+	if !strings.Contains(out.String(), "Policy: denied") {
+		t.Errorf("missing policy decision:\n%s", out)
+	}
+}
+
+func TestKeystore(t *testing.T) {
+	keystoreN = 1 << 10
+	dir := t.TempDir()
+	secret := make([]byte, 32)
+	secret[0] = 3
+	w, err := zanolib.LoadSpendSecret(secret, 0)
+	if err != nil {
+		t.Fatalf("failed to load wallet: %s", err)
+	}
+	secretFile := writeFile(t, filepath.Join(dir, "secret"), secret)
+	ks := filepath.Join(dir, "wallet.json")
+
+	if err := run([]string{"-secret", secretFile, "keystore", ks}, strings.NewReader("pass1\npass2\n"), &bytes.Buffer{}); err == nil {
+		t.Errorf("mismatched passwords accepted")
+	}
+	if err := run([]string{"-secret", secretFile, "keystore", ks}, strings.NewReader("hunter2\nhunter2\n"), &bytes.Buffer{}); err != nil {
+		t.Fatalf("failed to create keystore: %s", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := run([]string{"-keystore", ks, "address"}, strings.NewReader("hunter2\n"), out); err != nil {
+		t.Fatalf("failed to load keystore: %s", err)
+	}
+	if !strings.Contains(out.String(), w.Address().String()) {
+		t.Errorf("unexpected address:\n%s", out)
+	}
+	if err := run([]string{"-keystore", ks, "address"}, strings.NewReader("wrong\n"), &bytes.Buffer{}); err == nil {
+		t.Errorf("wrong password accepted")
+	}
+
+	// scrypt parameters from the file are bounded
+	var k map[string]any
+	if buf, err := os.ReadFile(ks); err != nil || json.Unmarshal(buf, &k) != nil {
+		t.Fatalf("failed to read keystore: %v", err)
+	}
+	k["scrypt_n"] = 1 << 30
+	buf, _ := json.Marshal(k)
+	huge := writeFile(t, filepath.Join(dir, "huge.json"), buf)
+	if err := run([]string{"-keystore", huge, "address"}, strings.NewReader("hunter2\n"), &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "scrypt") {
+		t.Errorf("huge scrypt parameters accepted: %v", err)
+	}
+}
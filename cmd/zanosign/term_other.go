@@ -0,0 +1,17 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// isTerminal always returns false on this platform, passwords are read with echo
+func isTerminal(f *os.File) bool {
+	return false
+}
+
+func disableEcho(f *os.File) (func(), error) {
+	return nil, errors.New("not supported on this platform")
+}
@@ -0,0 +1,239 @@
+package zanolib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+// StakeTemplate holds the block template data needed to stake, as returned by the daemon's
+// get_pos_mining_details and getblocktemplate
+type StakeTemplate struct {
+	Height        uint64 // height of the new block
+	Timestamp     uint64 // block timestamp, part of the stake kernel
+	Reward        uint64 // block reward
+	StakeModifier zanobase.StakeModifier
+	Difficulty    *big.Int // PoS difficulty
+	TxVersion     uint64   // version of the miner transaction, defaults to zanobase.TransactionVersionPostHF4
+	HardforkId    uint8    // hardfork of the block, only stored from version 3
+}
+
+// Coinstake is a PoS miner transaction built by Wallet.BuildCoinstake. Its prefix is final,
+// so it can be included in a block whose hash is then signed with Sign.
+type Coinstake struct {
+	Tx       *zanobase.Transaction
+	TxKey    *edwards25519.Scalar // transaction secret key
+	Template *StakeTemplate
+	Stake    *TxSource
+
+	keyImage              *edwards25519.Point
+	kernelHash            []byte
+	secretXp              *edwards25519.Scalar // stealth address secret key
+	secretQ               *edwards25519.Scalar // concealing point secret key, Q = q * G
+	pseudoOutBlindingMask *edwards25519.Scalar
+}
+
+// stakeSecrets derives the secrets of the staked output src: the stealth address secret key,
+// the key image and the concealing point secret key q
+func (w *Wallet) stakeSecrets(src *TxSource) (xp *edwards25519.Scalar, ki *edwards25519.Point, q *edwards25519.Scalar, err error) {
+	if w.WatchOnly() {
+		return nil, nil, nil, errors.New("watch-only wallet cannot stake")
+	}
+	if src.RealOutput >= uint64(len(src.Outputs)) {
+		return nil, nil, nil, errors.New("stake real output out of range")
+	}
+	realOut := src.Outputs[src.RealOutput]
+	if realOut.ConcealingPoint == nil || realOut.AmountCommitment == nil || realOut.BlindedAssetID == nil {
+		return nil, nil, nil, errors.New("staked output is not a Zarcanum output")
+	}
+	derivation, err := zanocrypto.GenerateKeyDerivation(src.RealOutTxKey.Point, w.ViewPrivKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	xp, err = zanocrypto.DeriveSecretKey(derivation.Bytes(), src.RealOutInTxIndex, w.SpendPrivKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pub := new(edwards25519.Point).ScalarBaseMult(xp)
+	if pub.Equal(realOut.StealthAddress.Point) != 1 {
+		return nil, nil, nil, errors.New("staked output does not belong to this wallet")
+	}
+	if ki, err = zanocrypto.ComputeKeyImage(xp, pub); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Q = Hs(CONCEALING_POINT, h) * V, found premultiplied by 1/8 or not depending on the
+	// transaction that created it
+	h := zanocrypto.HashToScalar(slices.Concat(derivation.Bytes(), zanobase.Varint(src.RealOutInTxIndex).Bytes()))
+	q = zanocrypto.HashToScalar(slices.Concat([]byte("ZANO_HDS_OUT_CONCEALING_POINT__\x00"), h.Bytes()))
+	q = q.Multiply(q, w.ViewPrivKey)
+	Q := new(edwards25519.Point).MultByCofactor(realOut.ConcealingPoint.Point)
+	switch {
+	case Q.Equal(new(edwards25519.Point).ScalarBaseMult(q)) == 1:
+	case realOut.ConcealingPoint.Point.Equal(new(edwards25519.Point).ScalarBaseMult(q)) == 1:
+		q = q.Multiply(q, zanocrypto.ScalarInt(8))
+	default:
+		return nil, nil, nil, errors.New("staked output concealing point mismatch")
+	}
+	return xp, ki, q, nil
+}
+
+// stakeKernelHash returns the hash of the stake kernel of ki in tmpl
+func stakeKernelHash(tmpl *StakeTemplate, ki *edwards25519.Point) []byte {
+	kernel := &zanobase.StakeKernel{StakeModifier: tmpl.StakeModifier, BlockTimestamp: tmpl.Timestamp}
+	copy(kernel.KeyImage[:], ki.Bytes())
+	return kernel.Hash()
+}
+
+// CheckStake returns true if the output src can produce a PoS block for tmpl. Callers
+// typically try several timestamps around the current time.
+func (w *Wallet) CheckStake(tmpl *StakeTemplate, src *TxSource) (bool, error) {
+	_, ki, q, err := w.stakeSecrets(src)
+	if err != nil {
+		return false, err
+	}
+	fq := new(edwards25519.Scalar).Add(src.RealOutAmountBlindingMask.Scalar, q)
+	return zanocrypto.ZarcanumCheckMainPosInequality(stakeKernelHash(tmpl, ki), tmpl.StakeModifier.LastPowId[:], fq, src.Amount, tmpl.Difficulty), nil
+}
+
+// BuildCoinstake builds the miner transaction of a PoS block for tmpl, staking the output
+// src of w. The block reward and the staked amount are sent back to addr (w's address if
+// nil) in two outputs. The stake must be eligible (see CheckStake), otherwise an error
+// wrapping zanocrypto.ErrStakeNotEligible is returned.
+func (w *Wallet) BuildCoinstake(rnd io.Reader, tmpl *StakeTemplate, src *TxSource, addr *Address) (*Coinstake, error) {
+	return w.buildCoinstake(rnd, tmpl, src, addr, nil)
+}
+
+func (w *Wallet) buildCoinstake(rnd io.Reader, tmpl *StakeTemplate, src *TxSource, addr *Address, extraNonce []byte) (*Coinstake, error) {
+	if tmpl.Difficulty == nil || tmpl.Difficulty.Sign() <= 0 {
+		return nil, errors.New("invalid PoS difficulty")
+	}
+	xp, ki, q, err := w.stakeSecrets(src)
+	if err != nil {
+		return nil, err
+	}
+	res := &Coinstake{
+		Template:   tmpl,
+		Stake:      src,
+		keyImage:   ki,
+		kernelHash: stakeKernelHash(tmpl, ki),
+		secretXp:   xp,
+		secretQ:    q,
+	}
+	fq := new(edwards25519.Scalar).Add(src.RealOutAmountBlindingMask.Scalar, q)
+	if !zanocrypto.ZarcanumCheckMainPosInequality(res.kernelHash, tmpl.StakeModifier.LastPowId[:], fq, src.Amount, tmpl.Difficulty) {
+		return nil, fmt.Errorf("height %d, timestamp %d: %w", tmpl.Height, tmpl.Timestamp, zanocrypto.ErrStakeNotEligible)
+	}
+	if addr == nil {
+		addr = w.Address()
+	}
+
+	offsets, err := KeyOffsets(src.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	in := &zanobase.TxInZcInput{KeyImage: &zanobase.Point{ki}}
+	for _, cur := range offsets {
+		in.KeyOffsets = append(in.KeyOffsets, zanobase.VariantFor(cur))
+	}
+
+	tx, err := newMinerTx(tmpl.TxVersion, tmpl.HardforkId)
+	if err != nil {
+		return nil, err
+	}
+	tx.Vin = []*zanobase.Variant{
+		zanobase.VariantFor(&zanobase.TxInGen{Height: tmpl.Height}),
+		zanobase.VariantFor(in),
+	}
+	ogc, err := buildMinerTx(rnd, tx, tmpl.Height, []*TxDest{minerDest(addr, tmpl.Reward), minerDest(addr, src.Amount)}, 1, extraNonce)
+	if err != nil {
+		return nil, err
+	}
+	res.Tx = tx
+	res.TxKey = ogc.TxKey.Sec.Scalar
+
+	// A^p - sum(outputs) = (f' - sum(f_j)) * G + a * r * X, with f' = sum(f_j) only the X
+	// component remains in the balance proof
+	res.pseudoOutBlindingMask = ogc.AmountBlindingMasksSum.Scalar
+	T := new(edwards25519.Point).MultByCofactor(src.Outputs[src.RealOutput].BlindedAssetID.Point)
+	pseudoOut := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(zanocrypto.ScalarInt(src.Amount), T, res.pseudoOutBlindingMask)
+	addRefPoint(&ogc.PseudoOutAmountCommitmentsSum, pseudoOut)
+	addRefScalar(&ogc.RealInAssetIdBlindingMaskXAmountSum, new(edwards25519.Scalar).Multiply(src.RealOutAssetIdBlindingMask.Scalar, zanocrypto.ScalarInt(src.Amount)))
+
+	if err := minerTxProofs(rnd, tx, ogc, tmpl.Reward); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Sign generates the Zarcanum proof of the stake for the block hash blockHash and appends it
+// to the transaction signatures. It can only be called once.
+func (c *Coinstake) Sign(rnd io.Reader, blockHash []byte) error {
+	if len(c.Tx.Signatures) != 0 {
+		return errors.New("coinstake is already signed")
+	}
+	if len(blockHash) != 32 {
+		return errors.New("invalid block hash")
+	}
+	src := c.Stake
+	sig, err := zanocrypto.GenerateZarcanumProof(rnd, blockHash, c.kernelHash, stakeRing(src.Outputs), c.Template.StakeModifier.LastPowId[:], c.keyImage, c.Template.Difficulty,
+		c.secretXp, c.secretQ, src.RealOutAmountBlindingMask.Scalar, src.RealOutAssetIdBlindingMask.Scalar, c.pseudoOutBlindingMask,
+		src.Amount, src.RealOutput,
+	)
+	if err != nil {
+		return err
+	}
+	c.Tx.Signatures = append(c.Tx.Signatures, zanobase.VariantFor(sig))
+	return nil
+}
+
+// VerifyCoinstake checks the Zarcanum proof of a PoS miner transaction against the block
+// hash, the template it was built for and the ring members referenced by its stake input.
+func VerifyCoinstake(tx *zanobase.Transaction, blockHash []byte, tmpl *StakeTemplate, ring []*TxSourceOutputEntry) error {
+	if len(tx.Vin) != 2 || !tx.IsCoinbase() {
+		return errors.New("not a coinstake transaction")
+	}
+	in, ok := tx.Vin[1].Value.(*zanobase.TxInZcInput)
+	if !ok || in.KeyImage == nil {
+		return errors.New("coinstake has no stake input")
+	}
+	if len(in.KeyOffsets) != len(ring) {
+		return errors.New("ring does not match the stake input")
+	}
+	if len(tx.Signatures) != 1 {
+		return errors.New("coinstake is not signed")
+	}
+	sig, ok := tx.Signatures[0].Value.(*zanobase.ZarcanumSig)
+	if !ok {
+		return errors.New("coinstake signature is not a zarcanum_sig")
+	}
+	if tmpl.Difficulty == nil {
+		return errors.New("invalid PoS difficulty")
+	}
+	for _, r := range ring {
+		if r.StealthAddress == nil || r.AmountCommitment == nil || r.BlindedAssetID == nil || r.ConcealingPoint == nil {
+			return errors.New("ring member is not a Zarcanum output")
+		}
+	}
+	kernelHash := stakeKernelHash(tmpl, in.KeyImage.Point)
+	return zanocrypto.VerifyZarcanumProof(blockHash, kernelHash, stakeRing(ring), tmpl.StakeModifier.LastPowId[:], in.KeyImage.Point, tmpl.Difficulty, sig)
+}
+
+func stakeRing(outputs []*TxSourceOutputEntry) []zanocrypto.CLSAG_GGXXGInputRef {
+	ring := make([]zanocrypto.CLSAG_GGXXGInputRef, len(outputs))
+	for n, o := range outputs {
+		ring[n] = zanocrypto.CLSAG_GGXXGInputRef{
+			StealthAddress:   o.StealthAddress.Point,
+			AmountCommitment: o.AmountCommitment.Point,
+			BlindedAssetID:   o.BlindedAssetID.Point,
+			ConcealingPoint:  o.ConcealingPoint.Point,
+		}
+	}
+	return ring
+}
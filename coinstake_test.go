@@ -0,0 +1,99 @@
+package zanolib_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+func TestCoinstake(t *testing.T) {
+	w := testWallet(t)
+
+	// stake the 390 change of a signed transaction
+	prev := must(w.Sign(rand.Reader, testSignableFTP(t, w), nil))
+	scan := must(w.ScanTransaction(prev.Tx))
+	owned := must(scan.OwnedOutput(prev.Tx, scan.Outputs[0], 2500))
+	ring, realOut, err := zanolib.BuildRing(testRing{}, owned, 4)
+	if err != nil {
+		t.Fatalf("failed to build ring: %s", err)
+	}
+	src := &zanolib.TxSource{
+		Outputs:                    ring,
+		RealOutput:                 uint64(realOut),
+		RealOutTxKey:               &zanobase.Point{owned.TxPubKey.ToPoint()},
+		RealOutAmountBlindingMask:  owned.AmountBlindingMask,
+		RealOutAssetIdBlindingMask: owned.AssetIdBlindingMask,
+		RealOutInTxIndex:           owned.Index,
+		Amount:                     owned.Amount,
+	}
+
+	tmpl := &zanolib.StakeTemplate{
+		Height:     3000000,
+		Timestamp:  1760000000,
+		Reward:     1000000000000,
+		Difficulty: new(big.Int).Lsh(big.NewInt(1), 180), // too high for 390
+	}
+	tmpl.StakeModifier.LastPowId[0] = 1
+	if ok, err := w.CheckStake(tmpl, src); err != nil || ok {
+		t.Errorf("CheckStake = %v, %v with a high difficulty", ok, err)
+	}
+	if _, err := w.BuildCoinstake(rand.Reader, tmpl, src, nil); !errors.Is(err, zanocrypto.ErrStakeNotEligible) {
+		t.Errorf("expected ErrStakeNotEligible, got %v", err)
+	}
+
+	tmpl.Difficulty = big.NewInt(1) // any kernel hash is eligible
+	if ok, err := w.CheckStake(tmpl, src); err != nil || !ok {
+		t.Fatalf("CheckStake = %v, %v", ok, err)
+	}
+	cs, err := w.BuildCoinstake(rand.Reader, tmpl, src, nil)
+	if err != nil {
+		t.Fatalf("failed to build coinstake: %s", err)
+	}
+	if !cs.Tx.IsCoinbase() || len(cs.Tx.Vin) != 2 || len(cs.Tx.Proofs) != 2 {
+		t.Fatalf("unexpected coinstake transaction")
+	}
+
+	blk := &zanobase.Block{MinerTx: cs.Tx}
+	blk.Timestamp = tmpl.Timestamp
+	blockHash := must(blk.Hash())
+	if err := cs.Sign(rand.Reader, blockHash); err != nil {
+		t.Fatalf("failed to sign coinstake: %s", err)
+	}
+	// signing does not change the block hash
+	if !bytes.Equal(must(blk.Hash()), blockHash) {
+		t.Errorf("block hash changed after signing")
+	}
+	if err := zanolib.VerifyCoinstake(cs.Tx, blockHash, tmpl, ring); err != nil {
+		t.Errorf("VerifyCoinstake: %s", err)
+	}
+	other := *tmpl
+	other.Timestamp++
+	if err := zanolib.VerifyCoinstake(cs.Tx, blockHash, &other, ring); err == nil {
+		t.Errorf("coinstake verified with another kernel")
+	}
+
+	// the signed transaction goes through serialization
+	buf := &bytes.Buffer{}
+	if _, err := cs.Tx.WriteTo(buf); err != nil {
+		t.Fatalf("failed to serialize: %s", err)
+	}
+	tx := new(zanobase.Transaction)
+	if _, err := tx.ReadFrom(buf); err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	if err := zanolib.VerifyCoinstake(tx, blockHash, tmpl, ring); err != nil {
+		t.Errorf("VerifyCoinstake after parsing: %s", err)
+	}
+
+	// the wallet receives the reward and its stake back
+	res := must(w.ScanTransaction(tx))
+	if total := res.Total(zanolib.NativeCoinAssetId); total != tmpl.Reward+390 {
+		t.Errorf("received %d, expected %d", total, tmpl.Reward+390)
+	}
+}
@@ -0,0 +1,231 @@
+package zanolib
+
+import (
+	"cmp"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mrand "math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+const (
+	// DefaultDecoySetSize is CURRENCY_DEFAULT_DECOY_SET_SIZE, the number of decoys used by
+	// default before HF4 where decoys were optional
+	DefaultDecoySetSize = 10
+
+	// HF4DecoySetSize is CURRENCY_HF4_MANDATORY_DECOY_SET_SIZE, the exact number of decoys
+	// each input must have after HF4
+	HF4DecoySetSize = 15
+
+	// HF4Height is ZANO_HARDFORK_04_AFTER_HEIGHT on mainnet: HF4 (zarcanum) is active on
+	// blocks above this height, and zarcanum outputs only exist from there
+	HF4Height = 2555000
+
+	// gamma distribution of the age of spent outputs in log(seconds). These are Monero's
+	// parameters, fitted on Monero spends by Möser et al., "An Empirical Analysis of
+	// Traceability in the Monero Blockchain" (PETS 2018) and used by Monero's wallet2
+	// (GAMMA_SHAPE, GAMMA_SCALE). Zano's wallet uses its own decoy_selection_generator,
+	// whose distribution is not ported here.
+	decoyGammaShape = 19.28
+	decoyGammaScale = 1 / 1.61
+
+	maxDecoyRounds = 32
+)
+
+// DecoySetSize returns the number of decoys an input should have in a transaction for
+// the given hardfork id
+func DecoySetSize(hardfork uint8) int {
+	if hardfork >= 4 {
+		return HF4DecoySetSize
+	}
+	return DefaultDecoySetSize
+}
+
+// OutputIndexSource provides the outputs a DecoySelector picks decoys from, typically
+// backed by a daemon
+type OutputIndexSource interface {
+	// OutputCount returns the number of zarcanum outputs on chain, i.e. the highest global
+	// index for amount 0 + 1
+	OutputCount() (uint64, error)
+
+	// TimeSpan returns the time elapsed since zarcanum outputs exist, i.e. the difference
+	// of the timestamps of the top block and the block at HF4Height
+	TimeSpan() (time.Duration, error)
+
+	// GetOutputs returns the ring member entries of the given global indices, in the same
+	// order. A nil entry means the output cannot be used as a decoy (not a zarcanum
+	// output, still locked, etc).
+	GetOutputs(indices []uint64) ([]*TxSourceOutputEntry, error)
+}
+
+// DecoySelector is a RingProvider picking decoys with a recent-biased distribution: the
+// age of each decoy follows Monero's gamma distribution in log-seconds, converted into a
+// distance from the most recent global index using the average rate of zarcanum outputs
+// since HF4. Picks out of the chain range fall back to a uniform selection.
+type DecoySelector struct {
+	Source           OutputIndexSource
+	OutputsPerSecond float64   // if zero, computed from the output count and time span of Source
+	Rand             io.Reader // defaults to crypto/rand.Reader
+}
+
+// GetDecoys implements RingProvider
+func (s *DecoySelector) GetDecoys(out *OwnedOutput, count int) ([]*TxSourceOutputEntry, error) {
+	total, err := s.Source.OutputCount()
+	if err != nil {
+		return nil, err
+	}
+	if total <= uint64(count) {
+		return nil, fmt.Errorf("not enough outputs on chain to pick %d decoys", count)
+	}
+	rate := s.OutputsPerSecond
+	if rate <= 0 {
+		span, err := s.Source.TimeSpan()
+		if err != nil {
+			return nil, err
+		}
+		if span < time.Second {
+			return nil, errors.New("output time span is too short")
+		}
+		rate = float64(total) / span.Seconds()
+	}
+	rng, err := s.rng()
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[uint64]bool{out.GlobalIndex: true}
+	var res []*TxSourceOutputEntry
+	for round := 0; len(res) < count; round++ {
+		if round >= maxDecoyRounds {
+			return nil, errors.New("could not find enough usable decoys")
+		}
+		var pick []uint64
+		for tries := 0; len(pick) < count-len(res) && tries < count*100; tries++ {
+			idx := pickDecoy(rng, total, rate)
+			if used[idx] {
+				continue
+			}
+			used[idx] = true
+			pick = append(pick, idx)
+		}
+		if len(pick) == 0 {
+			continue
+		}
+		entries, err := s.Source.GetOutputs(pick)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) != len(pick) {
+			return nil, fmt.Errorf("output source returned %d entries, expected %d", len(entries), len(pick))
+		}
+		for n, e := range entries {
+			if e == nil {
+				continue
+			}
+			e.OutReference = zanobase.VariantFor(pick[n])
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func (s *DecoySelector) rng() (*mrand.Rand, error) {
+	r := s.Rand
+	if r == nil {
+		r = rand.Reader
+	}
+	var seed [32]byte
+	if _, err := io.ReadFull(r, seed[:]); err != nil {
+		return nil, err
+	}
+	return mrand.New(mrand.NewChaCha8(seed)), nil
+}
+
+// pickDecoy returns a global index in [0, total), given rate outputs per second
+func pickDecoy(rng *mrand.Rand, total uint64, rate float64) uint64 {
+	dist := math.Exp(gammaSample(rng, decoyGammaShape, decoyGammaScale)) * rate
+	if dist >= float64(total) {
+		return rng.Uint64N(total)
+	}
+	return total - 1 - uint64(dist)
+}
+
+// gammaSample returns a value following a gamma distribution with shape k >= 1 and
+// scale theta, using Marsaglia and Tsang's method
+func gammaSample(rng *mrand.Rand, k, theta float64) float64 {
+	d := k - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		if math.Log(rng.Float64()) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v * theta
+		}
+	}
+}
+
+// BuildRing returns the ring used to spend out, made of out and ringSize-1 decoys from p
+// sorted by global index, as well as the position of out in the ring. As decoys are
+// random, so is this position.
+func BuildRing(p RingProvider, out *OwnedOutput, ringSize int) ([]*TxSourceOutputEntry, int, error) {
+	realOut, err := out.Entry()
+	if err != nil {
+		return nil, 0, err
+	}
+	ring := []*TxSourceOutputEntry{realOut}
+	if ringSize > 1 {
+		decoys, err := p.GetDecoys(out, ringSize-1)
+		if err != nil {
+			return nil, 0, fmt.Errorf("while getting decoys: %w", err)
+		}
+		if len(decoys) != ringSize-1 {
+			return nil, 0, fmt.Errorf("ring provider returned %d decoys, expected %d", len(decoys), ringSize-1)
+		}
+		ring = append(ring, decoys...)
+	}
+
+	seen := make(map[uint64]bool)
+	for _, e := range ring {
+		idx, ok := e.OutReference.Value.(uint64)
+		if !ok {
+			return nil, 0, errors.New("ring members must refer to outputs by global index")
+		}
+		if seen[idx] {
+			return nil, 0, fmt.Errorf("duplicate ring member %d", idx)
+		}
+		seen[idx] = true
+	}
+	slices.SortFunc(ring, func(a, b *TxSourceOutputEntry) int {
+		return cmp.Compare(a.OutReference.Value.(uint64), b.OutReference.Value.(uint64))
+	})
+	return ring, slices.Index(ring, realOut), nil
+}
+
+// KeyOffsets returns the relative offsets of ring as stored in an input's key_offsets. Ring
+// members must refer to outputs by global index and be sorted.
+func KeyOffsets(ring []*TxSourceOutputEntry) ([]uint64, error) {
+	res := make([]uint64, 0, len(ring))
+	var prev uint64
+	for n, e := range ring {
+		idx, ok := e.OutReference.Value.(uint64)
+		if !ok {
+			return nil, errors.New("ring members must refer to outputs by global index")
+		}
+		if n > 0 && idx <= prev {
+			return nil, errors.New("ring members are not sorted by global index")
+		}
+		res = append(res, idx-prev)
+		prev = idx
+	}
+	return res, nil
+}
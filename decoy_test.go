@@ -0,0 +1,103 @@
+package zanolib_test
+
+import (
+	"testing"
+	"time"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+// testOutputSource has 5000 outputs, every 7th output cannot be used as decoy
+type testOutputSource struct{}
+
+func (testOutputSource) OutputCount() (uint64, error) {
+	return 5000, nil
+}
+
+func (testOutputSource) TimeSpan() (time.Duration, error) {
+	return 5000 * 10 * time.Second, nil
+}
+
+func (testOutputSource) GetOutputs(indices []uint64) ([]*zanolib.TxSourceOutputEntry, error) {
+	pt := &zanobase.Point{new(edwards25519.Point).ScalarBaseMult(zanocrypto.ScalarInt(3))}
+	res := make([]*zanolib.TxSourceOutputEntry, len(indices))
+	for n, idx := range indices {
+		if idx%7 == 0 {
+			continue
+		}
+		res[n] = &zanolib.TxSourceOutputEntry{StealthAddress: pt, ConcealingPoint: pt, AmountCommitment: pt, BlindedAssetID: pt}
+	}
+	return res, nil
+}
+
+func TestDecoySelector(t *testing.T) {
+	if zanolib.DecoySetSize(4) != 15 || zanolib.DecoySetSize(3) != 10 {
+		t.Errorf("invalid decoy set sizes")
+	}
+
+	w := testWallet(t)
+	owned := &zanolib.OwnedOutput{GlobalIndex: 4990, TxPubKey: zanobase.Value256(w.ViewPubKey.Bytes())}
+	for _, v := range []*zanobase.Value256{&owned.StealthAddress, &owned.ConcealingPoint, &owned.AmountCommitment, &owned.BlindedAssetId} {
+		*v = zanobase.Value256(w.SpendPubKey.Bytes())
+	}
+
+	sel := &zanolib.DecoySelector{Source: testOutputSource{}}
+	ring, realOut, err := zanolib.BuildRing(sel, owned, zanolib.DefaultRingSize)
+	if err != nil {
+		t.Fatalf("failed to build ring: %s", err)
+	}
+	if len(ring) != zanolib.DefaultRingSize {
+		t.Fatalf("ring size = %d", len(ring))
+	}
+	if zanobase.VariantAs[uint64](ring[realOut].OutReference) != owned.GlobalIndex {
+		t.Errorf("real output not found at position %d", realOut)
+	}
+	for n, e := range ring {
+		idx := zanobase.VariantAs[uint64](e.OutReference)
+		if n != realOut && (idx%7 == 0 || idx >= 5000) {
+			t.Errorf("unusable decoy %d selected", idx)
+		}
+	}
+
+	offsets, err := zanolib.KeyOffsets(ring)
+	if err != nil {
+		t.Fatalf("failed to compute offsets: %s", err)
+	}
+	var sum uint64
+	for n, o := range offsets {
+		sum += o
+		if sum != zanobase.VariantAs[uint64](ring[n].OutReference) {
+			t.Errorf("invalid offset at %d", n)
+		}
+	}
+
+	ring[0], ring[1] = ring[1], ring[0]
+	if _, err := zanolib.KeyOffsets(ring); err == nil {
+		t.Errorf("unsorted ring should be rejected")
+	}
+
+	if _, err := sel.GetDecoys(owned, 5000); err == nil {
+		t.Errorf("asking for more decoys than outputs should fail")
+	}
+}
+
+// testYoungSource has its outputs all created in the same second
+type testYoungSource struct{ testOutputSource }
+
+func (testYoungSource) TimeSpan() (time.Duration, error) {
+	return 0, nil
+}
+
+func TestDecoySelectorTimeSpan(t *testing.T) {
+	owned := &zanolib.OwnedOutput{GlobalIndex: 4990}
+	if _, err := (&zanolib.DecoySelector{Source: testYoungSource{}}).GetDecoys(owned, 15); err == nil {
+		t.Errorf("a zero time span should be rejected")
+	}
+	sel := &zanolib.DecoySelector{Source: testYoungSource{}, OutputsPerSecond: 0.1}
+	if res, err := sel.GetDecoys(owned, 15); err != nil || len(res) != 15 {
+		t.Errorf("explicit rate: got %d decoys, err = %v", len(res), err)
+	}
+}
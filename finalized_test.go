@@ -0,0 +1,110 @@
+package zanolib_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+func testFTP(w *zanolib.Wallet) *zanolib.FinalizeTxParam {
+	other := must(zanolib.ParseAddress("ZxD5aoLDPTdcaRx4uCpyW4XiLfEXejepAVz8cSY2fwHNEiJNu6NmpBBDLGTJzCsUvn3acCVDVDPMV8yQXdPooAp338Se7AxeH"))
+	pt := &zanobase.Point{zanocrypto.C_point_G}
+
+	return &zanolib.FinalizeTxParam{
+		Extra:        []*zanobase.Variant{zanobase.VariantFor(uint64(42))},
+		CryptAddress: testAccount(other)[0],
+		Sources: []*zanolib.TxSource{{
+			Outputs: []*zanolib.TxSourceOutputEntry{{
+				OutReference:     zanobase.VariantFor(uint64(1234)),
+				StealthAddress:   pt,
+				ConcealingPoint:  pt,
+				AmountCommitment: pt,
+				BlindedAssetID:   pt,
+			}},
+			RealOutTxKey:               pt,
+			RealOutAmountBlindingMask:  &zanobase.Scalar{zanocrypto.ScalarInt(5)},
+			RealOutAssetIdBlindingMask: &zanobase.Scalar{zanocrypto.ScalarInt(6)},
+			Amount:                     1000,
+			HtlcOrigin:                 "\x00",
+		}},
+		SelectedTransfers: []zanobase.Varint{1},
+		PreparedDestinations: []*zanolib.TxDest{{
+			Amount:      990,
+			Addr:        testAccount(other),
+			HtlcOptions: &zanolib.TxDestHtlcOut{},
+			AssetId:     &zanobase.Point{zanocrypto.NativeCoinAssetIdPt},
+		}},
+		SpendPubKey: &zanobase.Point{w.SpendPubKey},
+		TxVersion:   2,
+	}
+}
+
+func TestFinalizedJSON(t *testing.T) {
+	w := testWallet(t)
+	ftp := testFTP(w)
+	finalized := &zanolib.FinalizedTx{
+		Tx:         &zanobase.Transaction{Version: 2},
+		OneTimeKey: &zanobase.Scalar{new(edwards25519.Scalar)},
+		FTP:        ftp,
+	}
+
+	enc := must(json.Marshal(finalized))
+	dec := new(zanolib.FinalizedTx)
+	if err := json.Unmarshal(enc, dec); err != nil {
+		t.Fatalf("failed to unmarshal: %s", err)
+	}
+	if enc2 := must(json.Marshal(dec)); !bytes.Equal(enc, enc2) {
+		t.Errorf("json did not round trip:\n%s\n%s", enc, enc2)
+	}
+	if !bytes.Equal(must(w.Encrypt(finalized)), must(w.Encrypt(dec))) {
+		t.Errorf("binary serialization differs after json round trip")
+	}
+}
+
+// testSignableFTP returns a FinalizeTxParam spending one output that belongs to w, with
+// a ring of two members, sending 600 to another address and 390 back to w
+func testSignableFTP(t *testing.T, w *zanolib.Wallet) *zanolib.FinalizeTxParam {
+	other := must(zanolib.ParseAddress("ZxD5aoLDPTdcaRx4uCpyW4XiLfEXejepAVz8cSY2fwHNEiJNu6NmpBBDLGTJzCsUvn3acCVDVDPMV8yQXdPooAp338Se7AxeH"))
+	point := func(v uint64) *zanobase.Point {
+		return &zanobase.Point{new(edwards25519.Point).ScalarBaseMult(zanocrypto.ScalarInt(v))}
+	}
+
+	txKey := point(7)
+	derivation := must(zanocrypto.GenerateKeyDerivation(txKey.Point, w.ViewPrivKey))
+	stealth := must(zanocrypto.DerivePublicKey(derivation.Bytes(), 0, w.SpendPubKey))
+
+	// T = H + r*X and A = a*T + f*G, stored premultiplied by 1/8
+	blindedAssetId := new(edwards25519.Point).Add(zanocrypto.NativeCoinAssetIdPt, new(edwards25519.Point).ScalarMult(zanocrypto.ScalarInt(6), zanocrypto.C_point_X))
+	commitment := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(zanocrypto.ScalarInt(1000), blindedAssetId, zanocrypto.ScalarInt(5))
+	div8 := func(p *edwards25519.Point) *zanobase.Point {
+		return &zanobase.Point{new(edwards25519.Point).ScalarMult(zanocrypto.Sc1div8, p)}
+	}
+
+	return &zanolib.FinalizeTxParam{
+		CryptAddress: testAccount(other)[0],
+		Sources: []*zanolib.TxSource{{
+			Outputs: []*zanolib.TxSourceOutputEntry{
+				{OutReference: zanobase.VariantFor(uint64(1000)), StealthAddress: point(11), ConcealingPoint: point(12), AmountCommitment: point(13), BlindedAssetID: point(14)},
+				{OutReference: zanobase.VariantFor(uint64(1234)), StealthAddress: &zanobase.Point{stealth}, ConcealingPoint: point(15), AmountCommitment: div8(commitment), BlindedAssetID: div8(blindedAssetId)},
+			},
+			RealOutput:                 1,
+			RealOutTxKey:               txKey,
+			RealOutAmountBlindingMask:  &zanobase.Scalar{zanocrypto.ScalarInt(5)},
+			RealOutAssetIdBlindingMask: &zanobase.Scalar{zanocrypto.ScalarInt(6)},
+			Amount:                     1000,
+			HtlcOrigin:                 "\x00",
+		}},
+		SelectedTransfers: []zanobase.Varint{1},
+		PreparedDestinations: []*zanolib.TxDest{
+			{Amount: 600, Addr: testAccount(other), HtlcOptions: &zanolib.TxDestHtlcOut{}, AssetId: &zanobase.Point{zanocrypto.NativeCoinAssetIdPt}},
+			{Amount: 390, Addr: testAccount(w.Address()), HtlcOptions: &zanolib.TxDestHtlcOut{}, AssetId: &zanobase.Point{zanocrypto.NativeCoinAssetIdPt}},
+		},
+		SpendPubKey: &zanobase.Point{w.SpendPubKey},
+		TxVersion:   2,
+	}
+}
@@ -11,8 +11,8 @@ import (
 
 type FinalizeTxParam struct {
 	UnlockTime           uint64
-	Extra                []*zanobase.Variant         // currency::extra_v
-	Attachments          []*zanobase.Variant         // currency::attachment_v
+	Extra                []*zanobase.Variant         `epee:"keepunknown"` // currency::extra_v
+	Attachments          []*zanobase.Variant         `epee:"keepunknown"` // currency::attachment_v
 	CryptAddress         *zanobase.AccountPublicAddr // currency::account_public_address
 	TxOutsAttr           uint8
 	Shuffle              bool
@@ -29,7 +29,26 @@ type FinalizeTxParam struct {
 	//GenContext      *GenContext // if flags & TX_FLAG_SIGNATURE_MODE_SEPARATE
 }
 
+// ParseOptions changes how a FinalizeTxParam is parsed. A nil *ParseOptions is the same
+// as the zero value.
+type ParseOptions struct {
+	// KeepUnknown allows entries of unknown types in Extra and Attachments to be kept as
+	// zanobase.RawVariant instead of failing, so transactions prepared by a newer wallet can
+	// still be signed. Only entries of a few common shapes can be recovered, and parsing
+	// fails if their length cannot be determined unambiguously, see
+	// zanobase.DeserializeKeepUnknown.
+	KeepUnknown bool
+}
+
 func ParseFTP(buf, viewSecretKey []byte) (*FinalizeTxParam, error) {
+	return ParseFTPWithOptions(buf, viewSecretKey, nil)
+}
+
+// ParseFTPWithOptions decrypts and parses a FinalizeTxParam with the given options
+func ParseFTPWithOptions(buf, viewSecretKey []byte, opts *ParseOptions) (*FinalizeTxParam, error) {
+	if opts == nil {
+		opts = &ParseOptions{}
+	}
 	code, err := zanocrypto.ChaCha8GenerateKey(viewSecretKey)
 	if err != nil {
 		return nil, err
@@ -39,9 +58,17 @@ func ParseFTP(buf, viewSecretKey []byte) (*FinalizeTxParam, error) {
 		return nil, err
 	}
 	//log.Printf("decoded buffer:\n%s", hex.Dump(buf))
-	r := bytes.NewReader(buf)
 	res := new(FinalizeTxParam)
 
+	if opts.KeepUnknown {
+		err = zanobase.DeserializeKeepUnknown(buf, res)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	r := bytes.NewReader(buf)
 	err = zanobase.Deserialize(r, res)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,42 @@
+package zanolib_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+func TestParseFTPKeepUnknown(t *testing.T) {
+	w := testWallet(t)
+	ftp := testFTP(w)
+	ftp.Attachments = []*zanobase.Variant{{Tag: 0xf1, Value: &zanobase.RawVariant{Data: []byte{0x01, 0x02}}}}
+	buf := must(w.Encrypt(ftp))
+
+	if _, err := w.ParseFTP(buf); err == nil {
+		t.Errorf("parsing unknown variants should fail by default")
+	}
+
+	ftp2, err := w.ParseFTPWithOptions(buf, &zanolib.ParseOptions{KeepUnknown: true})
+	if err != nil {
+		t.Fatalf("failed to parse with unknown variants: %s", err)
+	}
+	if raw, ok := ftp2.Attachments[0].Value.(*zanobase.RawVariant); !ok || !bytes.Equal(raw.Data, []byte{0x01, 0x02}) {
+		t.Errorf("unexpected attachment %+v", ftp2.Attachments[0])
+	}
+	if !bytes.Equal(must(w.Encrypt(ftp2)), buf) {
+		t.Errorf("did not re-serialize identically")
+	}
+}
+
+func TestParseFTPTrailingData(t *testing.T) {
+	w := testWallet(t)
+	buf := must(w.Encrypt(testFTP(w)))
+	if _, err := w.ParseFTP(buf); err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	if _, err := w.ParseFTP(append(buf, 0)); err == nil {
+		t.Errorf("parsing with trailing data should fail")
+	}
+}
@@ -7,6 +7,5 @@ require (
 	github.com/KarpelesLab/rc v1.0.0
 	github.com/ModChain/base58 v1.1.0
 	golang.org/x/crypto v0.36.0
+	golang.org/x/sys v0.31.0
 )
-
-require golang.org/x/sys v0.31.0 // indirect
@@ -0,0 +1,64 @@
+package zanolib
+
+import (
+	"errors"
+	"io"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanocrypto"
+	"golang.org/x/crypto/sha3"
+)
+
+// MessageSignatureSize is the size of a message signature, two scalars c and r
+const MessageSignatureSize = 64
+
+// messageHash returns the scalar signed for a message: Hs(cn_fast_hash(msg) || pub || comm)
+func messageHash(h, pub, comm []byte) *edwards25519.Scalar {
+	buf := make([]byte, 0, 96)
+	buf = append(buf, h...)
+	buf = append(buf, pub...)
+	buf = append(buf, comm...)
+	return zanocrypto.HashToScalar(buf)
+}
+
+// SignMessage signs msg with the wallet's spend key, as simplewallet's sign_message
+// does (wallet2::sign_buffer). The 64 bytes signature is usually exchanged as hex and can
+// be checked with VerifyMessage or Zano's validate_signature. Watch-only wallets cannot
+// sign messages.
+func (w *Wallet) SignMessage(rnd io.Reader, msg []byte) ([]byte, error) {
+	if w.WatchOnly() {
+		return nil, errors.New("watch-only wallet cannot sign")
+	}
+	// crypto::generate_signature(cn_fast_hash(msg), spend_public_key, spend_secret_key, sig)
+	h := hsum(sha3.NewLegacyKeccak256, msg)
+	k := zanocrypto.RandomScalar(rnd)
+	comm := new(edwards25519.Point).ScalarBaseMult(k)
+
+	c := messageHash(h, w.SpendPubKey.Bytes(), comm.Bytes())
+	r := new(edwards25519.Scalar).Subtract(k, new(edwards25519.Scalar).Multiply(c, w.SpendPrivKey))
+	return append(c.Bytes(), r.Bytes()...), nil
+}
+
+// VerifyMessage checks a signature made by SignMessage (or Zano's sign_message) over msg
+// with the spend key of address.
+func VerifyMessage(address *Address, msg, sig []byte) bool {
+	if len(sig) != MessageSignatureSize {
+		return false
+	}
+	pub, err := new(edwards25519.Point).SetBytes(address.SpendKey)
+	if err != nil {
+		return false
+	}
+	c, err := new(edwards25519.Scalar).SetCanonicalBytes(sig[:32])
+	if err != nil {
+		return false
+	}
+	r, err := new(edwards25519.Scalar).SetCanonicalBytes(sig[32:])
+	if err != nil {
+		return false
+	}
+	// comm = c*P + r*G
+	comm := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(c, pub, r)
+	h := hsum(sha3.NewLegacyKeccak256, msg)
+	return messageHash(h, address.SpendKey, comm.Bytes()).Equal(c) == 1
+}
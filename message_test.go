@@ -0,0 +1,50 @@
+package zanolib_test
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/ModChain/zanolib"
+)
+
+func TestSignMessage(t *testing.T) {
+	w := testWallet(t)
+	msg := []byte("I control this address")
+
+	sig := must(w.SignMessage(rand.Reader, msg))
+	if len(sig) != zanolib.MessageSignatureSize {
+		t.Fatalf("unexpected signature size %d", len(sig))
+	}
+	if !zanolib.VerifyMessage(w.Address(), msg, sig) {
+		t.Errorf("valid signature rejected")
+	}
+	if zanolib.VerifyMessage(w.Address(), []byte("something else"), sig) {
+		t.Errorf("signature accepted for another message")
+	}
+	other := must(zanolib.LoadSpendSecret(make([]byte, 32), 0))
+	if zanolib.VerifyMessage(other.Address(), msg, sig) {
+		t.Errorf("signature accepted for another address")
+	}
+	sig[40] ^= 1
+	if zanolib.VerifyMessage(w.Address(), msg, sig) {
+		t.Errorf("tampered signature accepted")
+	}
+	if zanolib.VerifyMessage(w.Address(), msg, sig[:32]) {
+		t.Errorf("truncated signature accepted")
+	}
+
+	// auditable addresses share the spend key
+	aw := must(zanolib.LoadSpendSecret([]byte{0: 5, 31: 0}, 1))
+	if !zanolib.VerifyMessage(aw.Address(), msg, must(aw.SignMessage(rand.Reader, msg))) {
+		t.Errorf("auditable wallet signature rejected")
+	}
+
+	watcher, _, err := zanolib.LoadTrackingSeed(must(aw.TrackingSeed(time.Time{})))
+	if err != nil {
+		t.Fatalf("failed to load tracking seed: %s", err)
+	}
+	if _, err := watcher.SignMessage(rand.Reader, msg); err == nil {
+		t.Errorf("watch-only wallet should not sign messages")
+	}
+}
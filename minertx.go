@@ -0,0 +1,217 @@
+package zanolib
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+	"github.com/ModChain/zanolib/zanoproof"
+)
+
+// MinedMoneyUnlockWindow is the number of blocks miner transaction outputs stay locked, see
+// CURRENCY_MINED_MONEY_UNLOCK_WINDOW
+const MinedMoneyUnlockWindow = 10
+
+// MinerTxParams describes a miner transaction built by BuildMinerTx
+type MinerTxParams struct {
+	Height     uint64        // height of the block
+	Reward     uint64        // block reward, including the fees of the block transactions
+	Address    *Address      // receives the reward
+	ExtraNonce []byte        // optional data stored in the extra, typically used by pools
+	Stake      *MinerTxStake // PoS parts, nil for PoW blocks
+	TxVersion  uint64        // defaults to zanobase.TransactionVersionPostHF4
+	HardforkId uint8         // hardfork of the block, only stored from version 3
+}
+
+// MinerTxStake holds the PoS parts of a miner transaction. The staked amount is sent back to
+// MinerTxParams.Address with the reward.
+type MinerTxStake struct {
+	Wallet        *Wallet   // owner of the staked output
+	Source        *TxSource // staked output and its ring
+	Timestamp     uint64    // block timestamp
+	StakeModifier zanobase.StakeModifier
+	Difficulty    *big.Int // PoS difficulty
+}
+
+// MinerTx is a miner transaction built by BuildMinerTx
+type MinerTx struct {
+	Tx        *zanobase.Transaction
+	TxKey     *edwards25519.Scalar // transaction secret key, see NewPaymentProof
+	Coinstake *Coinstake           // nil for PoW blocks
+}
+
+// BuildMinerTx builds a miner transaction paying p.Reward to p.Address, with its range
+// and balance proofs. Version 3 transactions (after HF5) also carry p.HardforkId. PoW
+// miner transactions are complete, while PoS ones must be signed with Sign once the block
+// hash is known.
+func BuildMinerTx(rnd io.Reader, p *MinerTxParams) (*MinerTx, error) {
+	if p.Address == nil {
+		return nil, errors.New("miner transaction requires an address")
+	}
+	if s := p.Stake; s != nil {
+		if s.Wallet == nil || s.Source == nil {
+			return nil, errors.New("stake requires a wallet and a source")
+		}
+		tmpl := &StakeTemplate{
+			Height:        p.Height,
+			Timestamp:     s.Timestamp,
+			Reward:        p.Reward,
+			StakeModifier: s.StakeModifier,
+			Difficulty:    s.Difficulty,
+			TxVersion:     p.TxVersion,
+			HardforkId:    p.HardforkId,
+		}
+		cs, err := s.Wallet.buildCoinstake(rnd, tmpl, s.Source, p.Address, p.ExtraNonce)
+		if err != nil {
+			return nil, err
+		}
+		return &MinerTx{Tx: cs.Tx, TxKey: cs.TxKey, Coinstake: cs}, nil
+	}
+
+	tx, err := newMinerTx(p.TxVersion, p.HardforkId)
+	if err != nil {
+		return nil, err
+	}
+	tx.Vin = []*zanobase.Variant{zanobase.VariantFor(&zanobase.TxInGen{Height: p.Height})}
+	// zero amount outputs up to MinOutputs, as for regular transactions
+	dests := []*TxDest{minerDest(p.Address, p.Reward)}
+	for len(dests) < MinOutputs {
+		dests = append(dests, minerDest(p.Address, 0))
+	}
+	ogc, err := buildMinerTx(rnd, tx, p.Height, dests, 0, p.ExtraNonce)
+	if err != nil {
+		return nil, err
+	}
+	if err := minerTxProofs(rnd, tx, ogc, p.Reward); err != nil {
+		return nil, err
+	}
+	return &MinerTx{Tx: tx, TxKey: ogc.TxKey.Sec.Scalar}, nil
+}
+
+// Sign adds the Zarcanum proof of PoS miner transactions for the given block hash, see
+// Coinstake.Sign. It does nothing for PoW miner transactions.
+func (m *MinerTx) Sign(rnd io.Reader, blockHash []byte) error {
+	if m.Coinstake == nil {
+		return nil
+	}
+	return m.Coinstake.Sign(rnd, blockHash)
+}
+
+// newMinerTx returns an empty miner transaction of the given version, 0 meaning
+// zanobase.TransactionVersionPostHF4
+func newMinerTx(version uint64, hardforkId uint8) (*zanobase.Transaction, error) {
+	if version == 0 {
+		version = zanobase.TransactionVersionPostHF4
+	}
+	if version < zanobase.TransactionVersionPostHF4 || version > zanobase.TransactionVersionPostHF5 {
+		return nil, fmt.Errorf("unsupported transaction version %d", version)
+	}
+	if version < zanobase.TransactionVersionPostHF5 && hardforkId != 0 {
+		return nil, fmt.Errorf("transaction version %d has no hardfork id", version)
+	}
+	return &zanobase.Transaction{Version: zanobase.Varint(version), HardforkId: hardforkId}, nil
+}
+
+func minerDest(addr *Address, amount uint64) *TxDest {
+	return &TxDest{
+		Amount:      amount,
+		Addr:        []*zanobase.AccountPublicAddr{addr.Account()},
+		HtlcOptions: &TxDestHtlcOut{},
+		AssetId:     &zanobase.Point{zanocrypto.NativeCoinAssetIdPt},
+	}
+}
+
+// buildMinerTx fills the extra and outputs of the miner transaction tx, which already has
+// its inputs. Outputs of miner transactions use the explicit native asset id (no asset id
+// blinding), so no asset surjection proof is needed. zcInputs is the number of ZC inputs.
+func buildMinerTx(rnd io.Reader, tx *zanobase.Transaction, height uint64, dests []*TxDest, zcInputs int, extraNonce []byte) (*zanobase.GenContext, error) {
+	txKey := zanocrypto.RandomScalar(rnd)
+	txPub := new(edwards25519.Point).ScalarBaseMult(txKey)
+	ogc := &zanobase.GenContext{
+		AoAmountBlindingMask:          &zanobase.Scalar{zanocrypto.ScalarInt(0)},
+		AssetIdBlindingMaskXAmountSum: &zanobase.Scalar{zanocrypto.ScalarInt(0)},
+		TxPubKeyP:                     &zanobase.Point{txPub},
+		TxKey:                         &zanobase.KeyPair{Sec: &zanobase.Scalar{txKey}, Pub: &zanobase.Point{txPub}},
+	}
+	ogc.Resize(zcInputs, len(dests))
+
+	var pubV zanobase.Value256
+	copy(pubV[:], txPub.Bytes())
+	tx.Extra = append(tx.Extra, &zanobase.Variant{Tag: zanobase.TagPubKey, Value: pubV})
+	if len(extraNonce) > 0 {
+		tx.Extra = append(tx.Extra, zanobase.VariantFor(&zanobase.ExtraUserData{Buff: string(extraNonce)}))
+	}
+	tx.Extra = append(tx.Extra, zanobase.VariantFor(&zanobase.EtcTxDetailsUnlockTime{V: height + MinedMoneyUnlockWindow}))
+
+	hints := make(map[uint16]bool)
+	for i, dst := range dests {
+		outputIndex := len(tx.Vout)
+		dstViewKey, err := new(edwards25519.Point).SetBytes(dst.Addr[0].ViewKey[:])
+		if err != nil {
+			return nil, err
+		}
+		derivation, err := zanocrypto.GenerateKeyDerivation(dstViewKey, txKey)
+		if err != nil {
+			return nil, err
+		}
+		hints[zanocrypto.DerivationHint(derivation)] = true
+		scalar := zanocrypto.HashToScalar(slices.Concat(derivation.Bytes(), zanobase.Varint(outputIndex).Bytes()))
+
+		amountMask := zanocrypto.HashToScalar(slices.Concat([]byte("ZANO_HDS_OUT_AMOUNT_MASK_______\x00"), scalar.Bytes()))
+		amountBlindingMask := zanocrypto.HashToScalar(slices.Concat(CRYPTO_HDS_OUT_AMOUNT_BLINDING_MASK, scalar.Bytes()))
+		ogc.AmountBlindingMasks[i] = &zanobase.Scalar{amountBlindingMask}
+
+		// explicit native asset id: T = H, s = 0
+		ogc.AssetIds[i] = dst.AssetId
+		ogc.AssetIdBlindingMasks[i] = &zanobase.Scalar{zanocrypto.ScalarInt(0)}
+		ogc.BlindedAssetIds[i] = &zanobase.Point{new(edwards25519.Point).Set(dst.AssetId.Point)}
+
+		vout := &zanobase.TxOutZarcanium{
+			EncryptedAmount: dst.Amount ^ binary.LittleEndian.Uint64(amountMask.Bytes()[:8]),
+		}
+		copy(vout.StealthAddress[:], dst.StealthAddress(scalar, ogc, i).Bytes())
+		copy(vout.ConcealingPoint[:], dst.ConcealingPoint(scalar, ogc, i).Bytes())
+		copy(vout.BlindedAssetId[:], new(edwards25519.Point).ScalarMult(zanocrypto.Sc1div8, dst.AssetId.Point).Bytes())
+		copy(vout.AmountCommitment[:], dst.AmountCommitment(scalar, ogc, i).Bytes())
+		if dst.Addr[0].Flags&1 == 1 {
+			vout.MixAttr = 1 // CURRENCY_TO_KEY_OUT_FORCED_NO_MIX
+		}
+
+		ogc.Amounts[i] = &zanobase.Scalar{zanocrypto.ScalarInt(dst.Amount)}
+		addRefScalar(&ogc.AmountBlindingMasksSum, amountBlindingMask)
+		addRefPoint(&ogc.AmountCommitmentsSum, ogc.AmountCommitments[i].Point)
+
+		tx.Vout = append(tx.Vout, zanobase.VariantFor(vout))
+	}
+
+	hintsArray := make([]uint16, 0, len(hints))
+	for hint := range hints {
+		hintsArray = append(hintsArray, hint)
+	}
+	slices.Sort(hintsArray)
+	for _, hint := range hintsArray {
+		tx.Extra = append(tx.Extra, &zanobase.Variant{Tag: zanobase.TagDerivationHint, Value: []byte{byte(hint & 0xff), byte((hint >> 8) & 0xff)}})
+	}
+	return ogc, nil
+}
+
+// minerTxProofs appends the range and balance proofs of a miner transaction
+func minerTxProofs(rnd io.Reader, tx *zanobase.Transaction, ogc *zanobase.GenContext, reward uint64) error {
+	txId, err := tx.Prefix().Hash()
+	if err != nil {
+		return err
+	}
+	if err := zanoproof.GenerateZcOutsRangeProof(rnd, tx, txId, ogc); err != nil {
+		return fmt.Errorf("while generating zc outs range proof: %w", err)
+	}
+	if err := zanoproof.GenerateTxBalanceProof(rnd, tx, txId, ogc, reward); err != nil {
+		return fmt.Errorf("while generating tx balance proof: %w", err)
+	}
+	return nil
+}
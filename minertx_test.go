@@ -0,0 +1,150 @@
+package zanolib_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+func TestBuildMinerTx(t *testing.T) {
+	w := testWallet(t)
+	const reward = 1000000000000
+
+	m, err := zanolib.BuildMinerTx(rand.Reader, &zanolib.MinerTxParams{
+		Height:     3000000,
+		Reward:     reward,
+		Address:    w.Address(),
+		ExtraNonce: []byte("pool"),
+	})
+	if err != nil {
+		t.Fatalf("failed to build miner tx: %s", err)
+	}
+	tx := m.Tx
+	if !tx.IsCoinbase() || len(tx.Vin) != 1 || len(tx.Vout) != zanolib.MinOutputs || len(tx.Proofs) != 2 {
+		t.Fatalf("unexpected miner transaction")
+	}
+	if in := tx.Vin[0].Value.(*zanobase.TxInGen); in.Height != 3000000 {
+		t.Errorf("height = %d", in.Height)
+	}
+	var unlock uint64
+	var nonce string
+	for _, e := range tx.Extra {
+		switch v := e.Value.(type) {
+		case *zanobase.EtcTxDetailsUnlockTime:
+			unlock = v.V
+		case *zanobase.ExtraUserData:
+			nonce = v.Buff
+		}
+	}
+	if unlock != 3000000+zanolib.MinedMoneyUnlockWindow || nonce != "pool" {
+		t.Errorf("unexpected extra: unlock time %d, nonce %q", unlock, nonce)
+	}
+	// PoW miner transactions need no signature
+	if err := m.Sign(rand.Reader, make([]byte, 32)); err != nil || len(tx.Signatures) != 0 {
+		t.Errorf("PoW miner tx signed: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if _, err := tx.WriteTo(buf); err != nil {
+		t.Fatalf("failed to serialize: %s", err)
+	}
+	parsed := new(zanobase.Transaction)
+	if _, err := parsed.ReadFrom(buf); err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	res := must(w.ScanTransaction(parsed))
+	if total := res.Total(zanolib.NativeCoinAssetId); total != reward {
+		t.Errorf("received %d, expected %d", total, reward)
+	}
+	// the pool can prove the payment with the transaction key
+	if outs, err := zanolib.CheckTxKey(parsed, m.TxKey, w.Address()); err != nil || len(outs) != zanolib.MinOutputs {
+		t.Errorf("CheckTxKey: %v", err)
+	}
+}
+
+func TestBuildMinerTxPoS(t *testing.T) {
+	w := testWallet(t)
+	prev := must(w.Sign(rand.Reader, testSignableFTP(t, w), nil))
+	scan := must(w.ScanTransaction(prev.Tx))
+	owned := must(scan.OwnedOutput(prev.Tx, scan.Outputs[0], 2500))
+	ring, realOut, err := zanolib.BuildRing(testRing{}, owned, 4)
+	if err != nil {
+		t.Fatalf("failed to build ring: %s", err)
+	}
+	stake := &zanolib.MinerTxStake{
+		Wallet: w,
+		Source: &zanolib.TxSource{
+			Outputs:                    ring,
+			RealOutput:                 uint64(realOut),
+			RealOutTxKey:               &zanobase.Point{owned.TxPubKey.ToPoint()},
+			RealOutAmountBlindingMask:  owned.AmountBlindingMask,
+			RealOutAssetIdBlindingMask: owned.AssetIdBlindingMask,
+			RealOutInTxIndex:           owned.Index,
+			Amount:                     owned.Amount,
+		},
+		Timestamp:  1760000000,
+		Difficulty: big.NewInt(1),
+	}
+	m, err := zanolib.BuildMinerTx(rand.Reader, &zanolib.MinerTxParams{Height: 3000000, Reward: 100, Address: w.Address(), Stake: stake})
+	if err != nil {
+		t.Fatalf("failed to build PoS miner tx: %s", err)
+	}
+	if m.Coinstake == nil || len(m.Tx.Vin) != 2 {
+		t.Fatalf("unexpected PoS miner transaction")
+	}
+	blockHash := make([]byte, 32)
+	rand.Read(blockHash)
+	if err := m.Sign(rand.Reader, blockHash); err != nil {
+		t.Fatalf("failed to sign: %s", err)
+	}
+	if err := zanolib.VerifyCoinstake(m.Tx, blockHash, m.Coinstake.Template, ring); err != nil {
+		t.Errorf("VerifyCoinstake: %s", err)
+	}
+	if total := must(w.ScanTransaction(m.Tx)).Total(zanolib.NativeCoinAssetId); total != 100+390 {
+		t.Errorf("received %d", total)
+	}
+
+	// version 3 after HF5
+	m, err = zanolib.BuildMinerTx(rand.Reader, &zanolib.MinerTxParams{Height: 3000000, Reward: 100, Address: w.Address(), Stake: stake, TxVersion: 3, HardforkId: 5})
+	if err != nil {
+		t.Fatalf("failed to build v3 PoS miner tx: %s", err)
+	}
+	if err := m.Sign(rand.Reader, blockHash); err != nil {
+		t.Fatalf("failed to sign v3: %s", err)
+	}
+	if m.Tx.Version != 3 || m.Tx.HardforkId != 5 || m.Coinstake.Template.HardforkId != 5 {
+		t.Errorf("unexpected version %d, hardfork id %d", m.Tx.Version, m.Tx.HardforkId)
+	}
+	if err := zanolib.VerifyCoinstake(m.Tx, blockHash, m.Coinstake.Template, ring); err != nil {
+		t.Errorf("VerifyCoinstake v3: %s", err)
+	}
+}
+
+func TestBuildMinerTxVersion(t *testing.T) {
+	w := testWallet(t)
+	m, err := zanolib.BuildMinerTx(rand.Reader, &zanolib.MinerTxParams{Height: 3000000, Reward: 100, Address: w.Address(), TxVersion: 3, HardforkId: 5})
+	if err != nil {
+		t.Fatalf("failed to build v3 miner tx: %s", err)
+	}
+	parsed := must(zanobase.ParseTransaction(must(m.Tx.Bytes())))
+	if parsed.Version != 3 || parsed.HardforkId != 5 {
+		t.Errorf("unexpected version %d, hardfork id %d", parsed.Version, parsed.HardforkId)
+	}
+	if total := must(w.ScanTransaction(parsed)).Total(zanolib.NativeCoinAssetId); total != 100 {
+		t.Errorf("received %d", total)
+	}
+
+	for _, p := range []*zanolib.MinerTxParams{
+		{Address: w.Address(), HardforkId: 5},
+		{Address: w.Address(), TxVersion: 1},
+		{Address: w.Address(), TxVersion: 4},
+	} {
+		if _, err := zanolib.BuildMinerTx(rand.Reader, p); err == nil {
+			t.Errorf("version %d with hardfork id %d should be rejected", p.TxVersion, p.HardforkId)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package zanolib
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+// PaymentIdServiceId is the service id of the tx_service_attachment carrying a payment id
+// (BC_PAYMENT_ID_SERVICE_ID)
+const PaymentIdServiceId = "d"
+
+// PaymentIdAttachment returns an attachment holding the given payment id. Its body is
+// flagged to be encrypted for the crypt address of the transaction when signing.
+func PaymentIdAttachment(paymentId []byte) *zanobase.Variant {
+	return zanobase.VariantFor(&zanobase.TxServiceAttachment{
+		ServiceId: PaymentIdServiceId,
+		Body:      bytes.Clone(paymentId),
+		Flags:     zanobase.TxServiceAttachmentEncryptBody,
+	})
+}
+
+// GetPaymentId returns the payment id found in the given attachments, or nil if there is
+// none. Attachments must have been decrypted first, see Wallet.DecryptAttachments.
+func GetPaymentId(attachments []*zanobase.Variant) []byte {
+	for _, a := range attachments {
+		if sa, ok := a.Value.(*zanobase.TxServiceAttachment); ok && sa.ServiceId == PaymentIdServiceId {
+			return sa.Body
+		}
+	}
+	return nil
+}
+
+// SetPaymentId prepares ftp to pay the given integrated address: the address' payment id
+// replaces any payment id found in the attachments, and the address becomes the crypt
+// address so the payment id can only be read by the recipient (and the sender).
+func (ftp *FinalizeTxParam) SetPaymentId(addr *Address) error {
+	if len(addr.PaymentId) == 0 {
+		return errors.New("address has no payment id")
+	}
+	att := make([]*zanobase.Variant, 0, len(ftp.Attachments)+1)
+	for _, a := range ftp.Attachments {
+		if sa, ok := a.Value.(*zanobase.TxServiceAttachment); ok && sa.ServiceId == PaymentIdServiceId {
+			continue
+		}
+		att = append(att, a)
+	}
+	ftp.Attachments = append(att, PaymentIdAttachment(addr.PaymentId))
+	ftp.CryptAddress = addr.Account()
+	return nil
+}
@@ -0,0 +1,177 @@
+package zanolib
+
+import (
+	"errors"
+	"io"
+	"slices"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+// paymentProofDomain separates payment proof challenges from other hashes
+var paymentProofDomain = []byte("ZANOLIB_PAYMENT_PROOF_V1\x00")
+
+// PaymentProof proves that a transaction sent outputs to an address, without revealing any
+// secret key. It discloses the shared secret D between the transaction key and the
+// address view key (D = r*V = v*R), along with a proof that D was computed either by the
+// sender from the transaction secret key r, or by the recipient from the view secret key
+// v. Anyone holding D can decode the amounts of the outputs sent to the address.
+type PaymentProof struct {
+	TxId     zanobase.Value256 `json:"txid"`
+	Address  string            `json:"address"`
+	Shared   zanobase.Value256 `json:"shared"`   // D = r*V = v*R
+	Outbound bool              `json:"outbound"` // true if made by the sender with the transaction secret key
+	C        *zanobase.Scalar  `json:"c"`
+	S        *zanobase.Scalar  `json:"s"`
+}
+
+// NewPaymentProof returns a proof, made by the sender using the transaction secret key (see
+// FinalizedTx.OneTimeKey), that tx paid addr.
+func NewPaymentProof(rnd io.Reader, tx *zanobase.Transaction, txKey *edwards25519.Scalar, addr *Address) (*PaymentProof, error) {
+	viewKey, err := new(edwards25519.Point).SetBytes(addr.ViewKey)
+	if err != nil {
+		return nil, err
+	}
+	txPub, err := txPubKeyPoint(tx)
+	if err != nil {
+		return nil, err
+	}
+	if new(edwards25519.Point).ScalarBaseMult(txKey).Equal(txPub) != 1 {
+		return nil, errors.New("secret key does not match the transaction public key")
+	}
+	return newPaymentProof(rnd, tx, addr, true, viewKey, txKey)
+}
+
+// PaymentProof returns a proof, made by the recipient using its view key, that tx paid w.
+// addr can be an integrated address of w, or nil to use w.Address().
+func (w *Wallet) PaymentProof(rnd io.Reader, tx *zanobase.Transaction, addr *Address) (*PaymentProof, error) {
+	if addr == nil {
+		addr = w.Address()
+	} else if !addr.SameKeys(w.Address()) {
+		return nil, errors.New("address does not belong to this wallet")
+	}
+	txPub, err := txPubKeyPoint(tx)
+	if err != nil {
+		return nil, err
+	}
+	return newPaymentProof(rnd, tx, addr, false, txPub, w.ViewPrivKey)
+}
+
+// newPaymentProof proves that D = secret*base is consistent with the public key of secret,
+// which is R for outbound proofs and V for inbound proofs
+func newPaymentProof(rnd io.Reader, tx *zanobase.Transaction, addr *Address, outbound bool, base *edwards25519.Point, secret *edwards25519.Scalar) (*PaymentProof, error) {
+	txId, err := tx.Hash()
+	if err != nil {
+		return nil, err
+	}
+	res := &PaymentProof{
+		TxId:     zanobase.Value256(txId),
+		Address:  addr.String(),
+		Shared:   zanobase.Value256(new(edwards25519.Point).ScalarMult(secret, base).Bytes()),
+		Outbound: outbound,
+	}
+	pub := new(edwards25519.Point).ScalarBaseMult(secret)
+
+	// Chaum-Pedersen proof that log_G(pub) = log_base(D)
+	k := zanocrypto.RandomScalar(rnd)
+	k1 := new(edwards25519.Point).ScalarBaseMult(k)
+	k2 := new(edwards25519.Point).ScalarMult(k, base)
+	c := res.challenge(addr, pub, k1, k2)
+	res.C = &zanobase.Scalar{c}
+	res.S = &zanobase.Scalar{new(edwards25519.Scalar).Subtract(k, new(edwards25519.Scalar).Multiply(c, secret))}
+	return res, nil
+}
+
+func (p *PaymentProof) challenge(addr *Address, pub, k1, k2 *edwards25519.Point) *edwards25519.Scalar {
+	var flag byte
+	if p.Outbound {
+		flag = 1
+	}
+	return zanocrypto.HashToScalar(slices.Concat(paymentProofDomain, []byte{flag}, p.TxId[:], addr.SpendKey, addr.ViewKey, pub.Bytes(), p.Shared[:], k1.Bytes(), k2.Bytes()))
+}
+
+// Verify checks the proof against tx and returns the outputs of tx sent to the proof's
+// address, with their decoded amount and asset id. An error is returned if the proof is
+// invalid or if tx has no output for the address.
+func (p *PaymentProof) Verify(tx *zanobase.Transaction) ([]*ReceivedOutput, error) {
+	if p.C == nil || p.S == nil || p.C.Scalar == nil || p.S.Scalar == nil {
+		return nil, errors.New("incomplete payment proof")
+	}
+	txId, err := tx.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(txId, p.TxId[:]) {
+		return nil, errors.New("payment proof is for another transaction")
+	}
+	addr, err := ParseAddress(p.Address)
+	if err != nil {
+		return nil, err
+	}
+	viewKey, err := new(edwards25519.Point).SetBytes(addr.ViewKey)
+	if err != nil {
+		return nil, err
+	}
+	txPub, err := txPubKeyPoint(tx)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := new(edwards25519.Point).SetBytes(p.Shared[:])
+	if err != nil {
+		return nil, err
+	}
+
+	pub, base := viewKey, txPub
+	if p.Outbound {
+		pub, base = txPub, viewKey
+	}
+	// k1 = s*G + c*pub, k2 = s*base + c*D
+	k1 := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(p.C.Scalar, pub, p.S.Scalar)
+	k2 := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{p.S.Scalar, p.C.Scalar}, []*edwards25519.Point{base, shared})
+	if p.challenge(addr, pub, k1, k2).Equal(p.C.Scalar) != 1 {
+		return nil, errors.New("invalid payment proof")
+	}
+	return CheckPayment(tx, shared, addr)
+}
+
+// CheckTxKey returns the outputs of tx sent to addr, using the transaction secret key as
+// disclosed by the sender. The key is checked against the transaction public key.
+func CheckTxKey(tx *zanobase.Transaction, txKey *edwards25519.Scalar, addr *Address) ([]*ReceivedOutput, error) {
+	viewKey, err := new(edwards25519.Point).SetBytes(addr.ViewKey)
+	if err != nil {
+		return nil, err
+	}
+	txPub, err := txPubKeyPoint(tx)
+	if err != nil {
+		return nil, err
+	}
+	if new(edwards25519.Point).ScalarBaseMult(txKey).Equal(txPub) != 1 {
+		return nil, errors.New("secret key does not match the transaction public key")
+	}
+	return CheckPayment(tx, new(edwards25519.Point).ScalarMult(txKey, viewKey), addr)
+}
+
+// CheckPayment returns the outputs of tx sent to addr given the shared secret D = r*V,
+// with their decoded amount and asset id. Malformed outputs are ignored.
+func CheckPayment(tx *zanobase.Transaction, shared *edwards25519.Point, addr *Address) ([]*ReceivedOutput, error) {
+	spendKey, err := new(edwards25519.Point).SetBytes(addr.SpendKey)
+	if err != nil {
+		return nil, err
+	}
+	derivation := new(edwards25519.Point).MultByCofactor(shared)
+	res, _ := scanOutputs(tx, derivation, spendKey)
+	if len(res) == 0 {
+		return nil, errors.New("transaction has no output for this address")
+	}
+	return res, nil
+}
+
+func txPubKeyPoint(tx *zanobase.Transaction) (*edwards25519.Point, error) {
+	pub, err := txPubKey(tx)
+	if err != nil {
+		return nil, err
+	}
+	return new(edwards25519.Point).SetBytes(pub[:])
+}
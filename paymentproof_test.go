@@ -0,0 +1,70 @@
+package zanolib_test
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/ModChain/zanolib"
+)
+
+func TestPaymentProof(t *testing.T) {
+	w := testWallet(t)
+	recipient := must(zanolib.LoadSpendSecret(make([]byte, 32), 0))
+
+	ftp := testSignableFTP(t, w)
+	ftp.PreparedDestinations[0].Addr = testAccount(recipient.Address())
+	res := must(w.Sign(rand.Reader, ftp, nil))
+	tx := res.Tx
+
+	check := func(name string) func([]*zanolib.ReceivedOutput, error) {
+		return func(outs []*zanolib.ReceivedOutput, err error) {
+			if err != nil {
+				t.Errorf("%s: %s", name, err)
+				return
+			}
+			if len(outs) != 1 || outs[0].Amount != 600 || outs[0].AssetId != zanolib.NativeCoinAssetId {
+				t.Errorf("%s: unexpected outputs %+v", name, outs)
+			}
+		}
+	}
+
+	check("tx key")(zanolib.CheckTxKey(tx, res.OneTimeKey.Scalar, recipient.Address()))
+
+	sender := must(zanolib.NewPaymentProof(rand.Reader, tx, res.OneTimeKey.Scalar, recipient.Address()))
+	check("outbound")(sender.Verify(tx))
+	inbound := must(recipient.PaymentProof(rand.Reader, tx, nil))
+	check("inbound")(inbound.Verify(tx))
+	if sender.Shared != inbound.Shared {
+		t.Errorf("sender and recipient shared secrets differ")
+	}
+
+	// json round trip
+	var dec zanolib.PaymentProof
+	if err := json.Unmarshal(must(json.Marshal(sender)), &dec); err != nil {
+		t.Fatalf("failed to decode proof: %s", err)
+	}
+	check("decoded")(dec.Verify(tx))
+
+	// tampering
+	dec.Outbound = false
+	if _, err := dec.Verify(tx); err == nil {
+		t.Errorf("proof with flipped direction was accepted")
+	}
+	dec.Outbound = true
+	dec.Address = w.Address().String()
+	if _, err := dec.Verify(tx); err == nil {
+		t.Errorf("proof for another address was accepted")
+	}
+	if _, err := w.PaymentProof(rand.Reader, tx, recipient.Address()); err == nil {
+		t.Errorf("proof for a foreign address was created")
+	}
+
+	// the sender's change is not a payment to the recipient, but is to the sender
+	if _, err := zanolib.NewPaymentProof(rand.Reader, tx, res.OneTimeKey.Scalar, w.Address()); err != nil {
+		t.Errorf("failed to prove change: %s", err)
+	}
+	if _, err := zanolib.CheckTxKey(tx, must(zanolib.LoadSpendSecret(make([]byte, 32), 0)).ViewPrivKey, recipient.Address()); err == nil {
+		t.Errorf("wrong tx key was accepted")
+	}
+}
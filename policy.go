@@ -0,0 +1,250 @@
+package zanolib
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+// NativeCoinAssetId is the asset id of Zano's native coin, as hex. It is used as the key
+// for the native coin in per-asset maps.
+const NativeCoinAssetId = "d6329b5b1f7c0805b5c345f4957554002a2f557845f64d7645dae0e051a6498a"
+
+// Policy defines rules a FinalizeTxParam must satisfy before it gets signed. It is meant
+// to be evaluated in front of Wallet.Sign for automated signatures.
+//
+// Zero values disable the corresponding check. Asset ids are given as hex strings, see
+// NativeCoinAssetId. Destinations going back to the signing wallet (change) are never
+// subject to destination lists or amount limits.
+type Policy struct {
+	AllowedDestinations []string          `json:"allowed_destinations,omitempty"` // if not empty, only these addresses can receive funds
+	DeniedDestinations  []string          `json:"denied_destinations,omitempty"`  // addresses that can never receive funds
+	MaxAmount           map[string]uint64 `json:"max_amount,omitempty"`           // maximum amount per asset sent to others in a single transaction
+	DailyLimit          map[string]uint64 `json:"daily_limit,omitempty"`          // maximum amount per asset sent to others over 24 hours, requires Ledger
+	MaxFee              uint64            `json:"max_fee,omitempty"`              // maximum fee in native coin atomic units
+	MaxFeePerKB         uint64            `json:"max_fee_per_kb,omitempty"`       // maximum fee relative to the estimated transaction size
+	RequireChangeToSelf bool              `json:"require_change_to_self,omitempty"`
+	AllowUnlockTime     bool              `json:"allow_unlock_time,omitempty"`    // if false, any unlock time causes a rejection
+	MaxUnlockTime       uint64            `json:"max_unlock_time,omitempty"`      // maximum unlock time value (height or timestamp) if AllowUnlockTime is set
+	RequireExpiration   bool              `json:"require_expiration,omitempty"`   // transaction must have an expiration time
+	MaxExpirationDelay  uint64            `json:"max_expiration_delay,omitempty"` // maximum delay in seconds between now and the expiration time
+	Ledger              SpendLedger       `json:"-"`                              // used for DailyLimit
+}
+
+// PolicyDecision is the result of a policy evaluation. If Allowed is false, Reasons will
+// contain at least one human readable reason.
+type PolicyDecision struct {
+	Allowed bool     `json:"allowed"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+func (d *PolicyDecision) deny(format string, args ...any) {
+	d.Allowed = false
+	d.Reasons = append(d.Reasons, fmt.Sprintf(format, args...))
+}
+
+// SpendLedger keeps track of amounts sent by a wallet so that daily limits can be applied.
+type SpendLedger interface {
+	// Spent returns the total amount of the given asset sent since the given time
+	Spent(assetId string, since time.Time) (uint64, error)
+	// Record stores an amount of the given asset as being sent at the given time
+	Record(assetId string, amount uint64, at time.Time) error
+}
+
+// Evaluate checks the given FinalizeTxParam against the policy. An error is returned only
+// if the policy itself could not be applied (invalid address in a list, ledger failure),
+// a transaction not matching the policy results in a decision with Allowed set to false.
+func (p *Policy) Evaluate(w *Wallet, ftp *FinalizeTxParam, now time.Time) (*PolicyDecision, error) {
+	allowed, err := parseAddressList(p.AllowedDestinations)
+	if err != nil {
+		return nil, fmt.Errorf("in allowed destinations: %w", err)
+	}
+	denied, err := parseAddressList(p.DeniedDestinations)
+	if err != nil {
+		return nil, fmt.Errorf("in denied destinations: %w", err)
+	}
+
+	sum := ftp.Summary(w)
+	res := &PolicyDecision{Allowed: true}
+	for _, warn := range sum.Warnings {
+		res.deny("%s", warn)
+	}
+
+	var hasChange bool
+	for n, dst := range ftp.PreparedDestinations {
+		if len(dst.Addr) == 0 {
+			res.deny("destination #%d has no address", n)
+			continue
+		}
+		if sum.Destinations[n].Change {
+			hasChange = true
+		} else {
+			for _, acc := range dst.Addr {
+				addr := AddressFromAccount(acc)
+				if len(allowed) > 0 && !addressInList(addr, allowed) {
+					res.deny("destination #%d address %s is not in the allowed list", n, addr)
+				}
+				if addressInList(addr, denied) {
+					res.deny("destination #%d address %s is denied", n, addr)
+				}
+			}
+		}
+		if dst.UnlockTime != 0 {
+			p.checkUnlockTime(res, dst.UnlockTime, fmt.Sprintf("destination #%d", n))
+		}
+	}
+
+	if p.RequireChangeToSelf && !hasChange {
+		res.deny("transaction has no change going back to this wallet")
+	}
+
+	if p.MaxFee != 0 && sum.Fee > p.MaxFee {
+		res.deny("fee %d exceeds maximum fee %d", sum.Fee, p.MaxFee)
+	}
+	if p.MaxFeePerKB != 0 {
+		if size, err := ftp.EstimateSize(); err != nil {
+			res.deny("could not estimate transaction size: %s", err)
+		} else if max := FeeForSize(size, p.MaxFeePerKB); sum.Fee > max {
+			res.deny("fee %d exceeds maximum fee %d for a %d bytes transaction", sum.Fee, max, size)
+		}
+	}
+
+	for asset, amount := range sum.Sent {
+		if limit, ok := p.MaxAmount[asset]; ok && amount > limit {
+			res.deny("amount %d of asset %s exceeds per transaction limit %d", amount, asset, limit)
+		}
+		if limit, ok := p.DailyLimit[asset]; ok {
+			if p.Ledger == nil {
+				return nil, errors.New("daily limit set without a ledger")
+			}
+			spent, err := p.Ledger.Spent(asset, now.Add(-24*time.Hour))
+			if err != nil {
+				return nil, fmt.Errorf("while reading ledger: %w", err)
+			}
+			if amount > limit || spent > limit-amount {
+				res.deny("amount %d of asset %s would exceed daily limit %d (already sent %d)", amount, asset, limit, spent)
+			}
+		}
+	}
+
+	if ftp.UnlockTime != 0 {
+		p.checkUnlockTime(res, ftp.UnlockTime, "transaction")
+	}
+
+	if ftp.ExpirationTime == 0 {
+		if p.RequireExpiration {
+			res.deny("transaction has no expiration time")
+		}
+	} else {
+		exp := time.Unix(int64(ftp.ExpirationTime), 0)
+		if !exp.After(now) {
+			res.deny("transaction expired at %s", exp.UTC().Format(time.RFC3339))
+		} else if p.MaxExpirationDelay != 0 && exp.Sub(now) > time.Duration(p.MaxExpirationDelay)*time.Second {
+			res.deny("expiration time %s is too far in the future", exp.UTC().Format(time.RFC3339))
+		}
+	}
+
+	return res, nil
+}
+
+// Record stores the amounts sent to others by ftp into the policy's ledger. It should be
+// called once the transaction has been signed, so daily limits account for it.
+func (p *Policy) Record(w *Wallet, ftp *FinalizeTxParam, now time.Time) error {
+	if p.Ledger == nil {
+		return nil
+	}
+	for asset, amount := range ftp.Summary(w).Sent {
+		if err := p.Ledger.Record(asset, amount, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Policy) checkUnlockTime(res *PolicyDecision, unlockTime uint64, what string) {
+	if !p.AllowUnlockTime {
+		res.deny("%s has an unlock time (%d)", what, unlockTime)
+		return
+	}
+	if p.MaxUnlockTime != 0 && unlockTime > p.MaxUnlockTime {
+		res.deny("%s unlock time %d exceeds maximum %d", what, unlockTime, p.MaxUnlockTime)
+	}
+}
+
+// isOwnAccount returns true if the given destination is exactly this wallet
+func (w *Wallet) isOwnAccount(addr []*zanobase.AccountPublicAddr) bool {
+	if len(addr) != 1 {
+		return false
+	}
+	return addr[0].SpendKey == zanobase.Value256(w.SpendPubKey.Bytes()) && addr[0].ViewKey == zanobase.Value256(w.ViewPubKey.Bytes())
+}
+
+func destAssetId(dst *TxDest) string {
+	return hex.EncodeToString(dst.assetId().Bytes())
+}
+
+func parseAddressList(list []string) ([]*Address, error) {
+	res := make([]*Address, 0, len(list))
+	for _, s := range list {
+		addr, err := ParseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %s: %w", s, err)
+		}
+		res = append(res, addr)
+	}
+	return res, nil
+}
+
+func addressInList(addr *Address, list []*Address) bool {
+	for _, a := range list {
+		if a.SameKeys(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// MemoryLedger is a SpendLedger keeping records in memory. Records older than 24 hours
+// are discarded when new records are added.
+type MemoryLedger struct {
+	lk      sync.Mutex
+	records []memoryLedgerRecord
+}
+
+type memoryLedgerRecord struct {
+	asset  string
+	amount uint64
+	at     time.Time
+}
+
+func (l *MemoryLedger) Spent(assetId string, since time.Time) (uint64, error) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	var res uint64
+	for _, r := range l.records {
+		if r.asset == assetId && r.at.After(since) {
+			res += r.amount
+		}
+	}
+	return res, nil
+}
+
+func (l *MemoryLedger) Record(assetId string, amount uint64, at time.Time) error {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	limit := at.Add(-24 * time.Hour)
+	records := l.records[:0]
+	for _, r := range l.records {
+		if r.at.After(limit) {
+			records = append(records, r)
+		}
+	}
+	l.records = append(records, memoryLedgerRecord{asset: assetId, amount: amount, at: at})
+	return nil
+}
@@ -0,0 +1,92 @@
+package zanolib_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+func testWallet(t *testing.T) *zanolib.Wallet {
+	secret := make([]byte, 32)
+	for n := range secret[:31] {
+		secret[n] = byte(n + 1)
+	}
+	w, err := zanolib.LoadSpendSecret(secret, 0)
+	if err != nil {
+		t.Fatalf("failed to load wallet: %s", err)
+	}
+	return w
+}
+
+func testAccount(addr *zanolib.Address) []*zanobase.AccountPublicAddr {
+	return []*zanobase.AccountPublicAddr{addr.Account()}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	w := testWallet(t)
+	other := must(zanolib.ParseAddress("ZxD5aoLDPTdcaRx4uCpyW4XiLfEXejepAVz8cSY2fwHNEiJNu6NmpBBDLGTJzCsUvn3acCVDVDPMV8yQXdPooAp338Se7AxeH"))
+	now := time.Unix(1700000000, 0)
+
+	ftp := &zanolib.FinalizeTxParam{
+		Sources: []*zanolib.TxSource{{Amount: 1000}},
+		PreparedDestinations: []*zanolib.TxDest{
+			{Amount: 600, Addr: testAccount(other)},
+			{Amount: 390, Addr: testAccount(w.Address())},
+		},
+		SpendPubKey: &zanobase.Point{w.SpendPubKey},
+	}
+
+	p := &zanolib.Policy{
+		AllowedDestinations: []string{other.String()},
+		MaxAmount:           map[string]uint64{zanolib.NativeCoinAssetId: 1000},
+		DailyLimit:          map[string]uint64{zanolib.NativeCoinAssetId: 1000},
+		MaxFee:              10,
+		RequireChangeToSelf: true,
+		Ledger:              &zanolib.MemoryLedger{},
+	}
+
+	dec, err := p.Evaluate(w, ftp, now)
+	if err != nil {
+		t.Fatalf("failed to evaluate policy: %s", err)
+	}
+	if !dec.Allowed {
+		t.Errorf("transaction should have been allowed, got reasons: %v", dec.Reasons)
+	}
+	if err := p.Record(w, ftp, now); err != nil {
+		t.Fatalf("failed to record: %s", err)
+	}
+
+	// second identical spend exceeds the daily limit
+	dec = must(p.Evaluate(w, ftp, now.Add(time.Hour)))
+	if dec.Allowed {
+		t.Errorf("transaction should have been denied by daily limit")
+	}
+	// but not the day after
+	dec = must(p.Evaluate(w, ftp, now.Add(25*time.Hour)))
+	if !dec.Allowed {
+		t.Errorf("transaction should have been allowed after 24h, got reasons: %v", dec.Reasons)
+	}
+
+	// a huge recorded amount must not wrap around
+	overflow := &zanolib.Policy{DailyLimit: p.DailyLimit, Ledger: &zanolib.MemoryLedger{}}
+	if err := overflow.Ledger.Record(zanolib.NativeCoinAssetId, math.MaxUint64-100, now); err != nil {
+		t.Fatalf("failed to record: %s", err)
+	}
+	if dec = must(overflow.Evaluate(w, ftp, now.Add(time.Hour))); dec.Allowed {
+		t.Errorf("transaction should have been denied by daily limit after overflow")
+	}
+
+	p.MaxFee = 5
+	p.DeniedDestinations = []string{other.String()}
+	ftp.ExpirationTime = uint64(now.Unix() - 10)
+	dec = must(p.Evaluate(w, ftp, now.Add(25*time.Hour)))
+	if dec.Allowed {
+		t.Errorf("transaction should have been denied")
+	}
+	if len(dec.Reasons) != 3 {
+		t.Errorf("expected 3 reasons, got %v", dec.Reasons)
+	}
+}
@@ -0,0 +1,173 @@
+package zanolib
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+var errNoTxPubKey = errors.New("transaction has no public key")
+
+// ReceivedOutput is an output of a transaction that belongs to a Wallet
+type ReceivedOutput struct {
+	Index               uint64            `json:"index"` // index in the transaction outputs
+	Amount              uint64            `json:"amount"`
+	AssetId             string            `json:"asset_id"` // hex, see NativeCoinAssetId
+	StealthAddress      zanobase.Value256 `json:"stealth_address"`
+	AmountBlindingMask  *zanobase.Scalar  `json:"amount_blinding_mask"`
+	AssetIdBlindingMask *zanobase.Scalar  `json:"asset_id_blinding_mask"`
+}
+
+// RejectedOutput is an output of a transaction addressed to a Wallet that could not be
+// decoded, for example because its amount commitment does not match the decoded amount
+type RejectedOutput struct {
+	Index  uint64 `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// ScanResult is the result of Wallet.ScanTransaction
+type ScanResult struct {
+	TxId       zanobase.Value256   `json:"txid"`
+	TxPubKey   zanobase.Value256   `json:"tx_pub_key"`
+	Outputs    []*ReceivedOutput   `json:"outputs,omitempty"`
+	Rejected   []*RejectedOutput   `json:"rejected,omitempty"` // outputs sent to the wallet that are malformed
+	PaymentId  []byte              `json:"payment_id,omitempty"`
+	Extra      []*zanobase.Variant `json:"extra,omitempty"`      // decrypted extra
+	Attachment []*zanobase.Variant `json:"attachment,omitempty"` // decrypted attachments
+}
+
+// Total returns the total amount received for the given asset id
+func (s *ScanResult) Total(assetId string) uint64 {
+	var res uint64
+	for _, out := range s.Outputs {
+		if out.AssetId == assetId {
+			res += out.Amount
+		}
+	}
+	return res
+}
+
+// txPubKey returns the transaction public key found in tx extra
+func txPubKey(tx *zanobase.Transaction) (zanobase.Value256, error) {
+	for _, e := range tx.Extra {
+		if v, ok := e.Value.(zanobase.Value256); ok && e.Tag == zanobase.TagPubKey {
+			return v, nil
+		}
+	}
+	return zanobase.Value256{}, errNoTxPubKey
+}
+
+// ScanTransaction looks for outputs of tx that belong to w and decodes their amount and
+// asset id. Malformed outputs are reported in Rejected without affecting the others. The
+// payment id and decrypted attachments are also returned if tx was sent to w, so deposits
+// can be matched with the integrated address they were sent to.
+func (w *Wallet) ScanTransaction(tx *zanobase.Transaction) (*ScanResult, error) {
+	txId, err := tx.Hash()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := txPubKey(tx)
+	if err != nil {
+		return nil, err
+	}
+	res := &ScanResult{TxId: zanobase.Value256(txId), TxPubKey: pub}
+
+	pubPt, err := new(edwards25519.Point).SetBytes(pub[:])
+	if err != nil {
+		return nil, err
+	}
+	derivation, err := zanocrypto.GenerateKeyDerivation(pubPt, w.ViewPrivKey)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Outputs, res.Rejected = scanOutputs(tx, derivation, w.SpendPubKey)
+
+	if len(res.Outputs) > 0 {
+		// attachments encrypted for another recipient of the same transaction are left out
+		if extra, att, err := w.DecryptAttachments(tx); err == nil {
+			res.Extra, res.Attachment = extra, att
+			res.PaymentId = GetPaymentId(att)
+		}
+	}
+	return res, nil
+}
+
+// scanOutputs returns the outputs of tx sent to the spend key spendPub, given the key
+// derivation 8*v*R (or 8*r*V on the sender side), with their amount and asset id decoded.
+// Outputs sent to spendPub that fail to decode are returned separately.
+func scanOutputs(tx *zanobase.Transaction, derivation, spendPub *edwards25519.Point) ([]*ReceivedOutput, []*RejectedOutput) {
+	var res []*ReceivedOutput
+	var rejected []*RejectedOutput
+	for n, vout := range tx.Vout {
+		out, ok := vout.Value.(*zanobase.TxOutZarcanium)
+		if !ok {
+			continue
+		}
+		// h = Hs(8 * v * R, i)
+		scalar := zanocrypto.HashToScalar(slices.Concat(derivation.Bytes(), zanobase.Varint(n).Bytes()))
+
+		// stealth address = h*G + B
+		stealth := new(edwards25519.Point).Add(new(edwards25519.Point).ScalarBaseMult(scalar), spendPub)
+		if !slices.Equal(stealth.Bytes(), out.StealthAddress[:]) {
+			continue
+		}
+
+		recv, err := decodeOutput(out, scalar)
+		if err != nil {
+			rejected = append(rejected, &RejectedOutput{Index: uint64(n), Reason: err.Error()})
+			continue
+		}
+		recv.Index = uint64(n)
+		res = append(res, recv)
+	}
+	return res, rejected
+}
+
+// decodeOutput decodes the amount and asset id of out given h = Hs(8 * v * R, i)
+func decodeOutput(out *zanobase.TxOutZarcanium, scalar *edwards25519.Scalar) (*ReceivedOutput, error) {
+	amountMask := zanocrypto.HashToScalar(slices.Concat([]byte("ZANO_HDS_OUT_AMOUNT_MASK_______\x00"), scalar.Bytes()))
+	amount := out.EncryptedAmount ^ binary.LittleEndian.Uint64(amountMask.Bytes()[:8])
+	amountBlindingMask := zanocrypto.HashToScalar(slices.Concat(CRYPTO_HDS_OUT_AMOUNT_BLINDING_MASK, scalar.Bytes()))
+	assetBlindingMask := zanocrypto.HashToScalar(slices.Concat([]byte("ZANO_HDS_OUT_ASSET_BLIND_MASK__\x00"), scalar.Bytes()))
+
+	// T = 8 * blinded_asset_id, H = T - r*X
+	blindedAssetId, err := new(edwards25519.Point).SetBytes(out.BlindedAssetId[:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid blinded asset id: %w", err)
+	}
+	blindedAssetId.MultByCofactor(blindedAssetId)
+	if blindedAssetId.Equal(zanocrypto.NativeCoinAssetIdPt) == 1 {
+		// explicit native asset id, as in miner transactions
+		assetBlindingMask = zanocrypto.ScalarInt(0)
+	}
+	assetId := new(edwards25519.Point).Subtract(blindedAssetId, new(edwards25519.Point).ScalarMult(assetBlindingMask, zanocrypto.C_point_X))
+
+	// check amount commitment: 8 * A = amount * T + f * G
+	commitment, err := new(edwards25519.Point).SetBytes(out.AmountCommitment[:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount commitment: %w", err)
+	}
+	commitment.MultByCofactor(commitment)
+	expect := new(edwards25519.Point).Add(
+		new(edwards25519.Point).ScalarMult(zanocrypto.ScalarInt(amount), blindedAssetId),
+		new(edwards25519.Point).ScalarMult(amountBlindingMask, zanocrypto.C_point_G),
+	)
+	if commitment.Equal(expect) != 1 {
+		return nil, errors.New("output amount commitment does not match decoded amount")
+	}
+
+	return &ReceivedOutput{
+		Amount:              amount,
+		AssetId:             hex.EncodeToString(assetId.Bytes()),
+		StealthAddress:      out.StealthAddress,
+		AmountBlindingMask:  &zanobase.Scalar{amountBlindingMask},
+		AssetIdBlindingMask: &zanobase.Scalar{assetBlindingMask},
+	}, nil
+}
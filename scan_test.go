@@ -0,0 +1,75 @@
+package zanolib_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+func TestScanPaymentId(t *testing.T) {
+	w := testWallet(t)
+	recipient := must(zanolib.LoadSpendSecret(make([]byte, 32), 0))
+	pid := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	addr := recipient.Address()
+	if err := addr.SetPaymentId(pid); err != nil {
+		t.Fatalf("failed to set payment id: %s", err)
+	}
+	addr = must(zanolib.ParseAddress(addr.String()))
+
+	ftp := testSignableFTP(t, w)
+	ftp.PreparedDestinations[0].Addr = testAccount(addr)
+	if err := ftp.SetPaymentId(addr); err != nil {
+		t.Fatalf("failed to set payment id on ftp: %s", err)
+	}
+	if err := ftp.SetPaymentId(recipient.Address()); err == nil {
+		t.Errorf("address without payment id should be rejected")
+	}
+
+	res := must(w.Sign(rand.Reader, ftp, nil))
+	if bytes.Equal(zanolib.GetPaymentId(res.Tx.Attachment), pid) {
+		t.Errorf("payment id was not encrypted")
+	}
+
+	scan := must(recipient.ScanTransaction(res.Tx))
+	if len(scan.Outputs) != 1 || scan.Outputs[0].Amount != 600 || scan.Outputs[0].Index != 0 {
+		t.Fatalf("unexpected outputs for recipient: %+v", scan.Outputs)
+	}
+	if scan.Outputs[0].AssetId != zanolib.NativeCoinAssetId {
+		t.Errorf("unexpected asset id %s", scan.Outputs[0].AssetId)
+	}
+	if !bytes.Equal(scan.PaymentId, pid) {
+		t.Errorf("payment id = %x, expected %x", scan.PaymentId, pid)
+	}
+	if scan.Total(zanolib.NativeCoinAssetId) != 600 {
+		t.Errorf("invalid total")
+	}
+
+	// sender sees its change and can read the payment id too
+	scan = must(w.ScanTransaction(res.Tx))
+	if len(scan.Outputs) != 1 || scan.Outputs[0].Amount != 390 || scan.Outputs[0].Index != 1 {
+		t.Fatalf("unexpected outputs for sender: %+v", scan.Outputs)
+	}
+	if !bytes.Equal(scan.PaymentId, pid) {
+		t.Errorf("sender payment id = %x, expected %x", scan.PaymentId, pid)
+	}
+}
+
+func TestScanRejectedOutput(t *testing.T) {
+	w := testWallet(t)
+	ftp := testSignableFTP(t, w)
+	ftp.PreparedDestinations[0].Addr = testAccount(w.Address())
+	res := must(w.Sign(rand.Reader, ftp, nil))
+
+	// tamper with the amount of the first output, the second one must still be found
+	res.Tx.Vout[0].Value.(*zanobase.TxOutZarcanium).EncryptedAmount ^= 1
+	scan := must(w.ScanTransaction(res.Tx))
+	if len(scan.Outputs) != 1 || scan.Outputs[0].Index != 1 || scan.Outputs[0].Amount != 390 {
+		t.Fatalf("unexpected outputs: %+v", scan.Outputs)
+	}
+	if len(scan.Rejected) != 1 || scan.Rejected[0].Index != 0 {
+		t.Errorf("unexpected rejected outputs: %+v", scan.Rejected)
+	}
+}
@@ -19,6 +19,9 @@ var (
 )
 
 func (w *Wallet) Sign(rnd io.Reader, ftp *FinalizeTxParam, oneTimeKey *edwards25519.Scalar) (*FinalizedTx, error) {
+	if w.WatchOnly() {
+		return nil, errors.New("watch-only wallet cannot sign")
+	}
 	if !bytes.Equal(ftp.SpendPubKey.Bytes(), w.SpendPubKey.Bytes()) {
 		return nil, errors.New("spend key does not match")
 	}
@@ -61,26 +64,39 @@ func (w *Wallet) Sign(rnd io.Reader, ftp *FinalizeTxParam, oneTimeKey *edwards25
 	//slices.Reverse(oneTimeKey)
 	//priv, pub, err := edwards25519.PrivKeyFromScalar(oneTimeKey)
 
+	// tx.extra = extra; then the tx details and pub key are added
+	tx.Extra = slices.Clone(ftp.Extra)
 	tx.Extra = append(tx.Extra, &zanobase.Variant{Tag: zanobase.TagPubKey, Value: pubV})
 	tx.Extra = append(tx.Extra, &zanobase.Variant{Tag: zanobase.TagEtcTxFlags16, Value: uint16(0)}) // Flags
+	details, err := ftp.detailsExtra()
+	if err != nil {
+		return nil, err
+	}
+	tx.Extra = append(tx.Extra, details...)
+
+	// encrypt_attachments(tx, sender_account_keys, crypt_destination_addr, txkey, result.derivation)
+	if err := w.encryptAttachments(tx, ftp, priv, res); err != nil {
+		return nil, err
+	}
 
 	// use ftp.Sources
 	for _, src := range ftp.Sources {
 		vin := &zanobase.TxInZcInput{}
 		realOut := src.Outputs[src.RealOutput]
 
-		var prev uint64
-		for _, out := range src.Outputs {
-			// generate_key_image_helper(sender_account_keys, src_entr.real_out_tx_key, src_entr.real_output_in_tx_index, in_context.in_ephemeral, img))
-			// → derive_ephemeral_key_helper(ack, tx_public_key, real_output_index, in_ephemeral)
-			//   → crypto::generate_key_derivation(tx_public_key, ack.view_secret_key, recv_derivation)
-			// → crypto::generate_key_image(in_ephemeral.pub, in_ephemeral.sec, ki)
-			val := zanobase.VariantAs[uint64](out.OutReference)
-			cur := val - prev
-			prev = val
+		offsets, err := KeyOffsets(src.Outputs)
+		if err != nil {
+			return nil, err
+		}
+		for _, cur := range offsets {
 			vin.KeyOffsets = append(vin.KeyOffsets, zanobase.VariantFor(cur))
 		}
 
+		// generate_key_image_helper(sender_account_keys, src_entr.real_out_tx_key, src_entr.real_output_in_tx_index, in_context.in_ephemeral, img))
+		// → derive_ephemeral_key_helper(ack, tx_public_key, real_output_index, in_ephemeral)
+		//   → crypto::generate_key_derivation(tx_public_key, ack.view_secret_key, recv_derivation)
+		// → crypto::generate_key_image(in_ephemeral.pub, in_ephemeral.sec, ki)
+
 		// Derive ephemeral
 		realOutTxKey := new(edwards25519.Point).Set(src.RealOutTxKey.Point)
 
@@ -156,7 +172,6 @@ func (w *Wallet) Sign(rnd io.Reader, ftp *FinalizeTxParam, oneTimeKey *edwards25
 		amountBlindingMask := zanocrypto.HashToScalar(slices.Concat(CRYPTO_HDS_OUT_AMOUNT_BLINDING_MASK, scalar.Bytes()))
 		ogc.AmountBlindingMasks[i] = &zanobase.Scalar{new(edwards25519.Scalar).Set(amountBlindingMask)}
 
-		//UnlockTime      uint64               //
 		vout := &zanobase.TxOutZarcanium{
 			EncryptedAmount: dst.Amount ^ binary.LittleEndian.Uint64(amountMask.Bytes()[:8]),
 		}
@@ -165,6 +180,8 @@ func (w *Wallet) Sign(rnd io.Reader, ftp *FinalizeTxParam, oneTimeKey *edwards25
 		copy(vout.BlindedAssetId[:], dst.BlindedAssetId(scalar, ogc, i).Bytes())
 		copy(vout.AmountCommitment[:], dst.AmountCommitment(scalar, ogc, i).Bytes())
 
+		// construct_tx_out(dst_entr, tx_key, output_index, tx, deriv_cache, sender_account_keys, tx_outs_attr)
+		vout.MixAttr = ftp.TxOutsAttr
 		// if audit address, set vout.MixAttr=1
 		if dst.Addr[0].Flags&1 == 1 {
 			vout.MixAttr = 1 // CURRENCY_TO_KEY_OUT_FORCED_NO_MIX
@@ -173,7 +190,7 @@ func (w *Wallet) Sign(rnd io.Reader, ftp *FinalizeTxParam, oneTimeKey *edwards25
 		// gen_context.amounts[output_index] = dst_entr.amount
 		ogc.Amounts[i] = &zanobase.Scalar{zanocrypto.ScalarInt(dst.Amount)}
 		// gen_context.asset_ids[output_index] = crypto::point_t(dst_entr.asset_id)
-		ogc.AssetIds[i] = dst.AssetId
+		ogc.AssetIds[i] = &zanobase.Point{dst.assetId()}
 		// gen_context.asset_id_blinding_mask_x_amount_sum += gen_context.asset_id_blinding_masks[output_index] * dst_entr.amount
 		addRefScalar(&ogc.AssetIdBlindingMaskXAmountSum, new(edwards25519.Scalar).Multiply(ogc.AssetIdBlindingMasks[i].Scalar, zanocrypto.ScalarInt(dst.Amount)))
 		// gen_context.amount_blinding_masks_sum += gen_context.amount_blinding_masks[output_index]
@@ -194,13 +211,21 @@ func (w *Wallet) Sign(rnd io.Reader, ftp *FinalizeTxParam, oneTimeKey *edwards25
 		tx.Extra = append(tx.Extra, &zanobase.Variant{Tag: zanobase.TagDerivationHint, Value: []byte{byte(hint & 0xff), byte((hint >> 8) & 0xff)}})
 	}
 
-	// compute total in & total out, compute fee
+	// compute total in & total out of the native coin, compute fee
 	var totalIn, totalOut uint64
-	for _, src := range ftp.Sources {
-		totalIn += src.Amount
+	for n, src := range ftp.Sources {
+		assetId, err := src.AssetId()
+		if err != nil {
+			return nil, fmt.Errorf("source %d: %w", n, err)
+		}
+		if assetId.Equal(zanocrypto.NativeCoinAssetIdPt) == 1 {
+			totalIn += src.Amount
+		}
 	}
 	for _, dst := range ftp.PreparedDestinations {
-		totalOut += dst.Amount
+		if dst.assetId().Equal(zanocrypto.NativeCoinAssetIdPt) == 1 {
+			totalOut += dst.Amount
+		}
 	}
 	if totalIn > totalOut {
 		// add fee to extras
@@ -225,7 +250,9 @@ func (w *Wallet) Sign(rnd io.Reader, ftp *FinalizeTxParam, oneTimeKey *edwards25
 			if err != nil {
 				return nil, err
 			}
-			src.generateZCSig(rnd, tx, n, sig, txHashForSig, ogc)
+			if err := src.generateZCSig(rnd, tx, n, n+1 == len(ftp.Sources), sig, txHashForSig, ogc); err != nil {
+				return nil, fmt.Errorf("while signing input %d: %w", n, err)
+			}
 			tx.Signatures = append(tx.Signatures, &zanobase.Variant{Tag: zanobase.TagZCSig, Value: sig})
 		}
 	}
@@ -257,6 +284,35 @@ func (w *Wallet) Sign(rnd io.Reader, ftp *FinalizeTxParam, oneTimeKey *edwards25
 	return res, nil
 }
 
+// detailsExtra returns the extra entries for the unlock time, flags and expiration time of
+// the transaction, see set_tx_unlock_time, set_tx_flags and set_tx_expiration_time. As in
+// construct_tx, per destination unlock times are stored in an etc_tx_details_unlock_time2
+// holding one entry per output, which cannot be combined with a transaction unlock time.
+func (ftp *FinalizeTxParam) detailsExtra() ([]*zanobase.Variant, error) {
+	var res []*zanobase.Variant
+	ut2 := &zanobase.EtcTxDetailsUnlockTime2{UnlockTimeArray: make([]zanobase.Varint, len(ftp.PreparedDestinations))}
+	perOutput := false
+	for n, dst := range ftp.PreparedDestinations {
+		ut2.UnlockTimeArray[n] = zanobase.Varint(dst.UnlockTime)
+		perOutput = perOutput || dst.UnlockTime != 0
+	}
+	switch {
+	case perOutput && ftp.UnlockTime != 0:
+		return nil, errors.New("unlock time cannot be set on both the transaction and its destinations")
+	case perOutput:
+		res = append(res, zanobase.VariantFor(ut2))
+	case ftp.UnlockTime != 0:
+		res = append(res, zanobase.VariantFor(&zanobase.EtcTxDetailsUnlockTime{V: ftp.UnlockTime}))
+	}
+	if ftp.Flags != 0 {
+		res = append(res, zanobase.VariantFor(&zanobase.EtcTxDetailsFlags{V: uint64(ftp.Flags)}))
+	}
+	if ftp.ExpirationTime != 0 {
+		res = append(res, zanobase.VariantFor(&zanobase.EtcTxDetailsExpirationTime{V: ftp.ExpirationTime}))
+	}
+	return res, nil
+}
+
 func addRefScalar(v **zanobase.Scalar, a *edwards25519.Scalar) {
 	if (*v) == nil {
 		(*v) = &zanobase.Scalar{new(edwards25519.Scalar).Set(a)}
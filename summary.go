@@ -0,0 +1,182 @@
+package zanolib
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+// currencyMaxBlockNumber is CURRENCY_MAX_BLOCK_NUMBER: unlock times below this value are
+// block heights, values above are unix timestamps.
+const currencyMaxBlockNumber = 500000000
+
+// FTPSummary is a human readable view of a FinalizeTxParam, meant to be displayed before
+// approving a signature. It can be marshalled as JSON or displayed with String.
+type FTPSummary struct {
+	TxVersion      uint64                `json:"tx_version"`
+	Destinations   []*SummaryDestination `json:"destinations"`
+	Inputs         map[string]uint64     `json:"inputs"`  // total of inputs per asset id
+	Outputs        map[string]uint64     `json:"outputs"` // total of outputs per asset id, including change
+	Sent           map[string]uint64     `json:"sent"`    // total sent to others per asset id, excluding change
+	Fee            uint64                `json:"fee"`
+	UnlockTime     uint64                `json:"unlock_time,omitempty"`
+	ExpirationTime uint64                `json:"expiration_time,omitempty"`
+	Warnings       []string              `json:"warnings,omitempty"`
+}
+
+// SummaryDestination describes one destination of a FinalizeTxParam
+type SummaryDestination struct {
+	Addresses   []string     `json:"addresses"` // more than one address means a multisig output
+	Amount      uint64       `json:"amount"`
+	AssetId     string       `json:"asset_id"`
+	Change      bool         `json:"change"` // true if this destination goes back to the signing wallet
+	MinimumSigs uint64       `json:"minimum_sigs,omitempty"`
+	UnlockTime  uint64       `json:"unlock_time,omitempty"`
+	Htlc        *SummaryHtlc `json:"htlc,omitempty"`
+}
+
+// SummaryHtlc holds the HTLC options of a destination
+type SummaryHtlc struct {
+	Expiration uint64            `json:"expiration"`
+	Hash       zanobase.Value256 `json:"hash"`
+}
+
+// Summary returns a summary of the transaction that will be generated when signing ftp
+// with the given wallet. Destinations going back to w are flagged as change.
+// The asset of each source is recovered from its real output, see TxSource.AssetId.
+func (ftp *FinalizeTxParam) Summary(w *Wallet) *FTPSummary {
+	res := &FTPSummary{
+		TxVersion:      ftp.TxVersion,
+		Inputs:         make(map[string]uint64),
+		Outputs:        make(map[string]uint64),
+		Sent:           make(map[string]uint64),
+		UnlockTime:     ftp.UnlockTime,
+		ExpirationTime: ftp.ExpirationTime,
+	}
+
+	if ftp.SpendPubKey == nil || ftp.SpendPubKey.Point == nil || ftp.SpendPubKey.Equal(w.SpendPubKey) != 1 {
+		res.Warnings = append(res.Warnings, "transaction was not prepared for this wallet")
+	}
+
+	for n, src := range ftp.Sources {
+		assetId, err := src.AssetId()
+		if err != nil {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("source %d: %s", n, err))
+			continue
+		}
+		res.Inputs[hex.EncodeToString(assetId.Bytes())] += src.Amount
+	}
+
+	for _, dst := range ftp.PreparedDestinations {
+		sd := &SummaryDestination{
+			Amount:     dst.Amount,
+			AssetId:    destAssetId(dst),
+			Change:     w.isOwnAccount(dst.Addr),
+			UnlockTime: dst.UnlockTime,
+		}
+		for _, acc := range dst.Addr {
+			sd.Addresses = append(sd.Addresses, AddressFromAccount(acc).String())
+		}
+		if len(dst.Addr) > 1 {
+			sd.MinimumSigs = dst.MinimumSigs
+		}
+		if dst.HtlcOptions != nil && (dst.HtlcOptions.Expiration != 0 || !dst.HtlcOptions.HtlcHash.IsZero()) {
+			sd.Htlc = &SummaryHtlc{Expiration: dst.HtlcOptions.Expiration, Hash: dst.HtlcOptions.HtlcHash}
+		}
+		res.Outputs[sd.AssetId] += dst.Amount
+		if !sd.Change {
+			res.Sent[sd.AssetId] += dst.Amount
+		}
+		res.Destinations = append(res.Destinations, sd)
+	}
+
+	for asset, out := range res.Outputs {
+		in := res.Inputs[asset]
+		switch {
+		case out > in:
+			res.Warnings = append(res.Warnings, fmt.Sprintf("outputs (%d) exceed inputs (%d) for asset %s", out, in, asset))
+		case asset == NativeCoinAssetId:
+			res.Fee = in - out
+		case out < in:
+			res.Warnings = append(res.Warnings, fmt.Sprintf("inputs (%d) exceed outputs (%d) for asset %s", in, out, asset))
+		}
+	}
+	if _, ok := res.Outputs[NativeCoinAssetId]; !ok {
+		res.Fee = res.Inputs[NativeCoinAssetId]
+	}
+	slices.Sort(res.Warnings)
+
+	return res
+}
+
+// String returns the summary as multi-line text
+func (s *FTPSummary) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "Transaction version: %d\n", s.TxVersion)
+	fmt.Fprintf(b, "Destinations:\n")
+	for n, dst := range s.Destinations {
+		fmt.Fprintf(b, "  #%d %s %s", n, formatAmount(dst.Amount, dst.AssetId), strings.Join(dst.Addresses, ", "))
+		if dst.Change {
+			b.WriteString(" [change]")
+		}
+		if dst.MinimumSigs != 0 {
+			fmt.Fprintf(b, " [multisig %d/%d]", dst.MinimumSigs, len(dst.Addresses))
+		}
+		if dst.UnlockTime != 0 {
+			fmt.Fprintf(b, " [unlock %s]", formatUnlockTime(dst.UnlockTime))
+		}
+		if dst.Htlc != nil {
+			fmt.Fprintf(b, " [htlc hash=%s expiration=%d]", dst.Htlc.Hash, dst.Htlc.Expiration)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(b, "Inputs:\n")
+	for _, asset := range sortedKeys(s.Inputs) {
+		fmt.Fprintf(b, "  %s\n", formatAmount(s.Inputs[asset], asset))
+	}
+	fmt.Fprintf(b, "Sent:\n")
+	for _, asset := range sortedKeys(s.Sent) {
+		fmt.Fprintf(b, "  %s\n", formatAmount(s.Sent[asset], asset))
+	}
+	fmt.Fprintf(b, "Fee: %s\n", formatAmount(s.Fee, NativeCoinAssetId))
+	if s.UnlockTime != 0 {
+		fmt.Fprintf(b, "Unlock time: %s\n", formatUnlockTime(s.UnlockTime))
+	}
+	if s.ExpirationTime != 0 {
+		fmt.Fprintf(b, "Expiration time: %s\n", time.Unix(int64(s.ExpirationTime), 0).UTC().Format(time.RFC3339))
+	}
+	for _, w := range s.Warnings {
+		fmt.Fprintf(b, "WARNING: %s\n", w)
+	}
+	return b.String()
+}
+
+// formatAmount returns a human readable amount. Native coin amounts are shown in ZANO
+// (12 decimals), other assets are shown in atomic units with their asset id since the
+// number of decimals is not known.
+func formatAmount(amount uint64, assetId string) string {
+	if assetId != NativeCoinAssetId {
+		return fmt.Sprintf("%d (asset %s)", amount, assetId)
+	}
+	return fmt.Sprintf("%d.%012d ZANO", amount/1e12, amount%1e12)
+}
+
+func formatUnlockTime(v uint64) string {
+	if v < currencyMaxBlockNumber {
+		return fmt.Sprintf("height %d", v)
+	}
+	return time.Unix(int64(v), 0).UTC().Format(time.RFC3339)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	slices.Sort(res)
+	return res
+}
@@ -0,0 +1,93 @@
+package zanolib_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+func TestFTPSummary(t *testing.T) {
+	w := testWallet(t)
+	other := must(zanolib.ParseAddress("ZxD5aoLDPTdcaRx4uCpyW4XiLfEXejepAVz8cSY2fwHNEiJNu6NmpBBDLGTJzCsUvn3acCVDVDPMV8yQXdPooAp338Se7AxeH"))
+
+	ftp := &zanolib.FinalizeTxParam{
+		Sources: []*zanolib.TxSource{{Amount: 3_000_000_000_000}, {Amount: 500_000_000_000}},
+		PreparedDestinations: []*zanolib.TxDest{
+			{Amount: 2_000_000_000_000, Addr: testAccount(other)},
+			{Amount: 1_490_000_000_000, Addr: testAccount(w.Address())},
+		},
+		SpendPubKey: &zanobase.Point{w.SpendPubKey},
+		TxVersion:   2,
+	}
+
+	sum := ftp.Summary(w)
+	if sum.Fee != 10_000_000_000 {
+		t.Errorf("invalid fee: %d", sum.Fee)
+	}
+	if sum.Destinations[0].Change || !sum.Destinations[1].Change {
+		t.Errorf("invalid change detection")
+	}
+	if sum.Destinations[0].Addresses[0] != other.String() {
+		t.Errorf("invalid destination address %s", sum.Destinations[0].Addresses[0])
+	}
+	if sum.Sent[zanolib.NativeCoinAssetId] != 2_000_000_000_000 {
+		t.Errorf("invalid sent amount: %d", sum.Sent[zanolib.NativeCoinAssetId])
+	}
+	if len(sum.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", sum.Warnings)
+	}
+	if txt := sum.String(); !strings.Contains(txt, "Fee: 0.010000000000 ZANO") || !strings.Contains(txt, "[change]") {
+		t.Errorf("unexpected text summary:\n%s", txt)
+	}
+	if _, err := json.Marshal(sum); err != nil {
+		t.Errorf("failed to marshal summary: %s", err)
+	}
+}
+
+func TestFTPSummaryAsset(t *testing.T) {
+	w := testWallet(t)
+	other := must(zanolib.ParseAddress("ZxD5aoLDPTdcaRx4uCpyW4XiLfEXejepAVz8cSY2fwHNEiJNu6NmpBBDLGTJzCsUvn3acCVDVDPMV8yQXdPooAp338Se7AxeH"))
+
+	// source of asset H with T = H + r * X, stored premultiplied by 1/8
+	asset := new(edwards25519.Point).ScalarBaseMult(zanocrypto.ScalarInt(99))
+	assetHex := hex.EncodeToString(asset.Bytes())
+	blinded := new(edwards25519.Point).Add(asset, new(edwards25519.Point).ScalarMult(zanocrypto.ScalarInt(6), zanocrypto.C_point_X))
+	assetSrc := &zanolib.TxSource{
+		Outputs:                    []*zanolib.TxSourceOutputEntry{{BlindedAssetID: &zanobase.Point{new(edwards25519.Point).ScalarMult(zanocrypto.Sc1div8, blinded)}}},
+		RealOutAssetIdBlindingMask: &zanobase.Scalar{zanocrypto.ScalarInt(6)},
+		Amount:                     500,
+	}
+
+	ftp := &zanolib.FinalizeTxParam{
+		Sources: []*zanolib.TxSource{assetSrc, {Amount: 100}},
+		PreparedDestinations: []*zanolib.TxDest{
+			{Amount: 400, Addr: testAccount(other), AssetId: &zanobase.Point{asset}},
+			{Amount: 100, Addr: testAccount(w.Address()), AssetId: &zanobase.Point{asset}},
+			{Amount: 90, Addr: testAccount(w.Address())},
+		},
+		SpendPubKey: &zanobase.Point{w.SpendPubKey},
+	}
+
+	sum := ftp.Summary(w)
+	if sum.Inputs[assetHex] != 500 || sum.Inputs[zanolib.NativeCoinAssetId] != 100 {
+		t.Errorf("invalid inputs: %v", sum.Inputs)
+	}
+	if sum.Fee != 10 || sum.Sent[assetHex] != 400 {
+		t.Errorf("invalid fee %d or sent amount %d", sum.Fee, sum.Sent[assetHex])
+	}
+	if len(sum.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", sum.Warnings)
+	}
+
+	p := &zanolib.Policy{MaxAmount: map[string]uint64{assetHex: 400}, RequireChangeToSelf: true}
+	if dec := must(p.Evaluate(w, ftp, time.Now())); !dec.Allowed {
+		t.Errorf("asset transfer should be allowed, got reasons: %v", dec.Reasons)
+	}
+}
@@ -0,0 +1,367 @@
+package zanolib
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+const (
+	// DefaultRingSize is the number of ring members of each input after HF4: the real
+	// output and its decoys
+	DefaultRingSize = HF4DecoySetSize + 1
+
+	// DefaultFee is TX_DEFAULT_FEE, in native coin atomic units (0.01 ZANO)
+	DefaultFee = 10000000000
+
+	// MinOutputs is CURRENCY_TX_MIN_ALLOWED_OUTS, zero amount change outputs are added
+	// to reach it
+	MinOutputs = 2
+)
+
+// OwnedOutput is an output belonging to a Wallet that can be spent by a TxBuilder. It
+// combines the on-chain output data with the secrets found by Wallet.ScanTransaction.
+type OwnedOutput struct {
+	GlobalIndex         uint64            `json:"global_index"`
+	TxPubKey            zanobase.Value256 `json:"tx_pub_key"` // public key of the transaction holding the output
+	Index               uint64            `json:"index"`      // index in the transaction outputs
+	Amount              uint64            `json:"amount"`
+	AssetId             string            `json:"asset_id"` // hex, see NativeCoinAssetId
+	StealthAddress      zanobase.Value256 `json:"stealth_address"`
+	ConcealingPoint     zanobase.Value256 `json:"concealing_point"`
+	AmountCommitment    zanobase.Value256 `json:"amount_commitment"`
+	BlindedAssetId      zanobase.Value256 `json:"blinded_asset_id"`
+	AmountBlindingMask  *zanobase.Scalar  `json:"amount_blinding_mask"`
+	AssetIdBlindingMask *zanobase.Scalar  `json:"asset_id_blinding_mask"`
+}
+
+// OwnedOutput returns the OwnedOutput matching out, which must be one of the outputs of this
+// scan result. tx is the scanned transaction, and globalIndex the global index of the output
+// as returned by the daemon.
+func (s *ScanResult) OwnedOutput(tx *zanobase.Transaction, out *ReceivedOutput, globalIndex uint64) (*OwnedOutput, error) {
+	if out.Index >= uint64(len(tx.Vout)) {
+		return nil, errors.New("output index out of range")
+	}
+	vout, ok := tx.Vout[out.Index].Value.(*zanobase.TxOutZarcanium)
+	if !ok || vout.StealthAddress != out.StealthAddress {
+		return nil, errors.New("output does not match transaction")
+	}
+	return &OwnedOutput{
+		GlobalIndex:         globalIndex,
+		TxPubKey:            s.TxPubKey,
+		Index:               out.Index,
+		Amount:              out.Amount,
+		AssetId:             out.AssetId,
+		StealthAddress:      vout.StealthAddress,
+		ConcealingPoint:     vout.ConcealingPoint,
+		AmountCommitment:    vout.AmountCommitment,
+		BlindedAssetId:      vout.BlindedAssetId,
+		AmountBlindingMask:  out.AmountBlindingMask,
+		AssetIdBlindingMask: out.AssetIdBlindingMask,
+	}, nil
+}
+
+// Entry returns the ring member entry for this output
+func (o *OwnedOutput) Entry() (*TxSourceOutputEntry, error) {
+	res := &TxSourceOutputEntry{OutReference: zanobase.VariantFor(o.GlobalIndex)}
+	for _, v := range []struct {
+		dst **zanobase.Point
+		val zanobase.Value256
+	}{
+		{&res.StealthAddress, o.StealthAddress},
+		{&res.ConcealingPoint, o.ConcealingPoint},
+		{&res.AmountCommitment, o.AmountCommitment},
+		{&res.BlindedAssetID, o.BlindedAssetId},
+	} {
+		pt := v.val.ToPoint()
+		if pt == nil {
+			return nil, fmt.Errorf("output %d has an invalid point", o.GlobalIndex)
+		}
+		*v.dst = &zanobase.Point{pt}
+	}
+	return res, nil
+}
+
+// RingProvider provides decoys used to build the ring of each input
+type RingProvider interface {
+	// GetDecoys returns count outputs that can be used as decoys when spending out. The
+	// returned entries must refer to outputs by global index and must not include out.
+	GetDecoys(out *OwnedOutput, count int) ([]*TxSourceOutputEntry, error)
+}
+
+// Destination is a recipient of a transaction built by TxBuilder
+type Destination struct {
+	Address *Address
+	Amount  uint64
+	AssetId string // hex, empty for the native coin
+}
+
+// assetPoint returns the asset id of d as a point
+func (d *Destination) assetPoint() (*edwards25519.Point, error) {
+	if d.AssetId == "" {
+		return zanocrypto.NativeCoinAssetIdPt, nil
+	}
+	buf, err := hex.DecodeString(d.AssetId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset id: %w", err)
+	}
+	pt, err := new(edwards25519.Point).SetBytes(buf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset id: %w", err)
+	}
+	return pt, nil
+}
+
+// TxBuilder builds a FinalizeTxParam from the wallet's own outputs, without the need for
+// simplewallet. Zero values use defaults, so only Wallet, Outputs, Ring and Destinations
+// are required.
+type TxBuilder struct {
+	Wallet         *Wallet
+	Outputs        []*OwnedOutput // outputs available for coin selection
+	Ring           RingProvider   // can be nil if RingSize is 1
+	RingSize       int            // number of ring members per input, defaults to DefaultRingSize
+	Destinations   []*Destination
+	Fee            uint64   // defaults to DefaultFee, unless FeePerKB is set
+	FeePerKB       uint64   // if set and Fee is zero, the fee is computed from the estimated size
+	ChangeAddress  *Address // defaults to the wallet's address
+	Attachments    []*zanobase.Variant
+	UnlockTime     uint64
+	ExpirationTime uint64
+}
+
+// SelectOutputs returns the outputs of b.Outputs of the given asset to spend in order to
+// cover amount. If a single output is enough, the smallest such output is used, otherwise
+// the largest outputs are taken until amount is reached.
+func (b *TxBuilder) SelectOutputs(assetId string, amount uint64) ([]*OwnedOutput, error) {
+	var avail []*OwnedOutput
+	var total uint64
+	for _, o := range b.Outputs {
+		if o.AssetId == assetId && o.Amount > 0 {
+			avail = append(avail, o)
+			total += o.Amount
+		}
+	}
+	if total < amount {
+		return nil, fmt.Errorf("not enough funds for asset %s: have %d, need %d", assetId, total, amount)
+	}
+	sort.SliceStable(avail, func(i, j int) bool { return avail[i].Amount < avail[j].Amount })
+
+	if n := sort.Search(len(avail), func(i int) bool { return avail[i].Amount >= amount }); n < len(avail) {
+		return avail[n : n+1], nil
+	}
+
+	var res []*OwnedOutput
+	var sum uint64
+	for i := len(avail) - 1; sum < amount; i-- {
+		res = append(res, avail[i])
+		sum += avail[i].Amount
+	}
+	return res, nil
+}
+
+// Build performs coin selection and returns a FinalizeTxParam ready to be passed to
+// Wallet.Sign
+func (b *TxBuilder) Build() (*FinalizeTxParam, error) {
+	w := b.Wallet
+	if w == nil {
+		return nil, errors.New("builder has no wallet")
+	}
+	if len(b.Destinations) == 0 {
+		return nil, errors.New("no destinations")
+	}
+	ringSize := b.RingSize
+	if ringSize == 0 {
+		ringSize = DefaultRingSize
+	}
+	if ringSize > 1 && b.Ring == nil {
+		return nil, errors.New("a ring provider is required for ring size > 1")
+	}
+	fee := b.Fee
+	if fee == 0 && b.FeePerKB == 0 {
+		fee = DefaultFee
+	}
+	change := b.ChangeAddress
+	if change == nil {
+		change = w.Address()
+	}
+
+	// amounts sent per asset, in order of first appearance. The fee is paid in native
+	// coin, so native outputs are always selected.
+	var assets []string
+	totals := make(map[string]uint64)
+	points := make(map[string]*zanobase.Point)
+	keys := make([]string, len(b.Destinations))
+	for n, d := range b.Destinations {
+		if d.Address == nil || d.Amount == 0 {
+			return nil, errors.New("destination requires an address and an amount")
+		}
+		pt, err := d.assetPoint()
+		if err != nil {
+			return nil, fmt.Errorf("destination %d: %w", n, err)
+		}
+		key := hex.EncodeToString(pt.Bytes())
+		if _, ok := points[key]; !ok {
+			points[key] = &zanobase.Point{pt}
+			assets = append(assets, key)
+		}
+		if totals[key]+d.Amount < totals[key] {
+			return nil, errors.New("destinations amount overflow")
+		}
+		totals[key] += d.Amount
+		keys[n] = key
+	}
+	if _, ok := points[NativeCoinAssetId]; !ok {
+		points[NativeCoinAssetId] = &zanobase.Point{zanocrypto.NativeCoinAssetIdPt}
+		assets = append(assets, NativeCoinAssetId)
+	}
+
+	var assetSelected []*OwnedOutput
+	for _, asset := range assets {
+		if asset == NativeCoinAssetId {
+			continue
+		}
+		sel, err := b.SelectOutputs(asset, totals[asset])
+		if err != nil {
+			return nil, err
+		}
+		assetSelected = append(assetSelected, sel...)
+	}
+	selected, fee, err := b.selectOutputs(totals[NativeCoinAssetId], fee, ringSize, len(assetSelected), len(assets))
+	if err != nil {
+		return nil, err
+	}
+	selected = append(selected, assetSelected...)
+	totals[NativeCoinAssetId] += fee
+
+	ftp := &FinalizeTxParam{
+		UnlockTime:     b.UnlockTime,
+		Attachments:    slices.Clone(b.Attachments),
+		ExpirationTime: b.ExpirationTime,
+		SpendPubKey:    &zanobase.Point{w.SpendPubKey},
+		TxVersion:      zanobase.TransactionVersionPostHF4,
+	}
+
+	totalIn := make(map[string]uint64)
+	for _, o := range selected {
+		src, err := b.source(o, ringSize)
+		if err != nil {
+			return nil, err
+		}
+		src.TransferIndex = uint64(slices.Index(b.Outputs, o))
+		ftp.Sources = append(ftp.Sources, src)
+		ftp.SelectedTransfers = append(ftp.SelectedTransfers, zanobase.Varint(src.TransferIndex))
+		totalIn[o.AssetId] += o.Amount
+	}
+
+	// destinations, then change of each asset, then zero amount outputs up to MinOutputs
+	addDest := func(addr *Address, amount uint64, assetId *zanobase.Point) {
+		ftp.PreparedDestinations = append(ftp.PreparedDestinations, &TxDest{
+			Amount:      amount,
+			Addr:        []*zanobase.AccountPublicAddr{addr.Account()},
+			HtlcOptions: &TxDestHtlcOut{},
+			AssetId:     assetId,
+		})
+	}
+	for n, d := range b.Destinations {
+		addDest(d.Address, d.Amount, points[keys[n]])
+	}
+	for _, asset := range assets {
+		if totalIn[asset] > totals[asset] {
+			addDest(change, totalIn[asset]-totals[asset], points[asset])
+		}
+	}
+	for len(ftp.PreparedDestinations) < MinOutputs {
+		addDest(change, 0, points[NativeCoinAssetId])
+	}
+
+	// see get_crypt_address_from_destinations
+	self := w.Address()
+	ftp.CryptAddress = self.Account()
+	for _, d := range b.Destinations {
+		if !d.Address.SameKeys(self) {
+			ftp.CryptAddress = d.Address.Account()
+			break
+		}
+	}
+	for _, d := range b.Destinations {
+		if len(d.Address.PaymentId) > 0 {
+			if err := ftp.SetPaymentId(d.Address); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	return ftp, nil
+}
+
+// selectOutputs selects native outputs for amount plus fee. If the fee is computed from
+// the transaction size, it is raised until the selected outputs can pay for it. The size
+// accounts for assetInputs other inputs and one change output for each of the assets.
+func (b *TxBuilder) selectOutputs(amount, fee uint64, ringSize, assetInputs, assets int) ([]*OwnedOutput, uint64, error) {
+	att := b.Attachments
+	for _, d := range b.Destinations {
+		if len(d.Address.PaymentId) > 0 {
+			att = append(slices.Clone(att), PaymentIdAttachment(d.Address.PaymentId))
+			break
+		}
+	}
+	for {
+		selected, err := b.SelectOutputs(NativeCoinAssetId, amount+fee)
+		if err != nil || b.Fee != 0 || b.FeePerKB == 0 {
+			return selected, fee, err
+		}
+		size, err := EstimateTxSize(&TxSizeParams{
+			Inputs:     len(selected) + assetInputs,
+			RingSize:   ringSize,
+			Outputs:    max(len(b.Destinations)+assets, MinOutputs),
+			Attachment: att,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		need := FeeForSize(size, b.FeePerKB)
+		if need <= fee {
+			return selected, fee, nil
+		}
+		fee = need
+	}
+}
+
+// source returns the TxSource spending o, with ring members sorted by global index
+func (b *TxBuilder) source(o *OwnedOutput, ringSize int) (*TxSource, error) {
+	ring, realOut, err := BuildRing(b.Ring, o, ringSize)
+	if err != nil {
+		return nil, err
+	}
+
+	txKey := o.TxPubKey.ToPoint()
+	if txKey == nil {
+		return nil, errors.New("invalid output transaction public key")
+	}
+	return &TxSource{
+		Outputs:                    ring,
+		RealOutput:                 uint64(realOut),
+		RealOutTxKey:               &zanobase.Point{txKey},
+		RealOutAmountBlindingMask:  o.AmountBlindingMask,
+		RealOutAssetIdBlindingMask: o.AssetIdBlindingMask,
+		RealOutInTxIndex:           o.Index,
+		Amount:                     o.Amount,
+	}, nil
+}
+
+// Sign builds the transaction and signs it with b.Wallet
+func (b *TxBuilder) Sign(rnd io.Reader) (*FinalizedTx, error) {
+	ftp, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return b.Wallet.Sign(rnd, ftp, nil)
+}
@@ -0,0 +1,163 @@
+package zanolib_test
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+type testRing struct{}
+
+func (testRing) GetDecoys(out *zanolib.OwnedOutput, count int) ([]*zanolib.TxSourceOutputEntry, error) {
+	point := func(v uint64) *zanobase.Point {
+		return &zanobase.Point{new(edwards25519.Point).ScalarBaseMult(zanocrypto.ScalarInt(v))}
+	}
+	var res []*zanolib.TxSourceOutputEntry
+	for i := 0; i < count; i++ {
+		idx := uint64(i * 1000)
+		res = append(res, &zanolib.TxSourceOutputEntry{
+			OutReference:     zanobase.VariantFor(idx),
+			StealthAddress:   point(idx + 1),
+			ConcealingPoint:  point(idx + 2),
+			AmountCommitment: point(idx + 3),
+			BlindedAssetID:   point(idx + 4),
+		})
+	}
+	return res, nil
+}
+
+func TestTxBuilder(t *testing.T) {
+	w := testWallet(t)
+	recipient := must(zanolib.LoadSpendSecret(make([]byte, 32), 0))
+
+	// get some funds for w: 390 in the change of a signed transaction
+	prev := must(w.Sign(rand.Reader, testSignableFTP(t, w), nil))
+	scan := must(w.ScanTransaction(prev.Tx))
+	if len(scan.Outputs) != 1 {
+		t.Fatalf("expected one output, got %d", len(scan.Outputs))
+	}
+	owned := must(scan.OwnedOutput(prev.Tx, scan.Outputs[0], 2500))
+
+	b := &zanolib.TxBuilder{
+		Wallet:       w,
+		Outputs:      []*zanolib.OwnedOutput{{Amount: 100, AssetId: zanolib.NativeCoinAssetId}, owned},
+		Ring:         testRing{},
+		Destinations: []*zanolib.Destination{{Address: recipient.Address(), Amount: 300}},
+		Fee:          10,
+	}
+	if _, err := b.SelectOutputs(zanolib.NativeCoinAssetId, 1000); err == nil {
+		t.Errorf("selecting more than available should fail")
+	}
+
+	ftp := must(b.Build())
+	if len(ftp.Sources) != 1 || ftp.Sources[0].Amount != 390 {
+		t.Fatalf("unexpected sources")
+	}
+	src := ftp.Sources[0]
+	if len(src.Outputs) != zanolib.DefaultRingSize {
+		t.Errorf("ring size = %d, expected %d", len(src.Outputs), zanolib.DefaultRingSize)
+	}
+	if src.RealOutput != 3 || zanobase.VariantAs[uint64](src.Outputs[src.RealOutput].OutReference) != 2500 {
+		t.Errorf("real output not at its sorted position: %d", src.RealOutput)
+	}
+	if len(ftp.PreparedDestinations) != 2 || ftp.PreparedDestinations[1].Amount != 80 {
+		t.Fatalf("unexpected destinations")
+	}
+
+	res := must(w.Sign(rand.Reader, ftp, nil))
+	received := must(recipient.ScanTransaction(res.Tx))
+	if received.Total(zanolib.NativeCoinAssetId) != 300 {
+		t.Errorf("recipient received %d, expected 300", received.Total(zanolib.NativeCoinAssetId))
+	}
+	change := must(w.ScanTransaction(res.Tx))
+	if change.Total(zanolib.NativeCoinAssetId) != 80 {
+		t.Errorf("change = %d, expected 80", change.Total(zanolib.NativeCoinAssetId))
+	}
+
+	// fee computed from the transaction size
+	b.Fee, b.FeePerKB = 0, 1
+	ftp = must(b.Build())
+	fee := zanolib.FeeForSize(must(ftp.EstimateSize()), 1)
+	if ftp.PreparedDestinations[1].Amount != 90-fee {
+		t.Errorf("change = %d, expected %d", ftp.PreparedDestinations[1].Amount, 90-fee)
+	}
+	b.Fee = 10
+
+	// exact amount still results in two outputs
+	b.Destinations[0].Amount = 380
+	ftp = must(b.Build())
+	if len(ftp.PreparedDestinations) != zanolib.MinOutputs || ftp.PreparedDestinations[1].Amount != 0 {
+		t.Errorf("expected a zero amount change output")
+	}
+}
+
+func TestTxBuilderAsset(t *testing.T) {
+	w := testWallet(t)
+	recipient := must(zanolib.LoadSpendSecret(make([]byte, 32), 0))
+	point := func(v uint64) *edwards25519.Point {
+		return new(edwards25519.Point).ScalarBaseMult(zanocrypto.ScalarInt(v))
+	}
+	div8 := func(p *edwards25519.Point) zanobase.Value256 {
+		return zanobase.Value256(new(edwards25519.Point).ScalarMult(zanocrypto.Sc1div8, p).Bytes())
+	}
+
+	// native funds: 390 in the change of a signed transaction
+	prev := must(w.Sign(rand.Reader, testSignableFTP(t, w), nil))
+	scan := must(w.ScanTransaction(prev.Tx))
+	native := must(scan.OwnedOutput(prev.Tx, scan.Outputs[0], 2500))
+
+	// 1000 of asset H, with T = H + r*X and A = a*T + f*G
+	asset := point(99)
+	assetHex := hex.EncodeToString(asset.Bytes())
+	txKey := point(8)
+	derivation := must(zanocrypto.GenerateKeyDerivation(txKey, w.ViewPrivKey))
+	blinded := new(edwards25519.Point).Add(asset, new(edwards25519.Point).ScalarMult(zanocrypto.ScalarInt(6), zanocrypto.C_point_X))
+	owned := &zanolib.OwnedOutput{
+		GlobalIndex:         3500,
+		TxPubKey:            zanobase.Value256(txKey.Bytes()),
+		Amount:              1000,
+		AssetId:             assetHex,
+		StealthAddress:      zanobase.Value256(must(zanocrypto.DerivePublicKey(derivation.Bytes(), 0, w.SpendPubKey)).Bytes()),
+		ConcealingPoint:     zanobase.Value256(point(15).Bytes()),
+		AmountCommitment:    div8(new(edwards25519.Point).VarTimeDoubleScalarBaseMult(zanocrypto.ScalarInt(1000), blinded, zanocrypto.ScalarInt(5))),
+		BlindedAssetId:      div8(blinded),
+		AmountBlindingMask:  &zanobase.Scalar{zanocrypto.ScalarInt(5)},
+		AssetIdBlindingMask: &zanobase.Scalar{zanocrypto.ScalarInt(6)},
+	}
+
+	b := &zanolib.TxBuilder{
+		Wallet:       w,
+		Outputs:      []*zanolib.OwnedOutput{native, owned},
+		Ring:         testRing{},
+		Destinations: []*zanolib.Destination{{Address: recipient.Address(), Amount: 300, AssetId: assetHex}},
+		Fee:          10,
+	}
+	ftp := must(b.Build())
+	if len(ftp.Sources) != 2 || len(ftp.PreparedDestinations) != 3 {
+		t.Fatalf("expected 2 sources and 3 destinations, got %d and %d", len(ftp.Sources), len(ftp.PreparedDestinations))
+	}
+
+	res := must(w.Sign(rand.Reader, ftp, nil))
+	if fee, _ := res.Tx.GetFee(); fee != 10 {
+		t.Errorf("fee = %d, expected 10", fee)
+	}
+	received := must(recipient.ScanTransaction(res.Tx))
+	if received.Total(assetHex) != 300 {
+		t.Errorf("recipient received %d, expected 300", received.Total(assetHex))
+	}
+	change := must(w.ScanTransaction(res.Tx))
+	if change.Total(assetHex) != 700 || change.Total(zanolib.NativeCoinAssetId) != 380 {
+		t.Errorf("change = %d asset and %d native, expected 700 and 380", change.Total(assetHex), change.Total(zanolib.NativeCoinAssetId))
+	}
+
+	// not enough of the asset
+	b.Destinations[0].Amount = 1001
+	if _, err := b.Build(); err == nil {
+		t.Errorf("sending more than available should fail")
+	}
+}
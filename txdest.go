@@ -18,7 +18,7 @@ type TxDest struct {
 	Addr            []*zanobase.AccountPublicAddr // account_public_address; destination address, in case of 1 address - txout_to_key, in case of more - txout_multisig
 	MinimumSigs     uint64                        // if txout_multisig: minimum signatures that are required to spend this output (minimum_sigs <= addr.size())  IF txout_to_key - not used
 	AmountToProvide uint64                        // amount money that provided by initial creator of tx, used with partially created transactions
-	UnlockTime      uint64                        //
+	UnlockTime      uint64                        // per output unlock time, stored in etc_tx_details_unlock_time2
 	HtlcOptions     *TxDestHtlcOut                // destination_option_htlc_out
 	AssetId         *zanobase.Point               // not blinded, not premultiplied
 	Flags           uint64                        // set of flags (see tx_destination_entry_flags)
@@ -43,13 +43,21 @@ func (dst *TxDest) ConcealingPoint(scalar *edwards25519.Scalar, ogc *zanobase.Ge
 	return new(edwards25519.Point).ScalarMult(h, v)
 }
 
+// assetId returns the asset id of dst, the native coin if it is not set
+func (dst *TxDest) assetId() *edwards25519.Point {
+	if dst.AssetId == nil || dst.AssetId.Point == nil {
+		return zanocrypto.NativeCoinAssetIdPt
+	}
+	return dst.AssetId.Point
+}
+
 func (dst *TxDest) BlindedAssetId(scalar *edwards25519.Scalar, ogc *zanobase.GenContext, i int) *edwards25519.Point {
 	// zanocrypto.HashToScalar will also reduce
 	assetBlindingMask := zanocrypto.HashToScalar(slices.Concat([]byte("ZANO_HDS_OUT_ASSET_BLIND_MASK__\x00"), scalar.Bytes()))
 	ogc.AssetIdBlindingMasks[i] = &zanobase.Scalar{new(edwards25519.Scalar).Set(assetBlindingMask)}
 
 	// 1) Decompress dst.AssetId (Q) to a Point
-	Q := new(edwards25519.Point).Set(dst.AssetId.Point)
+	Q := new(edwards25519.Point).Set(dst.assetId())
 
 	// 3) Multiply R = assetBlindingMask * X
 	R := new(edwards25519.Point).ScalarMult(assetBlindingMask, zanocrypto.C_point_X)
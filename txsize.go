@@ -0,0 +1,164 @@
+package zanolib
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+// bgeN is the n parameter of BGE proofs used in asset surjection proofs, see Generate_BGE_Proof
+const bgeN = 4
+
+// TxSizeParams describes a transaction whose size is estimated by EstimateTxSize
+type TxSizeParams struct {
+	Inputs     int
+	RingSize   int // ring members per input, defaults to DefaultRingSize
+	Outputs    int
+	Version    uint64              // defaults to zanobase.TransactionVersionPostHF4
+	Extra      []*zanobase.Variant // extra entries in addition to the ones always added when signing
+	Attachment []*zanobase.Variant
+}
+
+// EstimateTxSize returns the serialized size in bytes of a signed transaction as produced
+// by Wallet.Sign, including its CLSAG signatures, BGE asset surjection proofs and BP+ range
+// proof. The result is exact, except for derivation hints which are counted once per
+// output, and for deflated service attachments, making it an upper bound.
+func EstimateTxSize(p *TxSizeParams) (int, error) {
+	if p.Inputs <= 0 || p.Outputs <= 0 {
+		return 0, errors.New("transaction needs at least one input and one output")
+	}
+	ringSize := p.RingSize
+	if ringSize == 0 {
+		ringSize = DefaultRingSize
+	}
+	version := p.Version
+	if version == 0 {
+		version = zanobase.TransactionVersionPostHF4
+	}
+
+	pt := &zanobase.Point{edwards25519.NewIdentityPoint()}
+	sc := &zanobase.Scalar{new(edwards25519.Scalar)}
+	pts := func(n int) []*zanobase.Point {
+		res := make([]*zanobase.Point, n)
+		for i := range res {
+			res[i] = pt
+		}
+		return res
+	}
+	scs := func(n int) []*zanobase.Scalar {
+		res := make([]*zanobase.Scalar, n)
+		for i := range res {
+			res[i] = sc
+		}
+		return res
+	}
+
+	tx := &zanobase.Transaction{Version: zanobase.Varint(version)}
+	for i := 0; i < p.Inputs; i++ {
+		in := &zanobase.TxInZcInput{KeyImage: pt}
+		for j := 0; j < ringSize; j++ {
+			in.KeyOffsets = append(in.KeyOffsets, zanobase.VariantFor(uint64(0)))
+		}
+		tx.Vin = append(tx.Vin, zanobase.VariantFor(in))
+		tx.Signatures = append(tx.Signatures, zanobase.VariantFor(&zanobase.ZCSig{
+			PseudoOutAmountCommitment: pt,
+			PseudoOutBlindedAssetId:   pt,
+			GGX:                       &zanobase.CLSAG_Sig{C: sc, Rg: scs(ringSize), Rx: scs(ringSize), K1: pt, K2: pt},
+		}))
+	}
+
+	tx.Extra = append(tx.Extra,
+		&zanobase.Variant{Tag: zanobase.TagPubKey, Value: zanobase.Value256{}},
+		&zanobase.Variant{Tag: zanobase.TagEtcTxFlags16, Value: uint16(0)},
+	)
+	tx.Extra = append(tx.Extra, p.Extra...)
+	// encryption does not change sizes, but adds a tx_crypto_checksum
+	chs := zanobase.VariantFor(&zanobase.TxCryptoChecksum{})
+	if _, crypted, err := cryptAttachments(p.Extra, make([]byte, 32), true); err != nil {
+		return 0, err
+	} else if crypted {
+		tx.Extra = append(tx.Extra, chs)
+	}
+	if len(p.Attachment) > 0 {
+		tx.Attachment = append(tx.Attachment, p.Attachment...)
+		if _, crypted, err := cryptAttachments(p.Attachment, make([]byte, 32), true); err != nil {
+			return 0, err
+		} else if crypted {
+			tx.Attachment = append(tx.Attachment, chs)
+		}
+		buf := &bytes.Buffer{}
+		if err := zanobase.Serialize(buf, tx.Attachment); err != nil {
+			return 0, err
+		}
+		tx.Extra = append(tx.Extra, zanobase.VariantFor(&zanobase.ExtraAttachmentInfo{Size: uint64(buf.Len()), Cnt: uint64(len(tx.Attachment))}))
+	}
+
+	asp := &zanobase.ZCAssetSurjectionProof{}
+	bgeM := max(1, ceilLog(p.Inputs, bgeN))
+	for i := 0; i < p.Outputs; i++ {
+		tx.Vout = append(tx.Vout, zanobase.VariantFor(&zanobase.TxOutZarcanium{}))
+		tx.Extra = append(tx.Extra, &zanobase.Variant{Tag: zanobase.TagDerivationHint, Value: make([]byte, 2)})
+		asp.BGEProofs = append(asp.BGEProofs, &zanobase.BGEProof{A: pt, B: pt, Pk: pts(bgeM), F: scs(bgeM * (bgeN - 1)), Y: sc, Z: sc})
+	}
+	tx.Extra = append(tx.Extra, zanobase.VariantFor(&zanobase.ZarcaniumTxDataV1{}))
+
+	bppLog := ceilLog(p.Outputs, 2) + zanocrypto.TraitZCout.Log2N
+	tx.Proofs = append(tx.Proofs,
+		zanobase.VariantFor(asp),
+		zanobase.VariantFor(&zanobase.ZCOutsRangeProof{
+			BPP: &zanobase.BPPSignature{Lv: pts(bppLog), Rv: pts(bppLog), A0: pt, A: pt, B: pt, R: sc, S: sc, Delta: sc},
+			AggregationProof: &zanobase.UGAggProof{
+				AmountCommitmentsForRPAgg: pts(p.Outputs),
+				Y0s:                       scs(p.Outputs),
+				Y1s:                       scs(p.Outputs),
+				C:                         sc,
+			},
+		}),
+		zanobase.VariantFor(&zanobase.ZCBalanceProof{DSS: &zanobase.GenericDoubleSchnorrSig{C: sc, Y0: sc, Y1: sc}}),
+	)
+
+	buf, err := tx.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+// EstimateSize returns the estimated size of the transaction signed from ftp, see
+// EstimateTxSize
+func (ftp *FinalizeTxParam) EstimateSize() (int, error) {
+	details, err := ftp.detailsExtra()
+	if err != nil {
+		return 0, err
+	}
+	p := &TxSizeParams{
+		Inputs:     len(ftp.Sources),
+		Outputs:    len(ftp.PreparedDestinations),
+		Version:    ftp.TxVersion,
+		Extra:      append(slices.Clone(ftp.Extra), details...),
+		Attachment: ftp.Attachments,
+	}
+	for _, src := range ftp.Sources {
+		p.RingSize = max(p.RingSize, len(src.Outputs))
+	}
+	return EstimateTxSize(p)
+}
+
+// FeeForSize returns the fee for a transaction of the given size in bytes, given a fee
+// per started kilobyte (1024 bytes)
+func FeeForSize(size int, feePerKB uint64) uint64 {
+	return uint64((size+1023)/1024) * feePerKB
+}
+
+// ceilLog returns the smallest m such that n^m >= v
+func ceilLog(v, n int) int {
+	m := 0
+	for x := 1; x < v; x *= n {
+		m++
+	}
+	return m
+}
@@ -0,0 +1,49 @@
+package zanolib_test
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+func TestEstimateTxSize(t *testing.T) {
+	w := testWallet(t)
+
+	for _, att := range [][]*zanobase.Variant{nil, {zanobase.VariantFor(&zanobase.TxComment{Comment: "hello"})}} {
+		ftp := testSignableFTP(t, w)
+		ftp.Attachments = att
+		size := must(ftp.EstimateSize())
+
+		res := must(w.Sign(rand.Reader, ftp, nil))
+		blob := must(res.Tx.Bytes())
+		if size != len(blob) {
+			t.Errorf("estimated size %d, actual size %d", size, len(blob))
+		}
+	}
+
+	small := must(zanolib.EstimateTxSize(&zanolib.TxSizeParams{Inputs: 1, Outputs: 2}))
+	large := must(zanolib.EstimateTxSize(&zanolib.TxSizeParams{Inputs: 5, Outputs: 2}))
+	if large <= small {
+		t.Errorf("more inputs should result in a larger transaction")
+	}
+	if _, err := zanolib.EstimateTxSize(&zanolib.TxSizeParams{}); err == nil {
+		t.Errorf("empty transaction should fail")
+	}
+
+	// testSignableFTP pays a fee of 10 for a transaction of a few kilobytes
+	p := &zanolib.Policy{MaxFeePerKB: 1}
+	if dec := must(p.Evaluate(w, testSignableFTP(t, w), time.Now())); dec.Allowed {
+		t.Errorf("fee should exceed the maximum fee per kilobyte")
+	}
+	p.MaxFeePerKB = 10
+	if dec := must(p.Evaluate(w, testSignableFTP(t, w), time.Now())); !dec.Allowed {
+		t.Errorf("fee should be allowed, got reasons: %v", dec.Reasons)
+	}
+
+	if zanolib.FeeForSize(1024, 10) != 10 || zanolib.FeeForSize(1025, 10) != 20 {
+		t.Errorf("invalid fee for size")
+	}
+}
@@ -1,6 +1,7 @@
 package zanolib
 
 import (
+	"errors"
 	"io"
 
 	"filippo.io/edwards25519"
@@ -35,32 +36,61 @@ type TxSource struct {
 
 func (src *TxSource) IsZC() bool {
 	//return !real_out_amount_blinding_mask.is_zero()
-	return src.RealOutAssetIdBlindingMask.Scalar.Equal(zanocrypto.ScZero) == 0
+	// (outputs of miner transactions have a zero asset id blinding mask)
+	return src.RealOutAmountBlindingMask.Scalar.Equal(zanocrypto.ScZero) == 0
 }
 
-func (src *TxSource) generateZCSig(rnd io.Reader, tx *zanobase.Transaction, inputIndex int, sig *zanobase.ZCSig, txHashForSig []byte, ogc *zanobase.GenContext) error {
+// AssetId returns the asset id of the real output of src, H = 8 * T - r * X with T the
+// blinded asset id stored premultiplied by 1/8. Sources without ZC data are native coin.
+func (src *TxSource) AssetId() (*edwards25519.Point, error) {
+	if src.RealOutput >= uint64(len(src.Outputs)) {
+		if len(src.Outputs) == 0 {
+			return zanocrypto.NativeCoinAssetIdPt, nil
+		}
+		return nil, errors.New("real output out of range")
+	}
+	realOut := src.Outputs[src.RealOutput]
+	if realOut.BlindedAssetID == nil || realOut.BlindedAssetID.Point == nil {
+		return zanocrypto.NativeCoinAssetIdPt, nil
+	}
+	res := new(edwards25519.Point).MultByCofactor(realOut.BlindedAssetID.Point)
+	if src.RealOutAssetIdBlindingMask != nil && src.RealOutAssetIdBlindingMask.Scalar != nil {
+		res = res.Subtract(res, new(edwards25519.Point).ScalarMult(src.RealOutAssetIdBlindingMask.Scalar, zanocrypto.C_point_X))
+	}
+	return res, nil
+}
+
+func (src *TxSource) generateZCSig(rnd io.Reader, tx *zanobase.Transaction, inputIndex int, last bool, sig *zanobase.ZCSig, txHashForSig []byte, ogc *zanobase.GenContext) error {
 	in := zanobase.VariantAs[*zanobase.TxInZcInput](tx.Vin[inputIndex])
 
 	//crypto::point_t asset_id_pt(se.asset_id);
-	assetId := ogc.AssetIds[0].Point // TODO get asset id for source? How?
-	//assetId := edwards25519.NewIdentityPoint()
+	assetId, err := src.AssetId()
+	if err != nil {
+		return err
+	}
 	//crypto::point_t source_blinded_asset_id = asset_id_pt + se.real_out_asset_id_blinding_mask * crypto::c_point_X; // T_i = H_i + r_i * X
 	sourceBlindedAssetId := new(edwards25519.Point).Add(assetId, new(edwards25519.Point).ScalarMult(src.RealOutAssetIdBlindingMask.Scalar, zanocrypto.C_point_X))
 	//ogc.real_zc_ins_asset_ids.emplace_back(asset_id_pt);
 	ogc.RealZcInsAssetIds = append(ogc.RealZcInsAssetIds, &zanobase.Point{assetId})
 
 	//crypto::scalar_t pseudo_out_amount_blinding_mask = 0;
-
-	//pseudo_out_amount_blinding_mask = ogc.amount_blinding_masks_sum - ogc.pseudo_out_amount_blinding_masks_sum + (ogc.ao_commitment_in_outputs ? ogc.ao_amount_blinding_mask : -ogc.ao_amount_blinding_mask);      // A_1 - A^p_0 = (f_1 - f'_1) * G   =>  f'_{i-1} = sum{y_j} - sum{f'_i}
 	var pseudoOutAmountBlindingMask *edwards25519.Scalar
-	A := new(edwards25519.Scalar).Set(ogc.AoAmountBlindingMask.Scalar)
-	if !ogc.AoCommitmentInOutputs {
-		A = A.Negate(A)
-	}
-	if ogc.PseudoOutAmountBlindingMasksSum != nil {
-		A = new(edwards25519.Scalar).Add(ogc.PseudoOutAmountBlindingMasksSum.Scalar, A)
+	if last {
+		//pseudo_out_amount_blinding_mask = ogc.amount_blinding_masks_sum - ogc.pseudo_out_amount_blinding_masks_sum + (ogc.ao_commitment_in_outputs ? ogc.ao_amount_blinding_mask : -ogc.ao_amount_blinding_mask);      // A_1 - A^p_0 = (f_1 - f'_1) * G   =>  f'_{i-1} = sum{y_j} - sum{f'_i}
+		A := new(edwards25519.Scalar).Set(ogc.AoAmountBlindingMask.Scalar)
+		if !ogc.AoCommitmentInOutputs {
+			A = A.Negate(A)
+		}
+		if ogc.PseudoOutAmountBlindingMasksSum != nil {
+			A = new(edwards25519.Scalar).Add(ogc.PseudoOutAmountBlindingMasksSum.Scalar, A)
+		}
+		pseudoOutAmountBlindingMask = new(edwards25519.Scalar).Subtract(ogc.AmountBlindingMasksSum.Scalar, A)
+	} else {
+		//pseudo_out_amount_blinding_mask.make_random();
+		//ogc.pseudo_out_amount_blinding_masks_sum += pseudo_out_amount_blinding_mask;
+		pseudoOutAmountBlindingMask = zanocrypto.RandomScalar(rnd)
+		addRefScalar(&ogc.PseudoOutAmountBlindingMasksSum, pseudoOutAmountBlindingMask)
 	}
-	pseudoOutAmountBlindingMask = new(edwards25519.Scalar).Subtract(ogc.AmountBlindingMasksSum.Scalar, A)
 
 	pseudoOutAssetIdBlindingMask := zanocrypto.RandomScalar(rnd)
 
@@ -79,6 +79,10 @@ func (w *Wallet) ParseFTP(buf []byte) (*FinalizeTxParam, error) {
 	return ParseFTP(buf, key)
 }
 
+func (w *Wallet) ParseFTPWithOptions(buf []byte, opts *ParseOptions) (*FinalizeTxParam, error) {
+	return ParseFTPWithOptions(buf, w.ViewPrivKey.Bytes(), opts)
+}
+
 func (w *Wallet) ParseFinalized(buf []byte) (*FinalizedTx, error) {
 	// buf is encrypted using chacha8 xor initialized with the view private key
 	key := w.ViewPrivKey.Bytes()
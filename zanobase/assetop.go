@@ -0,0 +1,212 @@
+package zanobase
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/KarpelesLab/rc"
+)
+
+// Operation types of AssetDescriptorOperation
+const (
+	AssetDescriptorOperationUndefined  = 0
+	AssetDescriptorOperationRegister   = 1
+	AssetDescriptorOperationEmit       = 2
+	AssetDescriptorOperationUpdate     = 3
+	AssetDescriptorOperationPublicBurn = 4
+)
+
+// AssetDescriptorBase is asset_descriptor_base. OwnerEthPubKey is only serialized from
+// version 1.
+type AssetDescriptorBase struct {
+	Version        uint8    `json:"version"`
+	TotalMaxSupply uint64   `json:"total_max_supply"`
+	CurrentSupply  uint64   `json:"current_supply"`
+	DecimalPoint   uint8    `json:"decimal_point"`
+	Ticker         string   `json:"ticker"`
+	FullName       string   `json:"full_name"`
+	MetaInfo       string   `json:"meta_info"`
+	Owner          Value256 `json:"owner"`
+	HiddenSupply   bool     `json:"hidden_supply"`
+	OwnerEthPubKey []byte   `json:"owner_eth_pub_key,omitempty"` // optional, 33 bytes
+}
+
+// AssetDescriptorOperation is asset_descriptor_operation (extra_v). Versions 0 and 1 always
+// carry Descriptor and AmountCommitment, version 1 adds the optional AssetId. Version 2 makes
+// every field optional and adds Amount, AssetIdSalt and Etc.
+type AssetDescriptorOperation struct {
+	Version          uint8                `json:"version"`
+	OperationType    uint8                `json:"operation_type"`
+	AmountCommitment *Value256            `json:"amount_commitment,omitempty"` // premultiplied by 1/8
+	AssetId          *Value256            `json:"asset_id,omitempty"`
+	Descriptor       *AssetDescriptorBase `json:"descriptor,omitempty"`
+	Amount           *uint64              `json:"amount,omitempty"`
+	AssetIdSalt      *uint32              `json:"asset_id_salt,omitempty"`
+	Etc              []*Variant           `json:"etc,omitempty"`
+}
+
+func (d *AssetDescriptorBase) ReadFrom(r io.Reader) (int64, error) {
+	rc := rc.New(r)
+	if err := Deserialize(rc, &d.Version); err != nil {
+		return rc.Error64(err)
+	}
+	if d.Version > 1 {
+		return rc.Error64(fmt.Errorf("unsupported asset descriptor version %d", d.Version))
+	}
+	for _, v := range []any{&d.TotalMaxSupply, &d.CurrentSupply, &d.DecimalPoint, &d.Ticker, &d.FullName, &d.MetaInfo, &d.Owner, &d.HiddenSupply} {
+		if err := Deserialize(rc, v); err != nil {
+			return rc.Error64(err)
+		}
+	}
+	if d.Version >= 1 {
+		err := readOptional(rc, func() error {
+			d.OwnerEthPubKey = make([]byte, 33)
+			_, err := io.ReadFull(rc, d.OwnerEthPubKey)
+			return err
+		})
+		if err != nil {
+			return rc.Error64(err)
+		}
+	}
+	return rc.Ret64()
+}
+
+func (d *AssetDescriptorBase) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	for _, v := range []any{d.Version, d.TotalMaxSupply, d.CurrentSupply, d.DecimalPoint, d.Ticker, d.FullName, d.MetaInfo, d.Owner, d.HiddenSupply} {
+		if err := Serialize(cw, v); err != nil {
+			return cw.n, err
+		}
+	}
+	if d.Version >= 1 {
+		if d.OwnerEthPubKey != nil && len(d.OwnerEthPubKey) != 33 {
+			return cw.n, errors.New("invalid owner eth public key length")
+		}
+		err := writeOptional(cw, d.OwnerEthPubKey != nil, func() error {
+			_, err := cw.Write(d.OwnerEthPubKey)
+			return err
+		})
+		if err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+func (op *AssetDescriptorOperation) ReadFrom(r io.Reader) (int64, error) {
+	rc := rc.New(r)
+	for _, v := range []any{&op.Version, &op.OperationType} {
+		if err := Deserialize(rc, v); err != nil {
+			return rc.Error64(err)
+		}
+	}
+	switch op.Version {
+	case 0, 1:
+		op.Descriptor = new(AssetDescriptorBase)
+		op.AmountCommitment = new(Value256)
+		for _, v := range []any{op.Descriptor, op.AmountCommitment} {
+			if err := Deserialize(rc, v); err != nil {
+				return rc.Error64(err)
+			}
+		}
+		if op.Version == 1 {
+			if err := readOptional(rc, func() error { return Deserialize(rc, &op.AssetId) }); err != nil {
+				return rc.Error64(err)
+			}
+		}
+	case 2:
+		fields := []func() error{
+			func() error { return Deserialize(rc, &op.AmountCommitment) },
+			func() error { return Deserialize(rc, &op.AssetId) },
+			func() error { return Deserialize(rc, &op.Descriptor) },
+			func() error { op.Amount = new(uint64); return Deserialize(rc, op.Amount) },
+			func() error { op.AssetIdSalt = new(uint32); return Deserialize(rc, op.AssetIdSalt) },
+		}
+		for _, f := range fields {
+			if err := readOptional(rc, f); err != nil {
+				return rc.Error64(err)
+			}
+		}
+		if err := Deserialize(rc, &op.Etc); err != nil {
+			return rc.Error64(err)
+		}
+	default:
+		return rc.Error64(fmt.Errorf("unsupported asset descriptor operation version %d", op.Version))
+	}
+	return rc.Ret64()
+}
+
+func (op *AssetDescriptorOperation) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	for _, v := range []any{op.Version, op.OperationType} {
+		if err := Serialize(cw, v); err != nil {
+			return cw.n, err
+		}
+	}
+	switch op.Version {
+	case 0, 1:
+		if op.Descriptor == nil || op.AmountCommitment == nil {
+			return cw.n, errors.New("asset descriptor operation requires a descriptor and an amount commitment")
+		}
+		for _, v := range []any{op.Descriptor, op.AmountCommitment} {
+			if err := Serialize(cw, v); err != nil {
+				return cw.n, err
+			}
+		}
+		if op.Version == 1 {
+			if err := writeOptional(cw, op.AssetId != nil, func() error { return Serialize(cw, op.AssetId) }); err != nil {
+				return cw.n, err
+			}
+		}
+	case 2:
+		fields := []struct {
+			present bool
+			value   any
+		}{
+			{op.AmountCommitment != nil, op.AmountCommitment},
+			{op.AssetId != nil, op.AssetId},
+			{op.Descriptor != nil, op.Descriptor},
+			{op.Amount != nil, op.Amount},
+			{op.AssetIdSalt != nil, op.AssetIdSalt},
+		}
+		for _, f := range fields {
+			if err := writeOptional(cw, f.present, func() error { return Serialize(cw, f.value) }); err != nil {
+				return cw.n, err
+			}
+		}
+		if err := Serialize(cw, op.Etc); err != nil {
+			return cw.n, err
+		}
+	default:
+		return cw.n, fmt.Errorf("unsupported asset descriptor operation version %d", op.Version)
+	}
+	return cw.n, nil
+}
+
+// readOptional reads a boost::optional, which is prefixed with a "is none" flag
+func readOptional(r rc.ByteAndReadReader, read func() error) error {
+	isNone, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch isNone {
+	case 0:
+		return read()
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("invalid optional flag %d", isNone)
+	}
+}
+
+func writeOptional(w io.Writer, present bool, write func() error) error {
+	if !present {
+		_, err := w.Write([]byte{1})
+		return err
+	}
+	if _, err := w.Write([]byte{0}); err != nil {
+		return err
+	}
+	return write()
+}
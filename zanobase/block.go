@@ -0,0 +1,223 @@
+package zanobase
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/KarpelesLab/rc"
+	"golang.org/x/crypto/sha3"
+)
+
+// maxBlockTxHashes is a sanity limit on the number of transactions referenced by a block
+const maxBlockTxHashes = 65536
+
+type BlockHeader struct {
+	MajorVersion uint8    `json:"major_version"`
+	MinorVersion uint8    `json:"minor_version"` // varint
+	Timestamp    uint64   `json:"timestamp"`     // varint
+	PrevId       Value256 `json:"prev_id"`
+	Nonce        uint64   `json:"nonce"`
+	Flags        uint8    `json:"flags"`
+}
+
+type Block struct {
+	BlockHeader
+	MinerTx  *Transaction `json:"miner_tx"`
+	TxHashes []Value256   `json:"tx_hashes"`
+}
+
+// ParseBlock decodes a raw block blob and checks that no data follows the block.
+func ParseBlock(buf []byte) (*Block, error) {
+	r := bytes.NewReader(buf)
+	res := new(Block)
+	err := Deserialize(r, res)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("trailing data")
+	}
+	return res, nil
+}
+
+// Bytes returns the block serialized as a raw blob
+func (b *Block) Bytes() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	_, err := b.WriteTo(buf)
+	return buf.Bytes(), err
+}
+
+func (h *BlockHeader) WriteTo(w io.Writer) (int64, error) {
+	// major_version, nonce, prev_id, minor_version (varint), timestamp (varint), flags
+	buf := []byte{h.MajorVersion}
+	buf = binary.LittleEndian.AppendUint64(buf, h.Nonce)
+	buf = append(buf, h.PrevId[:]...)
+	buf = VarintAppendUint64(buf, uint64(h.MinorVersion))
+	buf = VarintAppendUint64(buf, h.Timestamp)
+	buf = append(buf, h.Flags)
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+func (h *BlockHeader) ReadFrom(r io.Reader) (int64, error) {
+	rc := rc.New(r)
+	var err error
+	h.MajorVersion, err = rc.ReadByte()
+	if err != nil {
+		return rc.Error64(err)
+	}
+	var nonce [8]byte
+	if err = rc.ReadFull(nonce[:]); err != nil {
+		return rc.Error64(notEOF(err))
+	}
+	h.Nonce = binary.LittleEndian.Uint64(nonce[:])
+	if err = rc.ReadFull(h.PrevId[:]); err != nil {
+		return rc.Error64(notEOF(err))
+	}
+	minor, err := VarintReadUint64(rc)
+	if err != nil {
+		return rc.Error64(err)
+	}
+	if minor > 0xff {
+		return rc.Error64(fmt.Errorf("invalid block minor version %d", minor))
+	}
+	h.MinorVersion = uint8(minor)
+	h.Timestamp, err = VarintReadUint64(rc)
+	if err != nil {
+		return rc.Error64(err)
+	}
+	h.Flags, err = rc.ReadByte()
+	if err != nil {
+		return rc.Error64(notEOF(err))
+	}
+	return rc.Ret64()
+}
+
+func (b *Block) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	if _, err := b.BlockHeader.WriteTo(cw); err != nil {
+		return cw.n, err
+	}
+	if b.MinerTx == nil {
+		return cw.n, errors.New("block has no miner transaction")
+	}
+	if _, err := b.MinerTx.WriteTo(cw); err != nil {
+		return cw.n, err
+	}
+	if _, err := cw.Write(Varint(len(b.TxHashes)).Bytes()); err != nil {
+		return cw.n, err
+	}
+	for _, h := range b.TxHashes {
+		if _, err := cw.Write(h[:]); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+func (b *Block) ReadFrom(r io.Reader) (int64, error) {
+	rc := rc.New(r)
+	if _, err := b.BlockHeader.ReadFrom(rc); err != nil {
+		return rc.Error64(err)
+	}
+	b.MinerTx = new(Transaction)
+	if _, err := b.MinerTx.ReadFrom(rc); err != nil {
+		return rc.Error64(fmt.Errorf("while reading miner tx: %w", err))
+	}
+	ln, err := VarintReadUint64(rc)
+	if err != nil {
+		return rc.Error64(err)
+	}
+	if ln > maxBlockTxHashes {
+		return rc.Error64(fmt.Errorf("too many transactions in block: %d > %d", ln, maxBlockTxHashes))
+	}
+	b.TxHashes = make([]Value256, ln)
+	for n := range b.TxHashes {
+		if err = rc.ReadFull(b.TxHashes[n][:]); err != nil {
+			return rc.Error64(notEOF(err))
+		}
+	}
+	return rc.Ret64()
+}
+
+// HashingBlob returns the data hashed to compute the block id: the serialized header,
+// the tree hash of the miner tx hash followed by the tx hashes, and the number of
+// transactions including the miner tx.
+func (b *Block) HashingBlob() ([]byte, error) {
+	if b.MinerTx == nil {
+		return nil, errors.New("block has no miner transaction")
+	}
+	minerHash, err := b.MinerTx.Hash()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]Value256, 0, len(b.TxHashes)+1)
+	hashes = append(hashes, Value256(minerHash))
+	hashes = append(hashes, b.TxHashes...)
+
+	buf := &bytes.Buffer{}
+	b.BlockHeader.WriteTo(buf)
+	root, err := TreeHash(hashes)
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(root[:])
+	buf.Write(Varint(len(hashes)).Bytes())
+	return buf.Bytes(), nil
+}
+
+// Hash returns the block id. As the hashing blob is serialized as a string before being
+// hashed, its length is prepended as a varint.
+func (b *Block) Hash() ([]byte, error) {
+	blob, err := b.HashingBlob()
+	if err != nil {
+		return nil, err
+	}
+	h := sha3.NewLegacyKeccak256()
+	h.Write(Varint(len(blob)).Bytes())
+	h.Write(blob)
+	return h.Sum(nil), nil
+}
+
+// TreeHash computes the CryptoNote merkle tree hash of the given hashes. hashes must not be
+// empty.
+func TreeHash(hashes []Value256) (Value256, error) {
+	switch len(hashes) {
+	case 0:
+		return Value256{}, errors.New("tree hash of empty list")
+	case 1:
+		return hashes[0], nil
+	case 2:
+		return hashPair(hashes[0], hashes[1]), nil
+	}
+
+	// cnt is the largest power of two strictly less than the number of hashes
+	cnt := 1
+	for cnt*2 < len(hashes) {
+		cnt *= 2
+	}
+
+	ints := make([]Value256, cnt)
+	direct := 2*cnt - len(hashes)
+	copy(ints, hashes[:direct])
+	for i, j := direct, direct; j < cnt; i, j = i+2, j+1 {
+		ints[j] = hashPair(hashes[i], hashes[i+1])
+	}
+	for cnt > 2 {
+		cnt /= 2
+		for i, j := 0, 0; j < cnt; i, j = i+2, j+1 {
+			ints[j] = hashPair(ints[i], ints[i+1])
+		}
+	}
+	return hashPair(ints[0], ints[1]), nil
+}
+
+func hashPair(a, b Value256) Value256 {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(a[:])
+	h.Write(b[:])
+	return Value256(h.Sum(nil))
+}
@@ -0,0 +1,107 @@
+package zanobase_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ModChain/zanolib/zanobase"
+	"golang.org/x/crypto/sha3"
+)
+
+func keccakPair(a, b zanobase.Value256) zanobase.Value256 {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(a[:])
+	h.Write(b[:])
+	return zanobase.Value256(h.Sum(nil))
+}
+
+func TestTreeHash(t *testing.T) {
+	h := make([]zanobase.Value256, 5)
+	for n := range h {
+		h[n][0] = byte(n + 1)
+	}
+
+	if must(zanobase.TreeHash(h[:1])) != h[0] {
+		t.Errorf("tree hash of one element should be the element itself")
+	}
+	if must(zanobase.TreeHash(h[:2])) != keccakPair(h[0], h[1]) {
+		t.Errorf("invalid tree hash for 2 elements")
+	}
+	if must(zanobase.TreeHash(h[:3])) != keccakPair(h[0], keccakPair(h[1], h[2])) {
+		t.Errorf("invalid tree hash for 3 elements")
+	}
+	if must(zanobase.TreeHash(h[:4])) != keccakPair(keccakPair(h[0], h[1]), keccakPair(h[2], h[3])) {
+		t.Errorf("invalid tree hash for 4 elements")
+	}
+	expect := keccakPair(keccakPair(h[0], h[1]), keccakPair(h[2], keccakPair(h[3], h[4])))
+	if must(zanobase.TreeHash(h)) != expect {
+		t.Errorf("invalid tree hash for 5 elements")
+	}
+	if _, err := zanobase.TreeHash(nil); err == nil {
+		t.Errorf("tree hash of an empty list should fail")
+	}
+}
+
+func TestBlock(t *testing.T) {
+	blk := &zanobase.Block{
+		BlockHeader: zanobase.BlockHeader{
+			MajorVersion: 3,
+			MinorVersion: 0,
+			Timestamp:    1700000000,
+			Nonce:        0x1122334455667788,
+			Flags:        1,
+		},
+		MinerTx:  testTransaction(),
+		TxHashes: []zanobase.Value256{{1}, {2}},
+	}
+	blk.PrevId[0] = 0xff
+	blk.MinerTx.Vin = []*zanobase.Variant{zanobase.VariantFor(&zanobase.TxInGen{Height: 2500000})}
+
+	buf, err := blk.Bytes()
+	if err != nil {
+		t.Fatalf("failed to serialize block: %s", err)
+	}
+	blk2, err := zanobase.ParseBlock(buf)
+	if err != nil {
+		t.Fatalf("failed to parse block: %s", err)
+	}
+	if !bytes.Equal(must(blk2.Bytes()), buf) {
+		t.Errorf("block did not round trip")
+	}
+	if blk2.Nonce != blk.Nonce || blk2.Timestamp != blk.Timestamp || blk2.PrevId != blk.PrevId || len(blk2.TxHashes) != 2 {
+		t.Errorf("parsed block header differs: %+v", blk2.BlockHeader)
+	}
+	if _, err := zanobase.ParseBlock(append(buf, 0)); err == nil {
+		t.Errorf("trailing data should have been rejected")
+	}
+
+	// block id is keccak(varint(len(blob)) || blob)
+	blob := must(blk.HashingBlob())
+	minerHash := must(blk.MinerTx.Hash())
+	root := must(zanobase.TreeHash([]zanobase.Value256{zanobase.Value256(minerHash), {1}, {2}}))
+	if !bytes.Equal(blob[len(blob)-33:len(blob)-1], root[:]) || blob[len(blob)-1] != 3 {
+		t.Errorf("invalid hashing blob %x", blob)
+	}
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte{byte(len(blob))})
+	h.Write(blob)
+	if !bytes.Equal(must(blk.Hash()), h.Sum(nil)) {
+		t.Errorf("invalid block hash")
+	}
+	if !bytes.Equal(must(blk2.Hash()), h.Sum(nil)) {
+		t.Errorf("parsed block hash differs")
+	}
+
+	enc, err := json.Marshal(blk)
+	if err != nil {
+		t.Fatalf("failed to marshal block: %s", err)
+	}
+	blk3 := new(zanobase.Block)
+	if err := json.Unmarshal(enc, blk3); err != nil {
+		t.Fatalf("failed to unmarshal block: %s", err)
+	}
+	if !bytes.Equal(must(blk3.Bytes()), buf) {
+		t.Errorf("block did not round trip through json")
+	}
+}
@@ -10,3 +10,16 @@ type BPPSignature struct {
 	S     *Scalar  // scalar_t
 	Delta *Scalar  // scalar_t
 }
+
+type BPPESignature struct {
+	// bppe_signature, double-blinded Bulletproofs+ (two masks per commitment)
+	Lv     []*Point // std::vector<public_key> size = ceil( log_2(m * n) )
+	Rv     []*Point // std::vector<public_key>
+	A0     *Point   // public_key
+	A      *Point   // public_key
+	B      *Point   // public_key
+	R      *Scalar  // scalar_t
+	S      *Scalar  // scalar_t
+	Delta1 *Scalar  // scalar_t
+	Delta2 *Scalar  // scalar_t
+}
@@ -3,6 +3,7 @@ package zanobase
 import (
 	"bufio"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -19,6 +20,10 @@ var (
 
 // Deserialize implements epee deserializer (kind of)
 func Deserialize(rx io.Reader, target any) error {
+	return deserializeTag(rx, target, "")
+}
+
+func deserializeTag(rx io.Reader, target any, tag string) error {
 	var err error
 	var buf rc.ByteAndReadReader
 	if v, ok := rx.(rc.ByteAndReadReader); ok {
@@ -50,9 +55,16 @@ func Deserialize(rx io.Reader, target any) error {
 		return subDeserialize(buf, obj.Addr().Interface(), "!")
 	}
 	if t == variantType {
+		if tag == tagKeepUnknown {
+			return subDeserialize(buf, obj.Addr().Interface(), tag)
+		}
 		return subDeserialize(buf, obj.Addr().Interface(), "!")
 	}
 	if t.Kind() == reflect.Slice {
+		var elemTag string
+		if tag == tagKeepUnknown {
+			elemTag = tag
+		}
 		ln, err := VarintReadUint64(buf)
 		if err != nil {
 			return err
@@ -61,11 +73,19 @@ func Deserialize(rx io.Reader, target any) error {
 			return fmt.Errorf("slice too large: %d > 128", ln)
 		}
 		val := reflect.MakeSlice(t, int(ln), int(ln))
+		var raw int
 		for i := 0; i < int(ln); i++ {
-			err = subDeserialize(buf, val.Index(i).Addr().Interface(), "")
+			err = subDeserialize(buf, val.Index(i).Addr().Interface(), elemTag)
 			if err != nil {
+				if raw > 0 && errors.Is(err, errRawVariantLength) {
+					// only one unknown entry per container can be recovered
+					return errors.New("more than one unknown variant in container")
+				}
 				return err
 			}
+			if v, ok := val.Index(i).Interface().(*Variant); ok && v != nil && v.isRaw() {
+				raw += 1
+			}
 		}
 		obj.Set(val)
 		return nil
@@ -92,7 +112,7 @@ func Deserialize(rx io.Reader, target any) error {
 func subDeserialize(r rc.ByteAndReadReader, o any, tag string) error {
 	var err error
 	switch v := o.(type) {
-	case *bool, *uint8, *uint16:
+	case *bool, *uint8, *uint16, *uint32:
 		err = binary.Read(r, binary.LittleEndian, v)
 	case *uint64:
 		if tag == "varint" {
@@ -132,14 +152,21 @@ func subDeserialize(r rc.ByteAndReadReader, o any, tag string) error {
 		}
 		return nil
 	case *Variant:
+		keepUnknown := tag == tagKeepUnknown
 		tagV, err := r.ReadByte()
 		if err != nil {
 			return err
 		}
 		tag := Tag(tagV)
+		def, ok := variantTags[tag]
+		if !ok {
+			if vr, ok := r.(*rawVariantReader); ok && keepUnknown {
+				return vr.readRaw(v, tag)
+			}
+			return fmt.Errorf("unsupported variant tag %d", tag)
+		}
 		v.Tag = tag
-		typ := tag.Type()
-		obj := reflect.New(typ)
+		obj := reflect.New(def.typ)
 		err = Deserialize(r, obj)
 		if err != nil {
 			return err
@@ -166,7 +193,7 @@ func subDeserialize(r rc.ByteAndReadReader, o any, tag string) error {
 		if tag == "!" {
 			return fmt.Errorf("unsupported deserialize type %T", o)
 		}
-		err = Deserialize(r, v)
+		err = deserializeTag(r, v, tag)
 
 	}
 	return err
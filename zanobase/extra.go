@@ -0,0 +1,109 @@
+package zanobase
+
+// Types used in extra_v, attachment_v and txin_etc_details_v variants
+
+type AccountPublicAddrOld struct {
+	SpendKey Value256 `json:"spend_key"`
+	ViewKey  Value256 `json:"view_key"`
+}
+
+// Signature is a crypto::signature
+type Signature struct {
+	C Value256 `json:"c"`
+	R Value256 `json:"r"`
+}
+
+type TxComment struct {
+	Comment string `json:"comment"`
+}
+
+type TxPayerOld struct {
+	Addr AccountPublicAddrOld `json:"acc_addr"`
+}
+
+type TxReceiverOld struct {
+	Addr AccountPublicAddrOld `json:"acc_addr"`
+}
+
+type TxPayer struct {
+	Addr AccountPublicAddr `json:"acc_addr"`
+}
+
+type TxReceiver struct {
+	Addr AccountPublicAddr `json:"acc_addr"`
+}
+
+type TxCryptoChecksum struct {
+	EncryptedKeyDerivation Value256 `json:"encrypted_key_derivation"` // key derivation encrypted with the sender's view secret key
+	DerivationHash         uint32   `json:"derivation_hash"`
+}
+
+type TxServiceAttachment struct {
+	ServiceId   string     `json:"service_id"`  // string identifying service which addressed this attachment
+	Instruction string     `json:"instruction"` // string identifying specific instructions for service/way to interpret data
+	Body        []byte     `json:"body"`        // any data identifying service, options etc
+	Security    []Value256 `json:"security"`    // some of commands need proof of owner
+	Flags       uint8      `json:"flags"`       // special flags, see TxServiceAttachmentEncryptBody and following
+}
+
+const (
+	TxServiceAttachmentEncryptBody                 = 1
+	TxServiceAttachmentDeflateBody                 = 2
+	TxServiceAttachmentEncryptBodyIsolateAuditable = 4
+	TxServiceAttachmentEncryptAddProof             = 8
+)
+
+type EtcTxDetailsUnlockTime struct {
+	V uint64 `json:"v" epee:"varint"`
+}
+
+type EtcTxDetailsUnlockTime2 struct {
+	UnlockTimeArray []Varint `json:"unlock_time_array"` // one unlock time per output
+}
+
+type EtcTxDetailsExpirationTime struct {
+	V uint64 `json:"v" epee:"varint"`
+}
+
+type EtcTxDetailsFlags struct {
+	V uint64 `json:"v" epee:"varint"`
+}
+
+type EtcTxTime struct {
+	V uint64 `json:"v" epee:"varint"`
+}
+
+type SignedParts struct {
+	NOuts   uint64 `json:"n_outs" epee:"varint"`
+	NExtras uint64 `json:"n_extras" epee:"varint"`
+}
+
+type ExtraAttachmentInfo struct {
+	Size uint64   `json:"sz" epee:"varint"`
+	Hash Value256 `json:"hsh"`
+	Cnt  uint64   `json:"cnt" epee:"varint"`
+}
+
+type ExtraUserData struct {
+	Buff string `json:"buff"`
+}
+
+type ExtraPadding struct {
+	Buff []byte `json:"buff"`
+}
+
+type ExtraAliasEntryOld struct {
+	Alias       string               `json:"alias"`
+	Address     AccountPublicAddrOld `json:"address"`
+	TextComment string               `json:"text_comment"`
+	ViewKey     []Value256           `json:"view_key"` // zero or one secret key
+	Sign        []Signature          `json:"sign"`     // zero or one signature
+}
+
+type ExtraAliasEntry struct {
+	Alias       string            `json:"alias"`
+	Address     AccountPublicAddr `json:"address"`
+	TextComment string            `json:"text_comment"`
+	ViewKey     []Value256        `json:"view_key"` // zero or one secret key
+	Sign        []Signature       `json:"sign"`     // zero or one signature
+}
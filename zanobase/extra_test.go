@@ -0,0 +1,130 @@
+package zanobase_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+func TestExtraVariants(t *testing.T) {
+	addr := zanobase.AccountPublicAddr{SpendKey: zanobase.Value256{1}, ViewKey: zanobase.Value256{2}, Flags: 1}
+	oldAddr := zanobase.AccountPublicAddrOld{SpendKey: zanobase.Value256{3}, ViewKey: zanobase.Value256{4}}
+
+	extra := []*zanobase.Variant{
+		zanobase.VariantFor(&zanobase.TxComment{Comment: "hello world"}),
+		zanobase.VariantFor(&zanobase.TxPayerOld{Addr: oldAddr}),
+		{Tag: zanobase.TagString, Value: "some string"},
+		zanobase.VariantFor(&zanobase.TxCryptoChecksum{EncryptedKeyDerivation: zanobase.Value256{5}, DerivationHash: 0xdeadbeef}),
+		zanobase.VariantFor(&zanobase.TxServiceAttachment{ServiceId: "d", Instruction: "x", Body: []byte{1, 2, 3}, Security: []zanobase.Value256{{6}}, Flags: zanobase.TxServiceAttachmentEncryptBody}),
+		zanobase.VariantFor(&zanobase.EtcTxDetailsUnlockTime{V: 1700000000}),
+		zanobase.VariantFor(&zanobase.EtcTxDetailsExpirationTime{V: 1700003600}),
+		zanobase.VariantFor(&zanobase.EtcTxDetailsFlags{V: 1}),
+		zanobase.VariantFor(&zanobase.SignedParts{NOuts: 2, NExtras: 300}),
+		zanobase.VariantFor(&zanobase.ExtraAttachmentInfo{Size: 1234, Hash: zanobase.Value256{7}, Cnt: 2}),
+		zanobase.VariantFor(&zanobase.ExtraUserData{Buff: "user data"}),
+		zanobase.VariantFor(&zanobase.ExtraAliasEntryOld{Alias: "oldalias", Address: oldAddr, TextComment: "comment", ViewKey: []zanobase.Value256{{8}}}),
+		zanobase.VariantFor(&zanobase.ExtraPadding{Buff: make([]byte, 17)}),
+		zanobase.VariantFor(&zanobase.EtcTxTime{V: 1700000001}),
+		zanobase.VariantFor(&zanobase.TxReceiverOld{Addr: oldAddr}),
+		zanobase.VariantFor(&zanobase.EtcTxDetailsUnlockTime2{UnlockTimeArray: []zanobase.Varint{0, 1000, 1700000000}}),
+		zanobase.VariantFor(&zanobase.TxPayer{Addr: addr}),
+		zanobase.VariantFor(&zanobase.TxReceiver{Addr: addr}),
+		zanobase.VariantFor(&zanobase.ExtraAliasEntry{Alias: "alias", Address: addr, Sign: []zanobase.Signature{{C: zanobase.Value256{9}, R: zanobase.Value256{10}}}}),
+	}
+	for n, v := range extra {
+		if v.Tag == 0xff {
+			t.Errorf("variant #%d (%T) has no registered tag", n, v.Value)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := zanobase.Serialize(buf, extra); err != nil {
+		t.Fatalf("failed to serialize: %s", err)
+	}
+	var extra2 []*zanobase.Variant
+	if err := zanobase.Deserialize(bytes.NewReader(buf.Bytes()), &extra2); err != nil {
+		t.Fatalf("failed to deserialize: %s", err)
+	}
+	buf2 := &bytes.Buffer{}
+	if err := zanobase.Serialize(buf2, extra2); err != nil {
+		t.Fatalf("failed to serialize decoded extra: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Errorf("extra did not round trip:\n%x\n%x", buf.Bytes(), buf2.Bytes())
+	}
+
+	enc := must(json.Marshal(extra))
+	var extra3 []*zanobase.Variant
+	if err := json.Unmarshal(enc, &extra3); err != nil {
+		t.Fatalf("failed to unmarshal json: %s", err)
+	}
+	if !bytes.Equal(must(json.Marshal(extra3)), enc) {
+		t.Errorf("extra did not round trip through json")
+	}
+
+	// unknown tags must fail without panicking
+	var v zanobase.Variant
+	if err := zanobase.Deserialize(bytes.NewReader([]byte{0xfe, 0}), &v); err == nil {
+		t.Errorf("unknown tag should fail to deserialize")
+	}
+	if zanobase.Tag(0xfe).New() != nil || zanobase.Tag(0xfe).Type() != nil {
+		t.Errorf("unknown tag should have no type")
+	}
+
+	// raw variants keep their tag and data through json
+	raw := &zanobase.Variant{Tag: 0xf0, Value: &zanobase.RawVariant{Data: []byte{1, 2, 3}}}
+	var raw2 zanobase.Variant
+	if err := json.Unmarshal(must(json.Marshal(raw)), &raw2); err != nil {
+		t.Fatalf("failed to unmarshal raw variant: %s", err)
+	}
+	if raw2.Tag != 0xf0 || !bytes.Equal(raw2.Value.(*zanobase.RawVariant).Data, []byte{1, 2, 3}) {
+		t.Errorf("raw variant did not round trip through json: %+v", raw2)
+	}
+}
+
+func TestAssetDescriptorOperation(t *testing.T) {
+	// register (version 1) and emit (version 2) operations, laid out as in currency_basic.h
+	fixture := "02" + // extra size
+		"31" + "01" + "01" + // tag, version, ASSET_DESCRIPTOR_OPERATION_REGISTER
+		"00" + "40420f0000000000" + "0000000000000000" + "0c" + // descriptor version, max supply, supply, decimal point
+		"045a545354" + "095a616e6f2054657374" + "00" + // ticker, full name, meta info
+		strings.Repeat("aa", 32) + "00" + // owner, hidden supply
+		strings.Repeat("bb", 32) + "01" + // amount commitment, no asset id
+		"31" + "02" + "02" + // tag, version, ASSET_DESCRIPTOR_OPERATION_EMIT
+		"00" + strings.Repeat("cc", 32) + "00" + strings.Repeat("dd", 32) + // amount commitment, asset id
+		"01" + "00" + "f401000000000000" + "01" + "00" // no descriptor, amount, no salt, etc
+
+	data := must(hex.DecodeString(fixture))
+	var extra []*zanobase.Variant
+	if err := zanobase.Deserialize(bytes.NewReader(data), &extra); err != nil {
+		t.Fatalf("failed to deserialize: %s", err)
+	}
+	reg := extra[0].Value.(*zanobase.AssetDescriptorOperation)
+	if reg.OperationType != zanobase.AssetDescriptorOperationRegister || reg.Descriptor.Ticker != "ZTST" || reg.Descriptor.TotalMaxSupply != 1000000 || reg.Descriptor.DecimalPoint != 12 || reg.AmountCommitment[0] != 0xbb || reg.AssetId != nil {
+		t.Errorf("invalid register operation: %+v", reg)
+	}
+	emit := extra[1].Value.(*zanobase.AssetDescriptorOperation)
+	if emit.OperationType != zanobase.AssetDescriptorOperationEmit || emit.AssetId[0] != 0xdd || emit.Descriptor != nil || *emit.Amount != 500 || emit.AssetIdSalt != nil {
+		t.Errorf("invalid emit operation: %+v", emit)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := zanobase.Serialize(buf, extra); err != nil {
+		t.Fatalf("failed to serialize: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("asset operations did not round trip:\n%x\n%x", buf.Bytes(), data)
+	}
+	var extra2 []*zanobase.Variant
+	if err := json.Unmarshal(must(json.Marshal(extra)), &extra2); err != nil {
+		t.Fatalf("failed to unmarshal json: %s", err)
+	}
+	buf.Reset()
+	if err := zanobase.Serialize(buf, extra2); err != nil || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("asset operations did not round trip through json: %v", err)
+	}
+}
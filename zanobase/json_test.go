@@ -0,0 +1,111 @@
+package zanobase_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+func testPoint(v uint64) *zanobase.Point {
+	var buf [32]byte
+	buf[0] = byte(v)
+	buf[1] = byte(v >> 8)
+	s, _ := new(edwards25519.Scalar).SetCanonicalBytes(buf[:])
+	return &zanobase.Point{new(edwards25519.Point).ScalarBaseMult(s)}
+}
+
+func testScalar(v uint64) *zanobase.Scalar {
+	var buf [32]byte
+	buf[0] = byte(v)
+	buf[1] = byte(v >> 8)
+	s, _ := new(edwards25519.Scalar).SetCanonicalBytes(buf[:])
+	return &zanobase.Scalar{s}
+}
+
+func testTransaction() *zanobase.Transaction {
+	var pub zanobase.Value256
+	copy(pub[:], testPoint(1).Bytes())
+
+	out := &zanobase.TxOutZarcanium{EncryptedAmount: 0x1234567890, MixAttr: 1}
+	copy(out.StealthAddress[:], testPoint(2).Bytes())
+	copy(out.ConcealingPoint[:], testPoint(3).Bytes())
+	copy(out.AmountCommitment[:], testPoint(4).Bytes())
+	copy(out.BlindedAssetId[:], testPoint(5).Bytes())
+
+	return &zanobase.Transaction{
+		Version: 2,
+		Vin: []*zanobase.Variant{
+			zanobase.VariantFor(&zanobase.TxInZcInput{
+				KeyOffsets: []*zanobase.Variant{zanobase.VariantFor(uint64(1337)), zanobase.VariantFor(uint64(42))},
+				KeyImage:   testPoint(6),
+			}),
+		},
+		Extra: []*zanobase.Variant{
+			{Tag: zanobase.TagPubKey, Value: pub},
+			{Tag: zanobase.TagEtcTxFlags16, Value: uint16(0)},
+			{Tag: zanobase.TagDerivationHint, Value: []byte{0x12, 0x34}},
+			{Tag: zanobase.TagZarcaniumTxDataV1, Value: &zanobase.ZarcaniumTxDataV1{Fee: 10000000000}},
+		},
+		Vout: []*zanobase.Variant{zanobase.VariantFor(out)},
+		Signatures: []*zanobase.Variant{
+			{Tag: zanobase.TagZCSig, Value: &zanobase.ZCSig{
+				PseudoOutAmountCommitment: testPoint(7),
+				PseudoOutBlindedAssetId:   testPoint(8),
+				GGX: &zanobase.CLSAG_Sig{
+					C:  testScalar(9),
+					Rg: []*zanobase.Scalar{testScalar(10), testScalar(11)},
+					Rx: []*zanobase.Scalar{testScalar(12), testScalar(13)},
+					K1: testPoint(14),
+					K2: testPoint(15),
+				},
+			}},
+		},
+		Proofs: []*zanobase.Variant{
+			{Tag: zanobase.TagZcBalanceProof, Value: &zanobase.ZCBalanceProof{
+				DSS: &zanobase.GenericDoubleSchnorrSig{C: testScalar(16), Y0: testScalar(17), Y1: testScalar(18)},
+			}},
+		},
+	}
+}
+
+func TestTransactionJSON(t *testing.T) {
+	tx := testTransaction()
+
+	enc, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("failed to marshal: %s", err)
+	}
+	tx2 := new(zanobase.Transaction)
+	err = json.Unmarshal(enc, tx2)
+	if err != nil {
+		t.Fatalf("failed to unmarshal: %s", err)
+	}
+	enc2, err := json.Marshal(tx2)
+	if err != nil {
+		t.Fatalf("failed to marshal decoded transaction: %s", err)
+	}
+	if !bytes.Equal(enc, enc2) {
+		t.Errorf("json did not round trip:\n%s\n%s", enc, enc2)
+	}
+
+	// binary serialization must also be identical
+	bin1 := &bytes.Buffer{}
+	bin2 := &bytes.Buffer{}
+	if err := zanobase.Serialize(bin1, tx); err != nil {
+		t.Fatalf("failed to serialize: %s", err)
+	}
+	if err := zanobase.Serialize(bin2, tx2); err != nil {
+		t.Fatalf("failed to serialize decoded transaction: %s", err)
+	}
+	if !bytes.Equal(bin1.Bytes(), bin2.Bytes()) {
+		t.Errorf("binary serialization differs after json round trip")
+	}
+
+	var v zanobase.Variant
+	if err := json.Unmarshal([]byte(`{"type":"nonexistent","value":1}`), &v); err == nil {
+		t.Errorf("unknown variant type should fail to decode")
+	}
+}
@@ -3,6 +3,7 @@ package zanobase
 import (
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
 
 	"filippo.io/edwards25519"
@@ -19,6 +20,19 @@ func (p *Point) MarshalJSON() ([]byte, error) {
 	return json.Marshal(hex.EncodeToString(p.Point.Bytes()))
 }
 
+func (p *Point) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		p.Point = nil
+		return nil
+	}
+	buf, err := unmarshalHex32(data)
+	if err != nil {
+		return fmt.Errorf("invalid point: %w", err)
+	}
+	p.Point, err = new(edwards25519.Point).SetBytes(buf)
+	return err
+}
+
 func (p *Point) WriteTo(w io.Writer) (int64, error) {
 	n, err := w.Write(p.Point.Bytes())
 	return int64(n), err
@@ -0,0 +1,196 @@
+package zanobase
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// tagKeepUnknown can be set on []*Variant fields (`epee:"keepunknown"`) to allow unknown
+// entries to be kept as RawVariant when decoding with DeserializeKeepUnknown.
+const tagKeepUnknown = "keepunknown"
+
+// maxRawVariantAttempts limits the number of decoding attempts made by
+// DeserializeKeepUnknown while guessing the length of unknown variants.
+const maxRawVariantAttempts = 256
+
+// rawVariantShapes are the serialized shapes an unknown variant may have. Each returns the
+// length of the value at the start of buf, or false if buf cannot hold such a value.
+var rawVariantShapes = []func(buf []byte) (int, bool){
+	rawVarintLength,
+	rawStringLength,
+	rawFixedLength(1),
+	rawFixedLength(2),
+	rawFixedLength(4),
+	rawFixedLength(8),
+	rawFixedLength(32),
+}
+
+// rawVarintLength is the length of a varint
+func rawVarintLength(buf []byte) (int, bool) {
+	for n, b := range buf {
+		if n >= 10 {
+			break
+		}
+		if b&0x80 == 0 {
+			return n + 1, true
+		}
+	}
+	return 0, false
+}
+
+// rawStringLength is the length of a varint prefixed string
+func rawStringLength(buf []byte) (int, bool) {
+	ln, err := VarintReadUint64(bytes.NewReader(buf))
+	if err != nil {
+		return 0, false
+	}
+	n, _ := rawVarintLength(buf)
+	if ln > uint64(len(buf)-n) {
+		return 0, false
+	}
+	return n + int(ln), true
+}
+
+// rawFixedLength returns a shape of ln bytes
+func rawFixedLength(ln int) func([]byte) (int, bool) {
+	return func(buf []byte) (int, bool) {
+		return ln, ln <= len(buf)
+	}
+}
+
+var (
+	errRawVariantLength = errors.New("unknown variant length")
+	errRawVariantGiveUp = errors.New("too many attempts while guessing unknown variants length")
+
+	// ErrAmbiguousRawVariant is returned by DeserializeKeepUnknown when an unknown entry can
+	// be split in more than one way
+	ErrAmbiguousRawVariant = errors.New("ambiguous unknown variant length")
+)
+
+// RawVariant is the value of a Variant whose tag is not known by this library. It holds
+// the serialized value as is, and is written back untouched when serialized.
+type RawVariant struct {
+	Data []byte
+}
+
+func (r *RawVariant) Bytes() []byte {
+	return r.Data
+}
+
+func (r *RawVariant) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(r.Data))
+}
+
+func (r *RawVariant) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	r.Data, err = hex.DecodeString(s)
+	return err
+}
+
+func (p *Variant) isRaw() bool {
+	_, ok := p.Value.(*RawVariant)
+	return ok
+}
+
+// rawVariantReader is used by DeserializeKeepUnknown to give the length of unknown
+// variants, in the order they appear in the stream.
+type rawVariantReader struct {
+	*bytes.Reader
+	lengths []int
+	used    int
+	pos     int // position of the unknown variant which length is missing
+}
+
+func (r *rawVariantReader) readRaw(v *Variant, tag Tag) error {
+	if r.used >= len(r.lengths) {
+		r.pos = int(r.Size()) - r.Len()
+		return errRawVariantLength
+	}
+	buf := make([]byte, r.lengths[r.used])
+	r.used += 1
+	_, err := io.ReadFull(r, buf)
+	if err != nil {
+		return notEOF(err)
+	}
+	v.Tag = tag
+	v.Value = &RawVariant{Data: buf}
+	return nil
+}
+
+// DeserializeKeepUnknown decodes buf into target like Deserialize, except that entries with
+// an unknown tag found in fields tagged `epee:"keepunknown"` are kept as RawVariant values
+// instead of causing an error. buf must contain exactly one object.
+//
+// As the serialization format does not include lengths, the length of an unknown entry is
+// guessed from a few common shapes: a varint, a varint prefixed string, or 1, 2, 4, 8 or
+// 32 bytes. It must be the only length for which the whole buffer decodes without trailing
+// data: if several lengths are valid, decoding fails rather than picking one. Unknown
+// entries of any other shape cannot be recovered. Only one unknown entry per container
+// can be recovered this way, and fields decoded by a custom ReadFrom (such as Transaction)
+// do not support it.
+func DeserializeKeepUnknown(buf []byte, target any) error {
+	t := reflect.TypeOf(target)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return errors.New("target must be a pointer")
+	}
+	var attempts int
+	obj, err := deserializeRaw(buf, t.Elem(), nil, &attempts)
+	if err != nil {
+		return err
+	}
+	reflect.ValueOf(target).Elem().Set(obj.Elem())
+	return nil
+}
+
+func deserializeRaw(buf []byte, t reflect.Type, lengths []int, attempts *int) (reflect.Value, error) {
+	if *attempts += 1; *attempts > maxRawVariantAttempts {
+		return reflect.Value{}, errRawVariantGiveUp
+	}
+	obj := reflect.New(t)
+	r := &rawVariantReader{Reader: bytes.NewReader(buf), lengths: lengths}
+	err := Deserialize(r, obj.Interface())
+	if err == nil {
+		if r.Len() != 0 {
+			return reflect.Value{}, errors.New("trailing data")
+		}
+		return obj, nil
+	}
+	if !errors.Is(err, errRawVariantLength) {
+		return reflect.Value{}, err
+	}
+
+	// keep searching after the first match, a second one means the split is ambiguous
+	var found reflect.Value
+	tried := make(map[int]bool)
+	for _, shape := range rawVariantShapes {
+		ln, ok := shape(buf[r.pos:])
+		if !ok || tried[ln] {
+			continue
+		}
+		tried[ln] = true
+		res, err := deserializeRaw(buf, t, append(lengths[:len(lengths):len(lengths)], ln), attempts)
+		if err != nil {
+			if errors.Is(err, errRawVariantGiveUp) || errors.Is(err, ErrAmbiguousRawVariant) {
+				return reflect.Value{}, err
+			}
+			continue
+		}
+		if found.IsValid() {
+			return reflect.Value{}, fmt.Errorf("%w at offset %d", ErrAmbiguousRawVariant, r.pos)
+		}
+		found = res
+	}
+	if found.IsValid() {
+		return found, nil
+	}
+	return reflect.Value{}, fmt.Errorf("could not find the length of unknown variant at offset %d", r.pos)
+}
@@ -0,0 +1,44 @@
+package zanobase_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+type rawTestObj struct {
+	Extra []*zanobase.Variant `epee:"keepunknown"`
+	Name  string
+}
+
+func TestDeserializeKeepUnknown(t *testing.T) {
+	// unknown tag 0xf0 holding aa bb, followed by a tx_comment, then Name = "hello"
+	data := []byte{0x02, 0xf0, 0xaa, 0xbb, 0x07, 0x01, 'x', 0x05, 'h', 'e', 'l', 'l', 'o'}
+	var obj rawTestObj
+	if err := zanobase.DeserializeKeepUnknown(data, &obj); err != nil {
+		t.Fatalf("failed to deserialize: %s", err)
+	}
+	raw, ok := obj.Extra[0].Value.(*zanobase.RawVariant)
+	if !ok || obj.Extra[0].Tag != 0xf0 || !bytes.Equal(raw.Data, []byte{0xaa, 0xbb}) || obj.Name != "hello" {
+		t.Fatalf("unexpected result: %+v", obj)
+	}
+	buf := &bytes.Buffer{}
+	if err := zanobase.Serialize(buf, &obj); err != nil || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("did not re-serialize identically: %x", buf.Bytes())
+	}
+
+	// 01 01 00 decodes both as a varint unknown entry followed by Name = "\x00", and as a
+	// string unknown entry followed by Name = ""
+	ambiguous := []byte{0x01, 0xf0, 0x01, 0x01, 0x00}
+	if err := zanobase.DeserializeKeepUnknown(ambiguous, &obj); !errors.Is(err, zanobase.ErrAmbiguousRawVariant) {
+		t.Errorf("ambiguous blob should fail, got %v", err)
+	}
+
+	// a 3 bytes unknown entry matches none of the known shapes
+	unknownShape := []byte{0x02, 0xf0, 0xaa, 0xbb, 0xcc, 0x07, 0x01, 'x', 0x05, 'h', 'e', 'l', 'l', 'o'}
+	if err := zanobase.DeserializeKeepUnknown(unknownShape, &obj); err == nil {
+		t.Errorf("unknown entry of unknown shape should fail")
+	}
+}
@@ -3,6 +3,7 @@ package zanobase
 import (
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
 
 	"filippo.io/edwards25519"
@@ -19,6 +20,19 @@ func (s *Scalar) MarshalJSON() ([]byte, error) {
 	return json.Marshal(hex.EncodeToString(s.Scalar.Bytes()))
 }
 
+func (s *Scalar) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		s.Scalar = nil
+		return nil
+	}
+	buf, err := unmarshalHex32(data)
+	if err != nil {
+		return fmt.Errorf("invalid scalar: %w", err)
+	}
+	s.Scalar, err = new(edwards25519.Scalar).SetCanonicalBytes(buf)
+	return err
+}
+
 func (s *Scalar) WriteTo(w io.Writer) (int64, error) {
 	n, err := w.Write(s.Scalar.Bytes())
 	return int64(n), err
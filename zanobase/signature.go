@@ -21,3 +21,30 @@ type CLSAG_GGX_Input struct {
 	StealthAddress   Value256
 	AmountCommitment Value256
 }
+
+type ZarcanumSig struct {
+	// zarcanum_sig, see crypto::zarcanum_proof
+	D                         *Scalar          `json:"d"`       // 0 < d <= floor(l / (z * D))
+	C                         *Point           `json:"C"`       // premultiplied by 1/8
+	CPrime                    *Point           `json:"C_prime"` // premultiplied by 1/8
+	E                         *Point           `json:"E"`       // premultiplied by 1/8
+	Challenge                 *Scalar          `json:"c"`       // shared Fiat-Shamir challenge for the linear relation proofs
+	Y0                        *Scalar          `json:"y0"`
+	Y1                        *Scalar          `json:"y1"`
+	Y2                        *Scalar          `json:"y2"`
+	Y3                        *Scalar          `json:"y3"`
+	Y4                        *Scalar          `json:"y4"`
+	ERangeProof               *BPPESignature   `json:"E_range_proof"`
+	PseudoOutAmountCommitment *Point           `json:"pseudo_out_amount_commitment"` // premultiplied by 1/8
+	GGXXG                     *CLSAG_GGXXG_Sig `json:"clsag_ggxxg"`
+}
+
+type CLSAG_GGXXG_Sig struct {
+	C  *Scalar   // scalar_t
+	Rg []*Scalar // for G-components (layers 0, 1, 4), size = size of the ring
+	Rx []*Scalar // for X-components (layers 2, 3),    size = size of the ring
+	K1 *Point    // public_key auxiliary key image for layer 1 (G)
+	K2 *Point    // public_key auxiliary key image for layer 2 (X)
+	K3 *Point    // public_key auxiliary key image for layer 3 (X)
+	K4 *Point    // public_key auxiliary key image for layer 4 (G)
+}
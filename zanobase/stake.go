@@ -0,0 +1,38 @@
+package zanobase
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// StakeModifier is the part of the stake kernel that depends on the chain: the kernel id
+// of the last PoS block and the id of the last PoW block
+type StakeModifier struct {
+	LastPosKernelId Value256 `json:"last_pos_kernel_id"`
+	LastPowId       Value256 `json:"last_pow_id"`
+}
+
+// StakeKernel is hashed to check whether a staked output can produce a PoS block at a
+// given timestamp, see stake_kernel
+type StakeKernel struct {
+	StakeModifier  StakeModifier `json:"stake_modifier"`
+	BlockTimestamp uint64        `json:"block_timestamp"`
+	KeyImage       Value256      `json:"kimage"`
+}
+
+// Bytes returns the kernel in its in-memory layout, as it is hashed
+func (k *StakeKernel) Bytes() []byte {
+	buf := make([]byte, 0, 104)
+	buf = append(buf, k.StakeModifier.LastPosKernelId[:]...)
+	buf = append(buf, k.StakeModifier.LastPowId[:]...)
+	buf = binary.LittleEndian.AppendUint64(buf, k.BlockTimestamp)
+	return append(buf, k.KeyImage[:]...)
+}
+
+// Hash returns the kernel hash
+func (k *StakeKernel) Hash() []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(k.Bytes())
+	return h.Sum(nil)
+}
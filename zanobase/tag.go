@@ -17,20 +17,41 @@ var (
 
 const (
 	TagGen                    Tag = 0
+	TagTxComment              Tag = 7
+	TagTxPayerOld             Tag = 8
+	TagString                 Tag = 9
+	TagTxCryptoChecksum       Tag = 10
 	TagDerivationHint         Tag = 11
+	TagTxServiceAttachment    Tag = 12
+	TagUnlockTime             Tag = 14
+	TagExpirationTime         Tag = 15
+	TagTxDetailsFlags         Tag = 16
+	TagSignedParts            Tag = 17
+	TagExtraAttachmentInfo    Tag = 18
+	TagExtraUserData          Tag = 19
+	TagExtraAliasEntryOld     Tag = 20
+	TagExtraPadding           Tag = 21
 	TagPubKey                 Tag = 22
 	TagEtcTxFlags16           Tag = 23
 	TagDeriveXor              Tag = 24
 	TagRefById                Tag = 25
 	TagUint64                 Tag = 26
+	TagEtcTxTime              Tag = 27
 	TagUint32                 Tag = 28
+	TagTxReceiverOld          Tag = 29
+	TagUnlockTime2            Tag = 30
+	TagTxPayer                Tag = 31
+	TagTxReceiver             Tag = 32
+	TagExtraAliasEntry        Tag = 33
 	TagTxinZcInput            Tag = 37
 	TagTxOutZarcanum          Tag = 38
 	TagZarcaniumTxDataV1      Tag = 39
 	TagZCSig                  Tag = 43
+	TagZarcanumSig            Tag = 45
 	TagZcAssetSurjectionProof Tag = 46
 	TagZcOutsRangeProof       Tag = 47
 	TagZcBalanceProof         Tag = 48
+	TagAssetDescriptorOp      Tag = 49
 )
 
 func defTag[T any](tag Tag, name string) {
@@ -46,20 +67,41 @@ func defTag[T any](tag Tag, name string) {
 
 func init() {
 	defTag[*TxInGen](TagGen, "gen")
+	defTag[*TxComment](TagTxComment, "tx_comment")
+	defTag[*TxPayerOld](TagTxPayerOld, "tx_payer_old")
+	defTag[string](TagString, "string")
+	defTag[*TxCryptoChecksum](TagTxCryptoChecksum, "tx_crypto_checksum")
 	defTag[[]byte](TagDerivationHint, "derivation_hint")
+	defTag[*TxServiceAttachment](TagTxServiceAttachment, "tx_service_attachment")
+	defTag[*EtcTxDetailsUnlockTime](TagUnlockTime, "etc_tx_details_unlock_time")
+	defTag[*EtcTxDetailsExpirationTime](TagExpirationTime, "etc_tx_details_expiration_time")
+	defTag[*EtcTxDetailsFlags](TagTxDetailsFlags, "etc_tx_details_flags")
+	defTag[*SignedParts](TagSignedParts, "signed_parts")
+	defTag[*ExtraAttachmentInfo](TagExtraAttachmentInfo, "extra_attachment_info")
+	defTag[*ExtraUserData](TagExtraUserData, "extra_user_data")
+	defTag[*ExtraAliasEntryOld](TagExtraAliasEntryOld, "extra_alias_entry_old")
+	defTag[*ExtraPadding](TagExtraPadding, "extra_padding")
 	defTag[Value256](TagPubKey, "pub_key")
 	defTag[uint16](TagEtcTxFlags16, "etc_tx_flags16")
 	defTag[uint16](TagDeriveXor, "derive_xor")
 	defTag[*RefById](TagRefById, "ref_by_id")
 	defTag[uint64](TagUint64, "uint64_t")
+	defTag[*EtcTxTime](TagEtcTxTime, "etc_tx_time")
 	defTag[uint32](TagUint32, "uint32_t")
+	defTag[*TxReceiverOld](TagTxReceiverOld, "tx_receiver_old")
+	defTag[*EtcTxDetailsUnlockTime2](TagUnlockTime2, "etc_tx_details_unlock_time2")
+	defTag[*TxPayer](TagTxPayer, "tx_payer")
+	defTag[*TxReceiver](TagTxReceiver, "tx_receiver")
+	defTag[*ExtraAliasEntry](TagExtraAliasEntry, "extra_alias_entry")
 	defTag[*TxInZcInput](TagTxinZcInput, "txin_zc_input")
 	defTag[*TxOutZarcanium](TagTxOutZarcanum, "tx_out_zarcanum")
 	defTag[*ZarcaniumTxDataV1](TagZarcaniumTxDataV1, "zarcanum_tx_data_v1")
 	defTag[*ZCSig](TagZCSig, "ZC_sig")
+	defTag[*ZarcanumSig](TagZarcanumSig, "zarcanum_sig")
 	defTag[*ZCAssetSurjectionProof](TagZcAssetSurjectionProof, "zc_asset_surjection_proof")
 	defTag[*ZCOutsRangeProof](TagZcOutsRangeProof, "zc_outs_range_proof")
 	defTag[*ZCBalanceProof](TagZcBalanceProof, "zc_balance_proof")
+	defTag[*AssetDescriptorOperation](TagAssetDescriptorOp, "asset_descriptor_operation")
 }
 
 func TagFor[T any]() Tag {
@@ -70,18 +112,20 @@ func TagFor[T any]() Tag {
 	return Tag(0xff)
 }
 
+// New returns a zero value of the type registered for t, or nil if t is not registered
 func (t Tag) New() any {
 	def, ok := variantTags[t]
 	if !ok {
-		panic("invalid tag")
+		return nil
 	}
 	return reflect.New(def.typ).Elem().Interface()
 }
 
+// Type returns the type registered for t, or nil if t is not registered
 func (t Tag) Type() reflect.Type {
 	def, ok := variantTags[t]
 	if !ok {
-		panic("invalid tag")
+		return nil
 	}
 	return def.typ
 }
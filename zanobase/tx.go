@@ -1,25 +1,43 @@
 package zanobase
 
-import "golang.org/x/crypto/sha3"
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/KarpelesLab/rc"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	TransactionVersionPostHF4 = 2
+	TransactionVersionPostHF5 = 3 // adds hardfork_id to the transaction prefix
+)
 
 type TransactionPrefix struct {
-	Version Varint     `json:"version"` // varint, ==2
-	Vin     []*Variant `json:"vin"`     // txin_v = boost::variant<txin_gen[0], txin_to_key[1], txin_multisig[2], txin_htlc[34], txin_zc_input[37]>
-	Extra   []*Variant `json:"extra"`   // extra_v
-	Vout    []*Variant `json:"vout"`    // tx_out_v = boost::variant<tx_out_bare[36], tx_out_zarcanum[38]>
+	Version    Varint     `json:"version"`               // varint, ==2
+	Vin        []*Variant `json:"vin"`                   // txin_v = boost::variant<txin_gen[0], txin_to_key[1], txin_multisig[2], txin_htlc[34], txin_zc_input[37]>
+	Extra      []*Variant `json:"extra"`                 // extra_v
+	Vout       []*Variant `json:"vout"`                  // tx_out_v = boost::variant<tx_out_bare[36], tx_out_zarcanum[38]>
+	HardforkId uint8      `json:"hardfork_id,omitempty"` // uint8_t, only if version >= 3
 }
 
 type Transaction struct {
-	Version Varint     `json:"version"` // varint, ==2
-	Vin     []*Variant `json:"vin"`     // txin_v = boost::variant<txin_gen[0], txin_to_key[1], txin_multisig[2], txin_htlc[34], txin_zc_input[37]>
-	Extra   []*Variant `json:"extra"`   // extra_v
-	Vout    []*Variant `json:"vout"`    // tx_out_v = boost::variant<tx_out_bare[36], tx_out_zarcanum[38]>
+	Version    Varint     `json:"version"`               // varint, ==2
+	Vin        []*Variant `json:"vin"`                   // txin_v = boost::variant<txin_gen[0], txin_to_key[1], txin_multisig[2], txin_htlc[34], txin_zc_input[37]>
+	Extra      []*Variant `json:"extra"`                 // extra_v
+	Vout       []*Variant `json:"vout"`                  // tx_out_v = boost::variant<tx_out_bare[36], tx_out_zarcanum[38]>
+	HardforkId uint8      `json:"hardfork_id,omitempty"` // uint8_t, only if version >= 3
 	// up to here this was transaction_prefix
 	Attachment []*Variant `json:"attachment,omitempty"`
 	Signatures []*Variant `json:"signatures"` // signature_v = boost::variant<NLSAG_sig, void_sig, ZC_sig, zarcanum_sig>
 	Proofs     []*Variant `json:"proofs"`     // proof_v
 }
 
+// TransactionV3 was a draft of a version 3 transaction.
+//
+// Deprecated: Transaction handles version 3 transactions using its HardforkId field.
 type TransactionV3 struct {
 	Version Varint     `json:"version"` // varint, ==2
 	Vin     []*Variant `json:"vin"`     // txin_v = boost::variant<txin_gen[0], txin_to_key[1], txin_multisig[2], txin_htlc[34], txin_zc_input[37]>
@@ -32,8 +50,42 @@ type TransactionV3 struct {
 	HardforkId uint8      `json:"hardfork_id"` // uint8_t
 }
 
+// ErrPreHF4Transaction is returned when decoding a transaction older than HF4 (version 0
+// or 1). Those use the transaction_v1 layout with bare inputs and outputs and NLSAG
+// signatures, which is not implemented.
+var ErrPreHF4Transaction = errors.New("pre-HF4 transactions are not supported")
+
+// ParseTransaction decodes a raw transaction blob, as returned by the daemon, and checks
+// that no data follows the transaction. Only transactions from HF4 onward (versions 2 and
+// 3) can be decoded, older chain transactions fail with ErrPreHF4Transaction.
+func ParseTransaction(buf []byte) (*Transaction, error) {
+	r := bytes.NewReader(buf)
+	res := new(Transaction)
+	err := Deserialize(r, res)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("trailing data")
+	}
+	return res, nil
+}
+
+// Bytes returns the transaction serialized as a raw blob
+func (tx *Transaction) Bytes() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	_, err := tx.WriteTo(buf)
+	return buf.Bytes(), err
+}
+
 func (tx *Transaction) Prefix() *TransactionPrefix {
-	return &TransactionPrefix{tx.Version, tx.Vin, tx.Extra, tx.Vout}
+	return &TransactionPrefix{tx.Version, tx.Vin, tx.Extra, tx.Vout, tx.HardforkId}
+}
+
+// Hash returns the transaction's hash (also known as tx id), which for Zano is the hash of
+// the transaction prefix.
+func (tx *Transaction) Hash() ([]byte, error) {
+	return tx.Prefix().Hash()
 }
 
 // Hash of a transaction prefix. Can fail if the variants contains invalid data
@@ -43,6 +95,80 @@ func (txp *TransactionPrefix) Hash() ([]byte, error) {
 	return h.Sum(nil), err
 }
 
+func (txp *TransactionPrefix) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	err := txp.serialize(cw)
+	return cw.n, err
+}
+
+func (txp *TransactionPrefix) serialize(w io.Writer) error {
+	if err := checkTxVersion(uint64(txp.Version)); err != nil {
+		return err
+	}
+	for _, v := range []any{txp.Version, txp.Vin, txp.Extra, txp.Vout} {
+		if err := Serialize(w, v); err != nil {
+			return err
+		}
+	}
+	if txp.Version >= TransactionVersionPostHF5 {
+		_, err := w.Write([]byte{txp.HardforkId})
+		return err
+	}
+	return nil
+}
+
+func (tx *Transaction) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	err := tx.Prefix().serialize(cw)
+	if err != nil {
+		return cw.n, err
+	}
+	for _, v := range [][]*Variant{tx.Attachment, tx.Signatures, tx.Proofs} {
+		if err := Serialize(cw, v); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+func (tx *Transaction) ReadFrom(r io.Reader) (int64, error) {
+	rc := rc.New(r)
+	err := Deserialize(rc, &tx.Version)
+	if err != nil {
+		return rc.Error64(err)
+	}
+	if err := checkTxVersion(uint64(tx.Version)); err != nil {
+		return rc.Error64(err)
+	}
+	for _, v := range []*[]*Variant{&tx.Vin, &tx.Extra, &tx.Vout} {
+		if err := Deserialize(rc, v); err != nil {
+			return rc.Error64(err)
+		}
+	}
+	if tx.Version >= TransactionVersionPostHF5 {
+		tx.HardforkId, err = rc.ReadByte()
+		if err != nil {
+			return rc.Error64(notEOF(err))
+		}
+	}
+	for _, v := range []*[]*Variant{&tx.Attachment, &tx.Signatures, &tx.Proofs} {
+		if err := Deserialize(rc, v); err != nil {
+			return rc.Error64(err)
+		}
+	}
+	return rc.Ret64()
+}
+
+func checkTxVersion(v uint64) error {
+	if v < TransactionVersionPostHF4 {
+		return fmt.Errorf("%w (version %d)", ErrPreHF4Transaction, v)
+	}
+	if v > TransactionVersionPostHF5 {
+		return fmt.Errorf("unsupported transaction version %d", v)
+	}
+	return nil
+}
+
 func (tx *Transaction) GetFee() (uint64, bool) {
 	// simple get fee: tx.Extra should contain a ZarcaniumTxDataV1
 	for _, e := range tx.Extra {
@@ -52,3 +178,19 @@ func (tx *Transaction) GetFee() (uint64, bool) {
 	}
 	return 0, false
 }
+
+// IsCoinbase returns true if tx is a miner transaction, with a txin_gen first input
+func (tx *Transaction) IsCoinbase() bool {
+	return len(tx.Vin) > 0 && tx.Vin[0].Tag == TagGen
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,74 @@
+package zanobase_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+func TestTransactionBlob(t *testing.T) {
+	tx := testTransaction()
+	tx.Vin[0].Value.(*zanobase.TxInZcInput).KeyOffsets = append(tx.Vin[0].Value.(*zanobase.TxInZcInput).KeyOffsets, zanobase.VariantFor(&zanobase.RefById{N: 0x12345678}))
+	tx.Extra = append(tx.Extra, &zanobase.Variant{Tag: zanobase.TagUint32, Value: uint32(128)})
+	tx.Vin = append(tx.Vin, zanobase.VariantFor(&zanobase.TxInGen{Height: 300}))
+
+	for _, ver := range []zanobase.Varint{2, 3} {
+		tx.Version = ver
+		if ver == 3 {
+			tx.HardforkId = 5
+		}
+
+		buf, err := tx.Bytes()
+		if err != nil {
+			t.Fatalf("v%d: failed to serialize: %s", ver, err)
+		}
+		tx2, err := zanobase.ParseTransaction(buf)
+		if err != nil {
+			t.Fatalf("v%d: failed to parse: %s", ver, err)
+		}
+		if tx2.HardforkId != tx.HardforkId {
+			t.Errorf("v%d: hardfork id = %d, expected %d", ver, tx2.HardforkId, tx.HardforkId)
+		}
+		buf2, err := tx2.Bytes()
+		if err != nil {
+			t.Fatalf("v%d: failed to serialize parsed transaction: %s", ver, err)
+		}
+		if !bytes.Equal(buf, buf2) {
+			t.Errorf("v%d: blob did not round trip:\n%x\n%x", ver, buf, buf2)
+		}
+
+		h1 := must(tx.Hash())
+		h2 := must(tx2.Hash())
+		if !bytes.Equal(h1, h2) {
+			t.Errorf("v%d: hash differs after round trip", ver)
+		}
+
+		if _, err := zanobase.ParseTransaction(append(buf, 0)); err == nil {
+			t.Errorf("v%d: trailing data should have been rejected", ver)
+		}
+		if _, err := zanobase.ParseTransaction(buf[:len(buf)-1]); err == nil {
+			t.Errorf("v%d: truncated blob should have been rejected", ver)
+		}
+	}
+
+	tx.Version = 1
+	if _, err := tx.Bytes(); err == nil {
+		t.Errorf("version 1 should not be serialized")
+	}
+	if _, err := zanobase.ParseTransaction([]byte{4}); err == nil {
+		t.Errorf("version 4 should not be parsed")
+	}
+	// pre-HF4 chain transactions are not supported
+	if _, err := zanobase.ParseTransaction([]byte{1, 0}); !errors.Is(err, zanobase.ErrPreHF4Transaction) {
+		t.Errorf("version 1 should fail with ErrPreHF4Transaction, got %v", err)
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
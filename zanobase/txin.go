@@ -1,7 +1,7 @@
 package zanobase
 
 type TxInGen struct {
-	Height uint64
+	Height uint64 `json:"height" epee:"varint"`
 }
 
 type TxInZcInput struct {
@@ -3,6 +3,8 @@ package zanobase
 import (
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 
 	"filippo.io/edwards25519"
@@ -27,6 +29,15 @@ func (v Value256) MarshalJSON() ([]byte, error) {
 	return json.Marshal(v.String())
 }
 
+func (v *Value256) UnmarshalJSON(data []byte) error {
+	buf, err := unmarshalHex32(data)
+	if err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+	copy(v[:], buf)
+	return nil
+}
+
 func (v Value256) IsZero() bool {
 	var t byte
 	for _, b := range v {
@@ -50,3 +61,19 @@ func (v *Value256) ToPoint() *edwards25519.Point {
 	}
 	return p
 }
+
+// unmarshalHex32 decodes a JSON string containing 32 bytes encoded as hex
+func unmarshalHex32(data []byte) ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	buf, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) != 32 {
+		return nil, errors.New("value must be 32 bytes long")
+	}
+	return buf, nil
+}
@@ -3,6 +3,7 @@ package zanobase
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 )
 
 type Variant struct {
@@ -32,6 +33,40 @@ func (p *Variant) MarshalJSON() ([]byte, error) {
 	return json.Marshal(obj)
 }
 
+func (p *Variant) UnmarshalJSON(data []byte) error {
+	var obj struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	}
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return err
+	}
+	var raw uint8
+	if _, err := fmt.Sscanf(obj.Type, "unknown#%d", &raw); err == nil {
+		// value kept as RawVariant
+		val := new(RawVariant)
+		if err := json.Unmarshal(obj.Value, val); err != nil {
+			return fmt.Errorf("while decoding variant %s: %w", obj.Type, err)
+		}
+		p.Tag = Tag(raw)
+		p.Value = val
+		return nil
+	}
+	tag, ok := tagNameLookup[obj.Type]
+	if !ok {
+		return fmt.Errorf("unknown variant type %s", obj.Type)
+	}
+	val := reflect.New(tag.Type())
+	err = json.Unmarshal(obj.Value, val.Interface())
+	if err != nil {
+		return fmt.Errorf("while decoding variant %s: %w", obj.Type, err)
+	}
+	p.Tag = tag
+	p.Value = val.Elem().Interface()
+	return nil
+}
+
 func VariantFor[T any](obj T) *Variant {
 	return &Variant{Tag: TagFor[T](), Value: obj}
 }
@@ -45,7 +45,7 @@ func VarintPackedSize(v uint64) int {
 }
 
 func VarintAppendUint64(buf []byte, v uint64) []byte {
-	for v > 0x80 {
+	for v >= 0x80 {
 		buf = append(buf, byte(v&0x7f)|0x80)
 		v >>= 7
 	}
@@ -7,7 +7,7 @@ import (
 )
 
 func TestVarint(t *testing.T) {
-	vectors := []uint64{0, 42, 1337, 0x123456789, 0xabcdef123456789}
+	vectors := []uint64{0, 42, 127, 128, 1337, 0x3fff, 0x4000, 0x123456789, 0xabcdef123456789}
 
 	for _, vec := range vectors {
 		buf := zanobase.VarintAppendUint64(nil, vec)
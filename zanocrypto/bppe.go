@@ -0,0 +1,364 @@
+package zanocrypto
+
+import (
+	"errors"
+	"io"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+// double-blinded Bulletproofs+, commitments are in the form value * bpp_G + mask * bpp_H + mask2 * bpp_H2
+// src/crypto/range_proof_bppe.h
+
+// CalcPedersenCommitment2 returns value * bpp_G + mask * bpp_H + mask2 * bpp_H2
+func (t *Trait) CalcPedersenCommitment2(value, mask, mask2 *edwards25519.Scalar) *edwards25519.Point {
+	res := t.CalcPedersenCommitment(value, mask)
+	return res.Add(res, new(edwards25519.Point).ScalarMult(mask2, t.H2))
+}
+
+// bppD returns the vector d of the aggregated protocol (BP+ paper, page 17), stored
+// column by column: d[at(i, j)] = 2^j * z^(2*(i+1))
+func (t *Trait) bppD(z *edwards25519.Scalar, m int) []*edwards25519.Scalar {
+	z_sq := new(edwards25519.Scalar).Multiply(z, z)
+	d := make([]*edwards25519.Scalar, m*t.N)
+	d[0] = z_sq
+	for i := 1; i < m; i += 1 {
+		d[t.at(i, 0)] = new(edwards25519.Scalar).Multiply(d[t.at(i-1, 0)], z_sq)
+	}
+	for j := 1; j < t.N; j += 1 {
+		for i := 0; i < m; i += 1 {
+			v := d[t.at(i, j-1)]
+			d[t.at(i, j)] = new(edwards25519.Scalar).Add(v, v)
+		}
+	}
+	return d
+}
+
+// BPPEGen generates a double-blinded range proof for values, see bppe_gen. The
+// commitments are values[i] * G + masks[i] * H + masks2[i] * H2 with the generators of
+// the trait, commitments_1div8 are supposed to be already calculated.
+func (trait *Trait) BPPEGen(rnd io.Reader, values, masks, masks2 []*edwards25519.Scalar, commitments_1div8 []*edwards25519.Point) (*zanobase.BPPESignature, error) {
+	if len(values) == 0 || len(values) > trait.ValuesMax || len(masks) != len(values) || len(masks2) != len(values) || len(commitments_1div8) != len(values) {
+		return nil, errors.New("BPPEGen: invalid number of values")
+	}
+	c_bpp_log2_m := ceilLog2(len(values))
+	c_bpp_m := 1 << c_bpp_log2_m
+	c_bpp_mn := c_bpp_m * trait.N
+
+	// aL, aR as in BPPGen
+	aLs := make([]*edwards25519.Scalar, c_bpp_mn)
+	aRs := make([]*edwards25519.Scalar, c_bpp_mn)
+	for n := range aLs {
+		aLs[n] = new(edwards25519.Scalar)
+		aRs[n] = new(edwards25519.Scalar)
+	}
+	for i := range values {
+		v := values[i].Bytes()
+		for j := 0; j < trait.N; j += 1 {
+			if (v[j/8] & (1 << (j % 8))) != 0 {
+				aLs[trait.at(i, j)] = ScOne // aL = 1, aR = 0
+			} else {
+				aRs[trait.at(i, j)] = ScM1 // aL = 0, aR = -1
+			}
+		}
+		// values must fit in N bits
+		for j := trait.N / 8; j < 32; j++ {
+			if v[j] != 0 {
+				return nil, errors.New("BPPEGen: value out of range")
+			}
+		}
+	}
+	for i := len(values); i < c_bpp_m; i++ {
+		for j := 0; j < trait.N; j += 1 {
+			aRs[trait.at(i, j)] = ScM1
+		}
+	}
+
+	hsc := NewHashHelper()
+	e := TraitInitialTranscript()
+	e = TraitUpdateTranscript(hsc, e, commitments_1div8)
+
+	// A0 = alpha_1 * H + alpha_2 * H2 + SUM(aL_i * G_i) + SUM(aR_i * H_i)
+	alpha_1 := RandomScalar(rnd)
+	alpha_2 := RandomScalar(rnd)
+	A0 := trait.CalcPedersenCommitment2(ScZero, alpha_1, alpha_2)
+	for i := range aLs {
+		A0 = A0.Add(A0, new(edwards25519.Point).ScalarMult(aLs[i], TraitGetGenerator(false, i)))
+		A0 = A0.Add(A0, new(edwards25519.Point).ScalarMult(aRs[i], TraitGetGenerator(true, i)))
+	}
+	A0 = A0.ScalarMult(Sc1div8, A0)
+
+	// challenges y and z
+	hsc.Add(e)
+	hsc.Add(A0)
+	y := hsc.CalcHash()
+	z := HashToScalar(y.Bytes())
+	e = z
+
+	d := trait.bppD(z, c_bpp_m)
+
+	// y = (1, y, y^2, ..., y^(mn+1))
+	y_powers := make([]*edwards25519.Scalar, c_bpp_mn+2)
+	y_powers[0] = ScalarInt(1)
+	for i := 1; i <= c_bpp_mn+1; i += 1 {
+		y_powers[i] = new(edwards25519.Scalar).Multiply(y_powers[i-1], y)
+	}
+	y_mn_p1 := y_powers[c_bpp_mn+1]
+
+	// aL_hat = aL - 1*z, aR_hat = aR + d o y^leftarr + 1*z
+	aLs_hat := matSub(aLs, z)
+	aRs_hat := matAdd(aRs, z)
+	for i := range aRs_hat {
+		aRs_hat[i] = aRs_hat[i].Add(aRs_hat[i], new(edwards25519.Scalar).Multiply(d[i], y_powers[c_bpp_mn-i]))
+	}
+
+	// alpha_hat_k = alpha_k + y^(mn+1) * SUM(z^(2j) * gamma_k_j)
+	alpha_hat_1 := new(edwards25519.Scalar)
+	alpha_hat_2 := new(edwards25519.Scalar)
+	for i := range masks {
+		alpha_hat_1 = alpha_hat_1.Add(alpha_hat_1, new(edwards25519.Scalar).Multiply(d[trait.at(i, 0)], masks[i]))
+		alpha_hat_2 = alpha_hat_2.Add(alpha_hat_2, new(edwards25519.Scalar).Multiply(d[trait.at(i, 0)], masks2[i]))
+	}
+	alpha_hat_1 = new(edwards25519.Scalar).Add(alpha_1, new(edwards25519.Scalar).Multiply(y_mn_p1, alpha_hat_1))
+	alpha_hat_2 = new(edwards25519.Scalar).Add(alpha_2, new(edwards25519.Scalar).Multiply(y_mn_p1, alpha_hat_2))
+
+	y_inverse := new(edwards25519.Scalar).Invert(y)
+	y_inverse_powers := make([]*edwards25519.Scalar, c_bpp_mn/2+1)
+	y_inverse_powers[0] = ScalarInt(1)
+	for i := 1; i < len(y_inverse_powers); i += 1 {
+		y_inverse_powers[i] = new(edwards25519.Scalar).Multiply(y_inverse_powers[i-1], y_inverse)
+	}
+
+	g := make([]*edwards25519.Point, c_bpp_mn)
+	h := make([]*edwards25519.Point, c_bpp_mn)
+	for i := range g {
+		g[i] = TraitGetGenerator(false, i)
+		h[i] = TraitGetGenerator(true, i)
+	}
+
+	a := aLs_hat
+	b := aRs_hat
+
+	res := new(zanobase.BPPESignature)
+	res.A0 = &zanobase.Point{A0}
+
+	// zk-WIP reduction rounds
+	for n := c_bpp_mn / 2; n >= 1; n = n / 2 {
+		dL := RandomScalar(rnd)
+		dR := RandomScalar(rnd)
+		dL2 := RandomScalar(rnd)
+		dR2 := RandomScalar(rnd)
+
+		// cL = <a1, ((y, y^2, ...) o b2)>
+		cL := ScalarInt(0)
+		for i := 0; i < n; i += 1 {
+			cL = cL.Add(cL, new(edwards25519.Scalar).Multiply(new(edwards25519.Scalar).Multiply(a[i], y_powers[i+1]), b[n+i]))
+		}
+		// cR = <a2, ((y, y^2, ...) o b1)> * y^n
+		cR := ScalarInt(0)
+		for i := 0; i < n; i += 1 {
+			cR = cR.Add(cR, new(edwards25519.Scalar).Multiply(new(edwards25519.Scalar).Multiply(a[n+i], y_powers[i+1]), b[i]))
+		}
+		cR = cR.Multiply(cR, y_powers[n])
+
+		// L = y^-n * a1 * g2 + b2 * h1 + cL * G + dL * H + dL2 * H2
+		sum := new(edwards25519.Point).Set(C_point_0)
+		for i := 0; i < n; i += 1 {
+			sum = sum.Add(sum, new(edwards25519.Point).ScalarMult(a[i], g[n+i]))
+		}
+		L := trait.CalcPedersenCommitment2(cL, dL, dL2)
+		for i := 0; i < n; i += 1 {
+			L = L.Add(L, new(edwards25519.Point).ScalarMult(b[n+i], h[i]))
+		}
+		L = L.Add(L, new(edwards25519.Point).ScalarMult(y_inverse_powers[n], sum))
+		L = L.ScalarMult(Sc1div8, L)
+
+		// R = y^n * a2 * g1 + b1 * h2 + cR * G + dR * H + dR2 * H2
+		sum = sum.Set(C_point_0)
+		for i := 0; i < n; i += 1 {
+			sum = sum.Add(sum, new(edwards25519.Point).ScalarMult(a[n+i], g[i]))
+		}
+		R := trait.CalcPedersenCommitment2(cR, dR, dR2)
+		for i := 0; i < n; i += 1 {
+			R = R.Add(R, new(edwards25519.Point).ScalarMult(b[i], h[n+i]))
+		}
+		R = R.Add(R, new(edwards25519.Point).ScalarMult(y_powers[n], sum))
+		R = R.ScalarMult(Sc1div8, R)
+
+		res.Lv = append(res.Lv, &zanobase.Point{L})
+		res.Rv = append(res.Rv, &zanobase.Point{R})
+
+		hsc.Add(e)
+		hsc.Add(L, R)
+		e = hsc.CalcHash()
+
+		e_squared := new(edwards25519.Scalar).Multiply(e, e)
+		e_inverse := new(edwards25519.Scalar).Invert(e)
+		e_inverse_squared := new(edwards25519.Scalar).Multiply(e_inverse, e_inverse)
+		e_y_inv_n := new(edwards25519.Scalar).Multiply(e, y_inverse_powers[n])
+		e_inv_y_n := new(edwards25519.Scalar).Multiply(e_inverse, y_powers[n])
+
+		for i := 0; i < n; i += 1 {
+			// g_hat = e^-1 * g1 + (e * y^-n) * g2
+			g[i] = new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{e_inverse, e_y_inv_n}, []*edwards25519.Point{g[i], g[n+i]})
+			// h_hat = e * h1 + e^-1 * h2
+			h[i] = new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{e, e_inverse}, []*edwards25519.Point{h[i], h[n+i]})
+			// a_hat = e * a1 + e^-1 * y^n * a2
+			a[i] = new(edwards25519.Scalar).Add(new(edwards25519.Scalar).Multiply(e, a[i]), new(edwards25519.Scalar).Multiply(e_inv_y_n, a[n+i]))
+			// b_hat = e^-1 * b1 + e * b2
+			b[i] = new(edwards25519.Scalar).Add(new(edwards25519.Scalar).Multiply(e_inverse, b[i]), new(edwards25519.Scalar).Multiply(e, b[n+i]))
+		}
+
+		// alpha_hat_k += e^2 * dL_k + e^-2 * dR_k
+		alpha_hat_1 = alpha_hat_1.Add(alpha_hat_1, new(edwards25519.Scalar).Add(new(edwards25519.Scalar).Multiply(e_squared, dL), new(edwards25519.Scalar).Multiply(e_inverse_squared, dR)))
+		alpha_hat_2 = alpha_hat_2.Add(alpha_hat_2, new(edwards25519.Scalar).Add(new(edwards25519.Scalar).Multiply(e_squared, dL2), new(edwards25519.Scalar).Multiply(e_inverse_squared, dR2)))
+	}
+
+	// zk-WIP last round
+	r := RandomScalar(rnd)
+	s := RandomScalar(rnd)
+	delta_1 := RandomScalar(rnd)
+	delta_2 := RandomScalar(rnd)
+	eta_1 := RandomScalar(rnd)
+	eta_2 := RandomScalar(rnd)
+
+	// A = r * g + s * h + (r y b + s y a) * G + delta_1 * H + delta_2 * H2
+	tmp := new(edwards25519.Scalar).Add(new(edwards25519.Scalar).Multiply(r, b[0]), new(edwards25519.Scalar).Multiply(s, a[0]))
+	A := trait.CalcPedersenCommitment2(new(edwards25519.Scalar).Multiply(y, tmp), delta_1, delta_2)
+	A = A.Add(A, new(edwards25519.Point).Add(new(edwards25519.Point).ScalarMult(r, g[0]), new(edwards25519.Point).ScalarMult(s, h[0])))
+	A = A.ScalarMult(Sc1div8, A)
+	res.A = &zanobase.Point{A}
+
+	// B = (r * y * s) * G + eta_1 * H + eta_2 * H2
+	B := trait.CalcPedersenCommitment2(new(edwards25519.Scalar).Multiply(new(edwards25519.Scalar).Multiply(r, y), s), eta_1, eta_2)
+	B = B.ScalarMult(Sc1div8, B)
+	res.B = &zanobase.Point{B}
+
+	hsc.Add(e, A, B)
+	e = hsc.CalcHash()
+	e_squared := new(edwards25519.Scalar).Multiply(e, e)
+
+	res.R = &zanobase.Scalar{new(edwards25519.Scalar).Add(r, new(edwards25519.Scalar).Multiply(e, a[0]))}
+	res.S = &zanobase.Scalar{new(edwards25519.Scalar).Add(s, new(edwards25519.Scalar).Multiply(e, b[0]))}
+	// delta_k = eta_k + e * delta_k + e^2 * alpha_hat_k
+	res.Delta1 = &zanobase.Scalar{new(edwards25519.Scalar).Add(eta_1, new(edwards25519.Scalar).Add(new(edwards25519.Scalar).Multiply(e, delta_1), new(edwards25519.Scalar).Multiply(e_squared, alpha_hat_1)))}
+	res.Delta2 = &zanobase.Scalar{new(edwards25519.Scalar).Add(eta_2, new(edwards25519.Scalar).Add(new(edwards25519.Scalar).Multiply(e, delta_2), new(edwards25519.Scalar).Multiply(e_squared, alpha_hat_2)))}
+
+	return res, nil
+}
+
+// BPPEVerify checks a range proof generated by BPPEGen for the given commitments
+// (premultiplied by 1/8). Unlike bppe_verify it does not batch proofs.
+func (trait *Trait) BPPEVerify(sig *zanobase.BPPESignature, commitments_1div8 []*edwards25519.Point) error {
+	if len(commitments_1div8) == 0 || len(commitments_1div8) > trait.ValuesMax {
+		return errors.New("BPPEVerify: invalid number of commitments")
+	}
+	c_bpp_log2_m := ceilLog2(len(commitments_1div8))
+	c_bpp_m := 1 << c_bpp_log2_m
+	c_bpp_mn := c_bpp_m * trait.N
+	if len(sig.Lv) != c_bpp_log2_m+trait.Log2N || len(sig.Rv) != len(sig.Lv) {
+		return errors.New("BPPEVerify: invalid number of rounds")
+	}
+	for _, p := range append(append([]*zanobase.Point{sig.A0, sig.A, sig.B}, sig.Lv...), sig.Rv...) {
+		if p == nil || p.Point == nil {
+			return errors.New("BPPEVerify: missing point")
+		}
+	}
+	if sig.R == nil || sig.S == nil || sig.Delta1 == nil || sig.Delta2 == nil || sig.R.Scalar == nil || sig.S.Scalar == nil || sig.Delta1.Scalar == nil || sig.Delta2.Scalar == nil {
+		return errors.New("BPPEVerify: missing scalar")
+	}
+	mul8 := func(p *edwards25519.Point) *edwards25519.Point {
+		return new(edwards25519.Point).MultByCofactor(p)
+	}
+
+	// replay the transcript
+	hsc := NewHashHelper()
+	e := TraitInitialTranscript()
+	e = TraitUpdateTranscript(hsc, e, commitments_1div8)
+	hsc.Add(e)
+	hsc.Add(sig.A0.Point)
+	y := hsc.CalcHash()
+	z := HashToScalar(y.Bytes())
+	e = z
+	es := make([]*edwards25519.Scalar, len(sig.Lv))
+	for n := range sig.Lv {
+		hsc.Add(e)
+		hsc.Add(sig.Lv[n].Point, sig.Rv[n].Point)
+		e = hsc.CalcHash()
+		es[n] = e
+	}
+	hsc.Add(e, sig.A.Point, sig.B.Point)
+	eFinal := hsc.CalcHash()
+
+	d := trait.bppD(z, c_bpp_m)
+	y_powers := make([]*edwards25519.Scalar, c_bpp_mn+2)
+	y_powers[0] = ScalarInt(1)
+	for i := 1; i <= c_bpp_mn+1; i += 1 {
+		y_powers[i] = new(edwards25519.Scalar).Multiply(y_powers[i-1], y)
+	}
+	y_mn_p1 := y_powers[c_bpp_mn+1]
+
+	// P_hat = A0 - z * SUM(g_i) + SUM((z + d_i * y^(mn-i)) * h_i) + y^(mn+1) * SUM(z^(2j) * V_j)
+	//       + ((z - z^2) * SUM(y^i) - z * y^(mn+1) * SUM(d_i)) * G
+	var scalars []*edwards25519.Scalar
+	var points []*edwards25519.Point
+	negZ := new(edwards25519.Scalar).Negate(z)
+	sumY := new(edwards25519.Scalar)
+	sumD := new(edwards25519.Scalar)
+	for i := 0; i < c_bpp_mn; i++ {
+		scalars = append(scalars, negZ, new(edwards25519.Scalar).Add(z, new(edwards25519.Scalar).Multiply(d[i], y_powers[c_bpp_mn-i])))
+		points = append(points, TraitGetGenerator(false, i), TraitGetGenerator(true, i))
+		sumY = sumY.Add(sumY, y_powers[i+1])
+		sumD = sumD.Add(sumD, d[i])
+	}
+	for j, v := range commitments_1div8 {
+		scalars = append(scalars, new(edwards25519.Scalar).Multiply(y_mn_p1, d[trait.at(j, 0)]))
+		points = append(points, mul8(v))
+	}
+	zMinusZsq := new(edwards25519.Scalar).Subtract(z, new(edwards25519.Scalar).Multiply(z, z))
+	cst := new(edwards25519.Scalar).Multiply(zMinusZsq, sumY)
+	cst = cst.Subtract(cst, new(edwards25519.Scalar).Multiply(new(edwards25519.Scalar).Multiply(z, y_mn_p1), sumD))
+	scalars = append(scalars, ScOne, cst)
+	points = append(points, mul8(sig.A0.Point), trait.G)
+	P := new(edwards25519.Point).VarTimeMultiScalarMult(scalars, points)
+
+	// fold the generators and P as the prover did
+	g := make([]*edwards25519.Point, c_bpp_mn)
+	h := make([]*edwards25519.Point, c_bpp_mn)
+	for i := range g {
+		g[i] = TraitGetGenerator(false, i)
+		h[i] = TraitGetGenerator(true, i)
+	}
+	y_inverse := new(edwards25519.Scalar).Invert(y)
+	for n, round := c_bpp_mn/2, 0; n >= 1; n, round = n/2, round+1 {
+		e := es[round]
+		e_squared := new(edwards25519.Scalar).Multiply(e, e)
+		e_inverse := new(edwards25519.Scalar).Invert(e)
+		e_inverse_squared := new(edwards25519.Scalar).Multiply(e_inverse, e_inverse)
+		y_inv_n := ScalarInt(1)
+		for i := 0; i < n; i++ {
+			y_inv_n = y_inv_n.Multiply(y_inv_n, y_inverse)
+		}
+		e_y_inv_n := new(edwards25519.Scalar).Multiply(e, y_inv_n)
+		for i := 0; i < n; i += 1 {
+			g[i] = new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{e_inverse, e_y_inv_n}, []*edwards25519.Point{g[i], g[n+i]})
+			h[i] = new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{e, e_inverse}, []*edwards25519.Point{h[i], h[n+i]})
+		}
+		// P_hat = e^2 * L + P + e^-2 * R
+		P = new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{e_squared, ScOne, e_inverse_squared}, []*edwards25519.Point{mul8(sig.Lv[round].Point), P, mul8(sig.Rv[round].Point)})
+	}
+
+	// e^2 * P + e * A + B == (r * e) * g + (s * e) * h + (r * y * s) * G + delta_1 * H + delta_2 * H2
+	e_squared := new(edwards25519.Scalar).Multiply(eFinal, eFinal)
+	lhs := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{e_squared, eFinal, ScOne}, []*edwards25519.Point{P, mul8(sig.A.Point), mul8(sig.B.Point)})
+	rys := new(edwards25519.Scalar).Multiply(new(edwards25519.Scalar).Multiply(sig.R.Scalar, y), sig.S.Scalar)
+	rhs := new(edwards25519.Point).VarTimeMultiScalarMult(
+		[]*edwards25519.Scalar{new(edwards25519.Scalar).Multiply(sig.R.Scalar, eFinal), new(edwards25519.Scalar).Multiply(sig.S.Scalar, eFinal), rys, sig.Delta1.Scalar, sig.Delta2.Scalar},
+		[]*edwards25519.Point{g[0], h[0], trait.G, trait.H, trait.H2},
+	)
+	if lhs.Equal(rhs) != 1 {
+		return errors.New("BPPEVerify: invalid proof")
+	}
+	return nil
+}
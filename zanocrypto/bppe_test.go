@@ -0,0 +1,40 @@
+package zanocrypto_test
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+func TestBPPE(t *testing.T) {
+	trait := zanocrypto.TraitZarcanum
+	for _, count := range []int{1, 2, 3} {
+		var values, masks, masks2 []*edwards25519.Scalar
+		var commitments []*edwards25519.Point
+		for i := 0; i < count; i++ {
+			v := zanocrypto.ScalarInt(uint64(1000 + 77*i))
+			m := zanocrypto.RandomScalar(rand.Reader)
+			m2 := zanocrypto.RandomScalar(rand.Reader)
+			values = append(values, v)
+			masks = append(masks, m)
+			masks2 = append(masks2, m2)
+			c := trait.CalcPedersenCommitment2(v, m, m2)
+			commitments = append(commitments, c.ScalarMult(zanocrypto.Sc1div8, c))
+		}
+		sig, err := trait.BPPEGen(rand.Reader, values, masks, masks2, commitments)
+		if err != nil {
+			t.Fatalf("BPPEGen(%d): %s", count, err)
+		}
+		if err := trait.BPPEVerify(sig, commitments); err != nil {
+			t.Errorf("BPPEVerify(%d): %s", count, err)
+		}
+		// a commitment to another value must not verify
+		other := trait.CalcPedersenCommitment2(zanocrypto.ScalarInt(5), masks[0], masks2[0])
+		commitments[0] = other.ScalarMult(zanocrypto.Sc1div8, other)
+		if err := trait.BPPEVerify(sig, commitments); err == nil {
+			t.Errorf("BPPEVerify(%d) accepted a wrong commitment", count)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package zanocrypto
+
+import (
+	"errors"
+	"io"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+var (
+	CRYPTO_HDS_CLSAG_GGXXG_LAYER_0   = []byte("ZANO_HDS_CLSAG_GGXXG_LAYER_ZERO\x00")
+	CRYPTO_HDS_CLSAG_GGXXG_LAYER_1   = []byte("ZANO_HDS_CLSAG_GGXXG_LAYER_ONE_\x00")
+	CRYPTO_HDS_CLSAG_GGXXG_LAYER_2   = []byte("ZANO_HDS_CLSAG_GGXXG_LAYER_TWO_\x00")
+	CRYPTO_HDS_CLSAG_GGXXG_LAYER_3   = []byte("ZANO_HDS_CLSAG_GGXXG_LAYER_THRE\x00")
+	CRYPTO_HDS_CLSAG_GGXXG_LAYER_4   = []byte("ZANO_HDS_CLSAG_GGXXG_LAYER_FOUR\x00")
+	CRYPTO_HDS_CLSAG_GGXXG_CHALLENGE = []byte("ZANO_HDS_CLSAG_GGXXG_CHALLENGE_\x00")
+)
+
+// CLSAG_GGXXGInputRef is a ring member of a Zarcanum stake input. All points but the
+// stealth address are premultiplied by 1/8, as found in the outputs.
+type CLSAG_GGXXGInputRef struct {
+	StealthAddress   *edwards25519.Point
+	AmountCommitment *edwards25519.Point
+	BlindedAssetID   *edwards25519.Point
+	ConcealingPoint  *edwards25519.Point
+}
+
+// clsagGGXXG holds the values shared by the signer and the verifier
+type clsagGGXXG struct {
+	inputHash []byte
+	wPubG     []*edwards25519.Point // agg_coeff_0 * P + agg_coeff_1 * (A - A^p) + agg_coeff_4 * Q
+	wPubX     []*edwards25519.Point // agg_coeff_2 * (C - A - Q) + agg_coeff_3 * (T - H)
+	aggCoeff  [5]*edwards25519.Scalar
+}
+
+func newClsagGGXXG(m []byte, ring []CLSAG_GGXXGInputRef, pseudoOutAmountCommitment, stakeCommitment, ki *edwards25519.Point, K [4]*edwards25519.Point) *clsagGGXXG {
+	hsc := NewHashHelper()
+	hsc.AddBytesModL(m)
+	for i := range ring {
+		hsc.Add(ring[i].StealthAddress, ring[i].AmountCommitment, ring[i].BlindedAssetID, ring[i].ConcealingPoint)
+	}
+	hsc.Add(new(edwards25519.Point).ScalarMult(Sc1div8, pseudoOutAmountCommitment))
+	hsc.Add(new(edwards25519.Point).ScalarMult(Sc1div8, stakeCommitment))
+	hsc.Add(ki, K[0], K[1], K[2], K[3])
+
+	res := &clsagGGXXG{inputHash: hsc.CalcRawHash()}
+	for n, layer := range [][]byte{CRYPTO_HDS_CLSAG_GGXXG_LAYER_0, CRYPTO_HDS_CLSAG_GGXXG_LAYER_1, CRYPTO_HDS_CLSAG_GGXXG_LAYER_2, CRYPTO_HDS_CLSAG_GGXXG_LAYER_3, CRYPTO_HDS_CLSAG_GGXXG_LAYER_4} {
+		hsc.AddBytes(layer)
+		hsc.AddBytes(res.inputHash)
+		res.aggCoeff[n] = hsc.CalcHash()
+	}
+
+	for i := range ring {
+		A := new(edwards25519.Point).MultByCofactor(ring[i].AmountCommitment)
+		T := new(edwards25519.Point).MultByCofactor(ring[i].BlindedAssetID)
+		Q := new(edwards25519.Point).MultByCofactor(ring[i].ConcealingPoint)
+
+		g := new(edwards25519.Point).VarTimeMultiScalarMult(
+			[]*edwards25519.Scalar{res.aggCoeff[0], res.aggCoeff[1], res.aggCoeff[4]},
+			[]*edwards25519.Point{ring[i].StealthAddress, new(edwards25519.Point).Subtract(A, pseudoOutAmountCommitment), Q},
+		)
+		cAQ := new(edwards25519.Point).Subtract(stakeCommitment, A)
+		cAQ = cAQ.Subtract(cAQ, Q)
+		x := new(edwards25519.Point).VarTimeMultiScalarMult(
+			[]*edwards25519.Scalar{res.aggCoeff[2], res.aggCoeff[3]},
+			[]*edwards25519.Point{cAQ, new(edwards25519.Point).Subtract(T, C_point_H)},
+		)
+		res.wPubG = append(res.wPubG, g)
+		res.wPubX = append(res.wPubX, x)
+	}
+	return res
+}
+
+// keyImages returns W_key_image_g and W_key_image_x, K are the auxiliary key images multiplied by 8
+func (c *clsagGGXXG) keyImages(ki *edwards25519.Point, K [4]*edwards25519.Point) (*edwards25519.Point, *edwards25519.Point) {
+	g := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{c.aggCoeff[0], c.aggCoeff[1], c.aggCoeff[4]}, []*edwards25519.Point{ki, K[0], K[3]})
+	x := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{c.aggCoeff[2], c.aggCoeff[3]}, []*edwards25519.Point{K[1], K[2]})
+	return g, x
+}
+
+// challenge returns c_{i+1} = Hs(input_hash, r_g*G + c*W_pub_keys_g[i], r_g*hp(P[i]) + c*W_key_image_g,
+// r_x*X + c*W_pub_keys_x[i], r_x*hp(P[i]) + c*W_key_image_x)
+func (c *clsagGGXXG) challenge(ring []CLSAG_GGXXGInputRef, i int, cPrev, rg, rx *edwards25519.Scalar, wKiG, wKiX *edwards25519.Point) *edwards25519.Scalar {
+	hpI := Hp(ring[i].StealthAddress.Bytes())
+	hsc := NewHashHelper()
+	hsc.AddBytes(CRYPTO_HDS_CLSAG_GGXXG_CHALLENGE)
+	hsc.AddBytes(c.inputHash)
+	hsc.Add(new(edwards25519.Point).VarTimeDoubleScalarBaseMult(cPrev, c.wPubG[i], rg))
+	hsc.Add(new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{rg, cPrev}, []*edwards25519.Point{hpI, wKiG}))
+	hsc.Add(new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{rx, cPrev}, []*edwards25519.Point{C_point_X, c.wPubX[i]}))
+	hsc.Add(new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{rx, cPrev}, []*edwards25519.Point{hpI, wKiX}))
+	return hsc.CalcHash()
+}
+
+// GenerateCLSAG_GGXXG generates the ring signature of a Zarcanum stake input, with 5 layers:
+//
+//	layer 0 (G): P[i]               secret_0_xp
+//	layer 1 (G): A[i] - A^p         secret_1_f = f - f'
+//	layer 2 (X): C - A[i] - Q[i]    secret_2_x = x0 - a * r
+//	layer 3 (X): T[i] - H           secret_3_r = r, the asset id blinding mask
+//	layer 4 (G): Q[i]               secret_4_q
+//
+// where A^p is the pseudo out amount commitment and C the stake commitment of the Zarcanum
+// proof. Both are given as is (not premultiplied by 1/8).
+func GenerateCLSAG_GGXXG(
+	rnd io.Reader,
+	m []byte,
+	ring []CLSAG_GGXXGInputRef,
+	ki *edwards25519.Point,
+	pseudoOutAmountCommitment, stakeCommitment *edwards25519.Point,
+	secret0Xp, secret1F, secret2X, secret3R, secret4Q *edwards25519.Scalar,
+	secretIndex uint64,
+) (*zanobase.CLSAG_GGXXG_Sig, error) {
+	ringSize := len(ring)
+	if ringSize == 0 {
+		return nil, errors.New("ring size is zero")
+	}
+	if secretIndex >= uint64(ringSize) {
+		return nil, errors.New("secretIndex out of range")
+	}
+
+	kiBase := Hp(ring[secretIndex].StealthAddress.Bytes())
+	if new(edwards25519.Point).ScalarMult(secret0Xp, kiBase).Equal(ki) != 1 {
+		return nil, errors.New("CLSAG_GGXXG keyImage mismatch")
+	}
+
+	sig := new(zanobase.CLSAG_GGXXG_Sig)
+	var K [4]*edwards25519.Point
+	for n, secret := range []*edwards25519.Scalar{secret1F, secret2X, secret3R, secret4Q} {
+		// K_div8 = (1/8 * secret) * ki_base
+		K[n] = new(edwards25519.Point).ScalarMult(new(edwards25519.Scalar).Multiply(Sc1div8, secret), kiBase)
+	}
+	sig.K1 = &zanobase.Point{K[0]}
+	sig.K2 = &zanobase.Point{K[1]}
+	sig.K3 = &zanobase.Point{K[2]}
+	sig.K4 = &zanobase.Point{K[3]}
+
+	c := newClsagGGXXG(m, ring, pseudoOutAmountCommitment, stakeCommitment, ki, K)
+
+	wSecKeyG := new(edwards25519.Scalar).Multiply(c.aggCoeff[0], secret0Xp)
+	wSecKeyG = wSecKeyG.Add(wSecKeyG, new(edwards25519.Scalar).Multiply(c.aggCoeff[1], secret1F))
+	wSecKeyG = wSecKeyG.Add(wSecKeyG, new(edwards25519.Scalar).Multiply(c.aggCoeff[4], secret4Q))
+	wSecKeyX := new(edwards25519.Scalar).Multiply(c.aggCoeff[2], secret2X)
+	wSecKeyX = wSecKeyX.Add(wSecKeyX, new(edwards25519.Scalar).Multiply(c.aggCoeff[3], secret3R))
+
+	// sanity check, mostly useful for debugging
+	if new(edwards25519.Point).ScalarBaseMult(wSecKeyG).Equal(c.wPubG[secretIndex]) != 1 {
+		return nil, errors.New("CLSAG_GGXXG w_sec_key_g mismatch")
+	}
+	if new(edwards25519.Point).ScalarMult(wSecKeyX, C_point_X).Equal(c.wPubX[secretIndex]) != 1 {
+		return nil, errors.New("CLSAG_GGXXG w_sec_key_x mismatch")
+	}
+
+	for n := range K {
+		K[n] = new(edwards25519.Point).MultByCofactor(K[n])
+	}
+	wKiG, wKiX := c.keyImages(ki, K)
+
+	alphaG := RandomScalar(rnd)
+	alphaX := RandomScalar(rnd)
+	hsc := NewHashHelper()
+	hsc.AddBytes(CRYPTO_HDS_CLSAG_GGXXG_CHALLENGE)
+	hsc.AddBytes(c.inputHash)
+	hsc.Add(new(edwards25519.Point).ScalarBaseMult(alphaG))
+	hsc.Add(new(edwards25519.Point).ScalarMult(alphaG, kiBase))
+	hsc.Add(new(edwards25519.Point).ScalarMult(alphaX, C_point_X))
+	hsc.Add(new(edwards25519.Point).ScalarMult(alphaX, kiBase))
+	cPrev := hsc.CalcHash()
+
+	sig.Rg = make([]*zanobase.Scalar, ringSize)
+	sig.Rx = make([]*zanobase.Scalar, ringSize)
+	for i := 0; i < ringSize; i++ {
+		sig.Rg[i] = &zanobase.Scalar{RandomScalar(rnd)}
+		sig.Rx[i] = &zanobase.Scalar{RandomScalar(rnd)}
+	}
+
+	i := (int(secretIndex) + 1) % ringSize
+	for j := 0; j < ringSize-1; j++ {
+		if i == 0 {
+			sig.C = &zanobase.Scalar{new(edwards25519.Scalar).Set(cPrev)}
+		}
+		cPrev = c.challenge(ring, i, cPrev, sig.Rg[i].Scalar, sig.Rx[i].Scalar, wKiG, wKiX)
+		i = (i + 1) % ringSize
+	}
+	if secretIndex == 0 {
+		sig.C = &zanobase.Scalar{new(edwards25519.Scalar).Set(cPrev)}
+	}
+
+	sig.Rg[secretIndex] = &zanobase.Scalar{new(edwards25519.Scalar).Subtract(alphaG, new(edwards25519.Scalar).Multiply(cPrev, wSecKeyG))}
+	sig.Rx[secretIndex] = &zanobase.Scalar{new(edwards25519.Scalar).Subtract(alphaX, new(edwards25519.Scalar).Multiply(cPrev, wSecKeyX))}
+
+	return sig, nil
+}
+
+// VerifyCLSAG_GGXXG checks a signature generated by GenerateCLSAG_GGXXG
+func VerifyCLSAG_GGXXG(m []byte, ring []CLSAG_GGXXGInputRef, ki *edwards25519.Point, pseudoOutAmountCommitment, stakeCommitment *edwards25519.Point, sig *zanobase.CLSAG_GGXXG_Sig) error {
+	ringSize := len(ring)
+	if ringSize == 0 {
+		return errors.New("ring size is zero")
+	}
+	if len(sig.Rg) != ringSize || len(sig.Rx) != ringSize {
+		return errors.New("CLSAG_GGXXG: invalid signature size")
+	}
+	if sig.C == nil || sig.C.Scalar == nil {
+		return errors.New("CLSAG_GGXXG: missing challenge")
+	}
+	var K [4]*edwards25519.Point
+	for n, k := range []*zanobase.Point{sig.K1, sig.K2, sig.K3, sig.K4} {
+		if k == nil || k.Point == nil {
+			return errors.New("CLSAG_GGXXG: missing auxiliary key image")
+		}
+		K[n] = k.Point
+	}
+	for i := range sig.Rg {
+		if sig.Rg[i] == nil || sig.Rx[i] == nil || sig.Rg[i].Scalar == nil || sig.Rx[i].Scalar == nil {
+			return errors.New("CLSAG_GGXXG: missing response")
+		}
+	}
+	// key image must be in the prime order subgroup: (l-1) * ki == -ki
+	if new(edwards25519.Point).ScalarMult(ScLm1, ki).Equal(new(edwards25519.Point).Negate(ki)) != 1 {
+		return errors.New("CLSAG_GGXXG: invalid key image")
+	}
+
+	c := newClsagGGXXG(m, ring, pseudoOutAmountCommitment, stakeCommitment, ki, K)
+	for n := range K {
+		K[n] = new(edwards25519.Point).MultByCofactor(K[n])
+	}
+	wKiG, wKiX := c.keyImages(ki, K)
+
+	cPrev := sig.C.Scalar
+	for i := 0; i < ringSize; i++ {
+		cPrev = c.challenge(ring, i, cPrev, sig.Rg[i].Scalar, sig.Rx[i].Scalar, wKiG, wKiX)
+	}
+	if cPrev.Equal(sig.C.Scalar) != 1 {
+		return errors.New("CLSAG_GGXXG: invalid signature")
+	}
+	return nil
+}
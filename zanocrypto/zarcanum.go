@@ -3,6 +3,7 @@ package zanocrypto
 import (
 	"errors"
 	"io"
+	"math/big"
 
 	"filippo.io/edwards25519"
 	"github.com/ModChain/zanolib/zanobase"
@@ -120,3 +121,255 @@ func GenerateDoubleSchnorrSig(rnd io.Reader, gen0, gen1 *edwards25519.Point, m [
 	}
 	return res, nil
 }
+
+var (
+	CRYPTO_HDS_ZARCANUM_LAST_POW_HASH = []byte("ZANO_HDS_ZARCANUM_LAST_POW_HASH\x00")
+	CRYPTO_HDS_ZARCANUM_PROOF_HASH    = []byte("ZANO_HDS_ZARCANUM_PROOF_HASH___\x00")
+
+	// ErrStakeNotEligible is returned when a stake does not satisfy the PoS inequality for the
+	// given kernel hash and difficulty
+	ErrStakeNotEligible = errors.New("stake does not satisfy the PoS inequality")
+
+	bigL = new(big.Int).Add(scalarBig(ScLm1), big.NewInt(1))
+	bigZ = new(big.Int).Lsh(big.NewInt(1), 64) // c_zarcanum_z_coeff
+)
+
+// scalarBig returns the value of s as an integer
+func scalarBig(s *edwards25519.Scalar) *big.Int {
+	b := s.Bytes()
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+// zarcanumLhs returns lhs = h * (f + q + f'), where h is the kernel hash reduced mod l and f'
+// is derived from the last PoW block id
+func zarcanumLhs(kernelHash, lastPowId []byte, fq *edwards25519.Scalar) (h, fPrime, lhs *edwards25519.Scalar) {
+	var wide [64]byte
+	copy(wide[:], kernelHash)
+	h, _ = new(edwards25519.Scalar).SetUniformBytes(wide[:])
+	hsc := NewHashHelper()
+	hsc.AddBytes(CRYPTO_HDS_ZARCANUM_LAST_POW_HASH)
+	hsc.AddBytes(lastPowId)
+	fPrime = hsc.CalcHash()
+	lhs = new(edwards25519.Scalar).Multiply(h, new(edwards25519.Scalar).Add(fq, fPrime))
+	return
+}
+
+// zarcanumMaxD returns floor(l / (z * D)), the largest d accepted for the given PoS difficulty
+func zarcanumMaxD(difficulty *big.Int) *big.Int {
+	return new(big.Int).Quo(bigL, new(big.Int).Mul(bigZ, difficulty))
+}
+
+// ZarcanumCheckMainPosInequality returns true if an output of the given amount can stake with
+// kernelHash, that is if h * (f + q + f') < z * floor(l / (z * D)) * amount. fq is the sum of
+// the amount blinding mask f and the concealing secret q of the output.
+func ZarcanumCheckMainPosInequality(kernelHash, lastPowId []byte, fq *edwards25519.Scalar, amount uint64, difficulty *big.Int) bool {
+	if difficulty == nil || difficulty.Sign() <= 0 {
+		return false
+	}
+	_, _, lhs := zarcanumLhs(kernelHash, lastPowId, fq)
+	rhs := new(big.Int).Mul(bigZ, zarcanumMaxD(difficulty))
+	rhs = rhs.Mul(rhs, new(big.Int).SetUint64(amount))
+	return scalarBig(lhs).Cmp(rhs) < 0
+}
+
+// zarcanumChallenge returns the challenge of the linear relation proofs
+func zarcanumChallenge(m, kernelHash []byte, sig *zanobase.ZarcanumSig, R01, R23, R4, CpCp, CmCp, F *edwards25519.Point) *edwards25519.Scalar {
+	hsc := NewHashHelper()
+	hsc.AddBytes(CRYPTO_HDS_ZARCANUM_PROOF_HASH)
+	hsc.AddBytes(m)
+	hsc.AddBytes(kernelHash)
+	hsc.Add(sig.C.Point, sig.CPrime.Point, sig.E.Point)
+	hsc.Add(R01, R23, R4, CpCp, CmCp, F)
+	return hsc.CalcHash()
+}
+
+// GenerateZarcanumProof generates the zarcanum_sig of a PoS block, proving that the output
+// ring[secretIndex] of the given amount satisfies the PoS inequality for kernelHash without
+// revealing it. m is the hash of the block. secretF and secretR are the amount and asset id
+// blinding masks of the staked output, secretQ its concealing secret (Q = q * G), and
+// pseudoOutBlindingMask the blinding mask f' of the pseudo out amount commitment
+// A^p = amount * T + f' * G.
+//
+// This follows the construction of Zarcanum (see zarcanum_generate_proof) but has not been
+// checked against test vectors from the reference implementation.
+func GenerateZarcanumProof(
+	rnd io.Reader,
+	m, kernelHash []byte,
+	ring []CLSAG_GGXXGInputRef,
+	lastPowId []byte,
+	ki *edwards25519.Point,
+	difficulty *big.Int,
+	secretXp, secretQ, secretF, secretR, pseudoOutBlindingMask *edwards25519.Scalar,
+	amount uint64,
+	secretIndex uint64,
+) (*zanobase.ZarcanumSig, error) {
+	if secretIndex >= uint64(len(ring)) {
+		return nil, errors.New("secretIndex out of range")
+	}
+	if !ZarcanumCheckMainPosInequality(kernelHash, lastPowId, new(edwards25519.Scalar).Add(secretF, secretQ), amount, difficulty) {
+		return nil, ErrStakeNotEligible
+	}
+	a := ScalarInt(amount)
+	fq := new(edwards25519.Scalar).Add(secretF, secretQ)
+	h, fPrime, lhs := zarcanumLhs(kernelHash, lastPowId, fq)
+
+	// d = floor(lhs / (z * a)) + 1, so that 0 < d * z * a - lhs <= z * a < 2^128
+	za := new(big.Int).Mul(bigZ, new(big.Int).SetUint64(amount))
+	dBig := new(big.Int).Quo(scalarBig(lhs), za)
+	dBig = dBig.Add(dBig, big.NewInt(1))
+	dz := new(edwards25519.Scalar).Multiply(bigScalar(dBig), Sc2p64)
+
+	// ba = dz * a - lhs, bf = dz * (f + q) - h * a, bx = x2 - h * x1 + dz * x0
+	x0 := RandomScalar(rnd)
+	x1 := RandomScalar(rnd)
+	x2 := RandomScalar(rnd)
+	ba := new(edwards25519.Scalar).Subtract(new(edwards25519.Scalar).Multiply(dz, a), lhs)
+	bf := new(edwards25519.Scalar).Subtract(new(edwards25519.Scalar).Multiply(dz, fq), new(edwards25519.Scalar).Multiply(h, a))
+	bx := new(edwards25519.Scalar).Subtract(x2, new(edwards25519.Scalar).Multiply(h, x1))
+	bx = bx.Add(bx, new(edwards25519.Scalar).Multiply(dz, x0))
+
+	// C = x0 * X + a * H + (f + q) * G
+	// C' = x1 * X + (f + q) * H + a * G
+	// E = bx * X + ba * H + bf * G
+	C := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{x0, a, fq}, []*edwards25519.Point{C_point_X, C_point_H, C_point_G})
+	CPrime := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{x1, fq, a}, []*edwards25519.Point{C_point_X, C_point_H, C_point_G})
+	E := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{bx, ba, bf}, []*edwards25519.Point{C_point_X, C_point_H, C_point_G})
+
+	sig := &zanobase.ZarcanumSig{
+		D:      &zanobase.Scalar{bigScalar(dBig)},
+		C:      &zanobase.Point{new(edwards25519.Point).ScalarMult(Sc1div8, C)},
+		CPrime: &zanobase.Point{new(edwards25519.Point).ScalarMult(Sc1div8, CPrime)},
+		E:      &zanobase.Point{new(edwards25519.Point).ScalarMult(Sc1div8, E)},
+	}
+
+	// sanity check: F = E - dz * C + h * C' + h * f' * H = x2 * X
+	F := new(edwards25519.Point).VarTimeMultiScalarMult(
+		[]*edwards25519.Scalar{ScOne, new(edwards25519.Scalar).Negate(dz), h, new(edwards25519.Scalar).Multiply(h, fPrime)},
+		[]*edwards25519.Point{E, C, CPrime, C_point_H},
+	)
+	if F.Equal(new(edwards25519.Point).ScalarMult(x2, C_point_X)) != 1 {
+		return nil, errors.New("zarcanum: F mismatch")
+	}
+
+	// linear relation proofs
+	//   C + C' = (x0 + x1) * X + (a + f + q) * (H + G)
+	//   C - C' = (x0 - x1) * X + (a - f - q) * (H - G)
+	//   F      = x2 * X
+	r := make([]*edwards25519.Scalar, 5)
+	for i := range r {
+		r[i] = RandomScalar(rnd)
+	}
+	R01 := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{r[0], r[1]}, []*edwards25519.Point{C_point_X, C_point_H_plus_G})
+	R23 := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{r[2], r[3]}, []*edwards25519.Point{C_point_X, C_point_H_minus_G})
+	R4 := new(edwards25519.Point).ScalarMult(r[4], C_point_X)
+	c := zarcanumChallenge(m, kernelHash, sig, R01, R23, R4, new(edwards25519.Point).Add(C, CPrime), new(edwards25519.Point).Subtract(C, CPrime), F)
+	sig.Challenge = &zanobase.Scalar{c}
+
+	secrets := []*edwards25519.Scalar{
+		new(edwards25519.Scalar).Add(x0, x1),
+		new(edwards25519.Scalar).Add(a, fq),
+		new(edwards25519.Scalar).Subtract(x0, x1),
+		new(edwards25519.Scalar).Subtract(a, fq),
+		x2,
+	}
+	y := make([]*zanobase.Scalar, 5)
+	for i := range y {
+		// y_i = r_i + c * secret_i
+		y[i] = &zanobase.Scalar{new(edwards25519.Scalar).Add(r[i], new(edwards25519.Scalar).Multiply(c, secrets[i]))}
+	}
+	sig.Y0, sig.Y1, sig.Y2, sig.Y3, sig.Y4 = y[0], y[1], y[2], y[3], y[4]
+
+	// range proof of ba, with E = ba * H + bf * G + bx * X
+	rp, err := TraitZarcanum.BPPEGen(rnd, []*edwards25519.Scalar{ba}, []*edwards25519.Scalar{bf}, []*edwards25519.Scalar{bx}, []*edwards25519.Point{sig.E.Point})
+	if err != nil {
+		return nil, err
+	}
+	sig.ERangeProof = rp
+
+	// pseudo out amount commitment A^p = a * T + f' * G
+	T := new(edwards25519.Point).MultByCofactor(ring[secretIndex].BlindedAssetID)
+	pseudoOut := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{a, pseudoOutBlindingMask}, []*edwards25519.Point{T, C_point_G})
+	sig.PseudoOutAmountCommitment = &zanobase.Point{new(edwards25519.Point).ScalarMult(Sc1div8, pseudoOut)}
+
+	sig.GGXXG, err = GenerateCLSAG_GGXXG(rnd, m, ring, ki, pseudoOut, C,
+		secretXp,
+		new(edwards25519.Scalar).Subtract(secretF, pseudoOutBlindingMask),
+		new(edwards25519.Scalar).Subtract(x0, new(edwards25519.Scalar).Multiply(a, secretR)),
+		secretR,
+		secretQ,
+		secretIndex,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+// VerifyZarcanumProof checks a zarcanum_sig generated by GenerateZarcanumProof
+func VerifyZarcanumProof(m, kernelHash []byte, ring []CLSAG_GGXXGInputRef, lastPowId []byte, ki *edwards25519.Point, difficulty *big.Int, sig *zanobase.ZarcanumSig) error {
+	for _, s := range []*zanobase.Scalar{sig.D, sig.Challenge, sig.Y0, sig.Y1, sig.Y2, sig.Y3, sig.Y4} {
+		if s == nil || s.Scalar == nil {
+			return errors.New("zarcanum: missing scalar")
+		}
+	}
+	for _, p := range []*zanobase.Point{sig.C, sig.CPrime, sig.E, sig.PseudoOutAmountCommitment} {
+		if p == nil || p.Point == nil {
+			return errors.New("zarcanum: missing point")
+		}
+	}
+	if sig.ERangeProof == nil || sig.GGXXG == nil {
+		return errors.New("zarcanum: missing proof")
+	}
+	if difficulty.Sign() <= 0 {
+		return errors.New("zarcanum: invalid difficulty")
+	}
+	d := scalarBig(sig.D.Scalar)
+	if d.Sign() <= 0 || d.Cmp(zarcanumMaxD(difficulty)) > 0 {
+		return errors.New("zarcanum: d out of range")
+	}
+
+	var wide [64]byte
+	copy(wide[:], kernelHash)
+	h, _ := new(edwards25519.Scalar).SetUniformBytes(wide[:])
+	_, fPrime, _ := zarcanumLhs(kernelHash, lastPowId, ScZero)
+	dz := new(edwards25519.Scalar).Multiply(sig.D.Scalar, Sc2p64)
+
+	C := new(edwards25519.Point).MultByCofactor(sig.C.Point)
+	CPrime := new(edwards25519.Point).MultByCofactor(sig.CPrime.Point)
+	E := new(edwards25519.Point).MultByCofactor(sig.E.Point)
+	F := new(edwards25519.Point).VarTimeMultiScalarMult(
+		[]*edwards25519.Scalar{ScOne, new(edwards25519.Scalar).Negate(dz), h, new(edwards25519.Scalar).Multiply(h, fPrime)},
+		[]*edwards25519.Point{E, C, CPrime, C_point_H},
+	)
+	CpCp := new(edwards25519.Point).Add(C, CPrime)
+	CmCp := new(edwards25519.Point).Subtract(C, CPrime)
+
+	// R = y * base - c * commitment
+	c := sig.Challenge.Scalar
+	negC := new(edwards25519.Scalar).Negate(c)
+	R01 := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{sig.Y0.Scalar, sig.Y1.Scalar, negC}, []*edwards25519.Point{C_point_X, C_point_H_plus_G, CpCp})
+	R23 := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{sig.Y2.Scalar, sig.Y3.Scalar, negC}, []*edwards25519.Point{C_point_X, C_point_H_minus_G, CmCp})
+	R4 := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{sig.Y4.Scalar, negC}, []*edwards25519.Point{C_point_X, F})
+	if zarcanumChallenge(m, kernelHash, sig, R01, R23, R4, CpCp, CmCp, F).Equal(c) != 1 {
+		return errors.New("zarcanum: invalid linear relation proof")
+	}
+
+	if err := TraitZarcanum.BPPEVerify(sig.ERangeProof, []*edwards25519.Point{sig.E.Point}); err != nil {
+		return err
+	}
+
+	pseudoOut := new(edwards25519.Point).MultByCofactor(sig.PseudoOutAmountCommitment.Point)
+	return VerifyCLSAG_GGXXG(m, ring, ki, pseudoOut, C, sig.GGXXG)
+}
+
+// bigScalar returns v mod l as a scalar
+func bigScalar(v *big.Int) *edwards25519.Scalar {
+	b := new(big.Int).Mod(v, bigL).FillBytes(make([]byte, 32))
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return must(new(edwards25519.Scalar).SetCanonicalBytes(b))
+}
@@ -0,0 +1,84 @@
+package zanocrypto_test
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib/zanocrypto"
+)
+
+func div8(p *edwards25519.Point) *edwards25519.Point {
+	return new(edwards25519.Point).ScalarMult(zanocrypto.Sc1div8, p)
+}
+
+func randomPoint() *edwards25519.Point {
+	return new(edwards25519.Point).ScalarBaseMult(zanocrypto.RandomScalar(rand.Reader))
+}
+
+func TestZarcanumProof(t *testing.T) {
+	const amount = 1_000_000_000_000
+	xp := zanocrypto.RandomScalar(rand.Reader) // stealth address secret
+	f := zanocrypto.RandomScalar(rand.Reader)  // amount blinding mask
+	r := zanocrypto.RandomScalar(rand.Reader)  // asset id blinding mask
+	q := zanocrypto.RandomScalar(rand.Reader)  // concealing secret
+	fp := zanocrypto.RandomScalar(rand.Reader) // pseudo out blinding mask
+
+	P := new(edwards25519.Point).ScalarBaseMult(xp)
+	ki, err := zanocrypto.ComputeKeyImage(xp, P)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// T = H + r * X, A = a * T + f * G
+	T := new(edwards25519.Point).Add(zanocrypto.C_point_H, new(edwards25519.Point).ScalarMult(r, zanocrypto.C_point_X))
+	A := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{zanocrypto.ScalarInt(amount), f}, []*edwards25519.Point{T, zanocrypto.C_point_G})
+
+	const secretIndex = 2
+	ring := make([]zanocrypto.CLSAG_GGXXGInputRef, 4)
+	for i := range ring {
+		ring[i] = zanocrypto.CLSAG_GGXXGInputRef{randomPoint(), randomPoint(), randomPoint(), randomPoint()}
+	}
+	ring[secretIndex] = zanocrypto.CLSAG_GGXXGInputRef{P, div8(A), div8(T), div8(new(edwards25519.Point).ScalarBaseMult(q))}
+
+	m := make([]byte, 32)
+	kernelHash := make([]byte, 32)
+	lastPowId := make([]byte, 32)
+	rand.Read(m)
+	rand.Read(kernelHash)
+	rand.Read(lastPowId)
+	difficulty := big.NewInt(1000)
+
+	sig, err := zanocrypto.GenerateZarcanumProof(rand.Reader, m, kernelHash, ring, lastPowId, ki, difficulty, xp, q, f, r, fp, amount, secretIndex)
+	if err != nil {
+		t.Fatalf("GenerateZarcanumProof: %s", err)
+	}
+	if err := zanocrypto.VerifyZarcanumProof(m, kernelHash, ring, lastPowId, ki, difficulty, sig); err != nil {
+		t.Errorf("VerifyZarcanumProof: %s", err)
+	}
+	// the pseudo out commitment must commit to the same amount with the new mask
+	pseudoOut := new(edwards25519.Point).VarTimeMultiScalarMult([]*edwards25519.Scalar{zanocrypto.ScalarInt(amount), fp}, []*edwards25519.Point{T, zanocrypto.C_point_G})
+	if new(edwards25519.Point).MultByCofactor(sig.PseudoOutAmountCommitment.Point).Equal(pseudoOut) != 1 {
+		t.Errorf("unexpected pseudo out amount commitment")
+	}
+
+	// the proof is bound to the block hash and the kernel
+	other := make([]byte, 32)
+	rand.Read(other)
+	if err := zanocrypto.VerifyZarcanumProof(other, kernelHash, ring, lastPowId, ki, difficulty, sig); err == nil {
+		t.Errorf("proof verified with another block hash")
+	}
+	if err := zanocrypto.VerifyZarcanumProof(m, other, ring, lastPowId, ki, difficulty, sig); err == nil {
+		t.Errorf("proof verified with another kernel hash")
+	}
+	// d is too large for a much higher difficulty
+	if err := zanocrypto.VerifyZarcanumProof(m, kernelHash, ring, lastPowId, ki, new(big.Int).Lsh(big.NewInt(1), 180), sig); err == nil {
+		t.Errorf("proof verified with a higher difficulty")
+	}
+
+	_, err = zanocrypto.GenerateZarcanumProof(rand.Reader, m, kernelHash, ring, lastPowId, ki, new(big.Int).Lsh(big.NewInt(1), 180), xp, q, f, r, fp, amount, secretIndex)
+	if !errors.Is(err, zanocrypto.ErrStakeNotEligible) {
+		t.Errorf("expected ErrStakeNotEligible, got %v", err)
+	}
+}
@@ -0,0 +1,383 @@
+package zanoepee
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Unmarshal decodes a portable storage document into v, which must be a pointer to a
+// struct, a map with string keys such as Section, or an interface. See Marshal for struct
+// tags. Integer values can be decoded into any integer type they fit in, and unknown
+// entries are ignored.
+func Unmarshal(buf []byte, v any) error {
+	if len(buf) < len(header)+1 || !bytes.Equal(buf[:len(header)], header) {
+		return errors.New("portable storage: invalid signature")
+	}
+	if buf[len(header)] != Version {
+		return fmt.Errorf("portable storage: unsupported version %d", buf[len(header)])
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("portable storage: Unmarshal requires a non-nil pointer")
+	}
+	d := &decoder{buf: buf[len(header)+1:]}
+	if err := d.readSection(rv.Elem(), 0); err != nil {
+		return err
+	}
+	if len(d.buf) != 0 {
+		return errors.New("portable storage: trailing data")
+	}
+	return nil
+}
+
+type decoder struct {
+	buf []byte
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.buf) < n {
+		return nil, errTruncated
+	}
+	res := d.buf[:n]
+	d.buf = d.buf[n:]
+	return res, nil
+}
+
+func (d *decoder) readByte() (byte, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *decoder) readVarint() (uint64, error) {
+	if len(d.buf) == 0 {
+		return 0, errTruncated
+	}
+	b, err := d.read(1 << (d.buf[0] & 3))
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v >> 2, nil
+}
+
+// readCount reads a size and checks it against the remaining data, each item using at
+// least one byte
+func (d *decoder) readCount() (int, error) {
+	n, err := d.readVarint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.buf)) {
+		return 0, errTruncated
+	}
+	return int(n), nil
+}
+
+func (d *decoder) readSection(v reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return errors.New("portable storage: maximum depth exceeded")
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	var fields map[string]*field
+	switch v.Kind() {
+	case reflect.Interface:
+		s := Section{}
+		if err := d.readSection(reflect.ValueOf(s), depth); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(s))
+		return nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("portable storage: unsupported map key type %s", v.Type().Key())
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	case reflect.Struct:
+		fields = make(map[string]*field)
+		for _, f := range structFields(v.Type()) {
+			fields[f.name] = f
+		}
+	default:
+		return fmt.Errorf("portable storage: cannot decode object into %s", v.Type())
+	}
+
+	count, err := d.readCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		l, err := d.readByte()
+		if err != nil {
+			return err
+		}
+		name, err := d.read(int(l))
+		if err != nil {
+			return err
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := d.readEntry(e, false, depth); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			v.SetMapIndex(reflect.ValueOf(string(name)).Convert(v.Type().Key()), e)
+		case reflect.Struct:
+			f, ok := fields[string(name)]
+			if !ok {
+				// skip unknown entries
+				var skip any
+				if err := d.readEntry(reflect.ValueOf(&skip).Elem(), false, depth); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				continue
+			}
+			if err := d.readEntry(v.FieldByIndex(f.index), f.blob, depth); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *decoder) readEntry(v reflect.Value, blob bool, depth int) error {
+	t, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	typ := Type(t)
+	if typ&TypeArrayFlag == 0 {
+		return d.readValue(v, typ, blob, depth)
+	}
+	typ &^= TypeArrayFlag
+
+	count, err := d.readCount()
+	if err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		res := make([]any, count)
+		for i := range res {
+			if err := d.readValue(reflect.ValueOf(&res[i]).Elem(), typ, false, depth); err != nil {
+				return err
+			}
+		}
+		v.Set(reflect.ValueOf(res))
+	case reflect.Slice:
+		res := reflect.MakeSlice(v.Type(), count, count)
+		for i := 0; i < count; i++ {
+			if err := d.readValue(res.Index(i), typ, false, depth); err != nil {
+				return err
+			}
+		}
+		v.Set(res)
+	default:
+		return fmt.Errorf("portable storage: cannot decode array into %s", v.Type())
+	}
+	return nil
+}
+
+func (d *decoder) readValue(v reflect.Value, typ Type, blob bool, depth int) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Interface {
+		if typ == TypeObject {
+			return d.readSection(v, depth+1)
+		}
+		g, err := d.readGeneric(typ)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(g))
+		return nil
+	}
+
+	switch typ {
+	case TypeInt64, TypeInt32, TypeInt16, TypeInt8:
+		g, err := d.readGeneric(typ)
+		if err != nil {
+			return err
+		}
+		return setInt(v, reflect.ValueOf(g).Int())
+	case TypeUint64, TypeUint32, TypeUint16, TypeUint8:
+		g, err := d.readGeneric(typ)
+		if err != nil {
+			return err
+		}
+		return setUint(v, reflect.ValueOf(g).Uint())
+	case TypeDouble:
+		g, err := d.readGeneric(typ)
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.Float64 && v.Kind() != reflect.Float32 {
+			return fmt.Errorf("portable storage: cannot decode double into %s", v.Type())
+		}
+		v.SetFloat(g.(float64))
+	case TypeBool:
+		b, err := d.readByte()
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.Bool {
+			return fmt.Errorf("portable storage: cannot decode bool into %s", v.Type())
+		}
+		v.SetBool(b != 0)
+	case TypeString:
+		n, err := d.readCount()
+		if err != nil {
+			return err
+		}
+		s, err := d.read(n)
+		if err != nil {
+			return err
+		}
+		return setString(v, s, blob)
+	case TypeObject:
+		return d.readSection(v, depth+1)
+	default:
+		return fmt.Errorf("portable storage: unknown type %d", typ)
+	}
+	return nil
+}
+
+// readGeneric reads a scalar value of the given type as its Go equivalent
+func (d *decoder) readGeneric(typ Type) (any, error) {
+	size := map[Type]int{
+		TypeInt64: 8, TypeInt32: 4, TypeInt16: 2, TypeInt8: 1,
+		TypeUint64: 8, TypeUint32: 4, TypeUint16: 2, TypeUint8: 1,
+		TypeDouble: 8, TypeBool: 1,
+	}
+	switch typ {
+	case TypeString:
+		n, err := d.readCount()
+		if err != nil {
+			return nil, err
+		}
+		s, err := d.read(n)
+		return string(s), err
+	}
+	n, ok := size[typ]
+	if !ok {
+		return nil, fmt.Errorf("portable storage: unknown type %d", typ)
+	}
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	var u uint64
+	for i := n - 1; i >= 0; i-- {
+		u = u<<8 | uint64(b[i])
+	}
+	switch typ {
+	case TypeInt64:
+		return int64(u), nil
+	case TypeInt32:
+		return int32(u), nil
+	case TypeInt16:
+		return int16(u), nil
+	case TypeInt8:
+		return int8(u), nil
+	case TypeUint64:
+		return u, nil
+	case TypeUint32:
+		return uint32(u), nil
+	case TypeUint16:
+		return uint16(u), nil
+	case TypeUint8:
+		return uint8(u), nil
+	case TypeDouble:
+		return math.Float64frombits(u), nil
+	}
+	return u != 0, nil // TypeBool
+}
+
+func setInt(v reflect.Value, i int64) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(i) {
+			return fmt.Errorf("portable storage: value %d overflows %s", i, v.Type())
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i < 0 {
+			return fmt.Errorf("portable storage: negative value %d for %s", i, v.Type())
+		}
+		return setUint(v, uint64(i))
+	}
+	return fmt.Errorf("portable storage: cannot decode integer into %s", v.Type())
+}
+
+func setUint(v reflect.Value, u uint64) error {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.OverflowUint(u) {
+			return fmt.Errorf("portable storage: value %d overflows %s", u, v.Type())
+		}
+		v.SetUint(u)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if u > math.MaxInt64 {
+			return fmt.Errorf("portable storage: value %d overflows %s", u, v.Type())
+		}
+		return setInt(v, int64(u))
+	}
+	return fmt.Errorf("portable storage: cannot decode integer into %s", v.Type())
+}
+
+func setString(v reflect.Value, s []byte, blob bool) error {
+	t := v.Type()
+	switch {
+	case t.Kind() == reflect.String:
+		v.SetString(string(s))
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		v.SetBytes(bytes.Clone(s))
+	case blob && t.Kind() == reflect.Slice && isPOD(t.Elem()):
+		size := int(t.Elem().Size())
+		if len(s)%size != 0 {
+			return fmt.Errorf("portable storage: blob of %d bytes is not a multiple of %d", len(s), size)
+		}
+		res := reflect.MakeSlice(t, len(s)/size, len(s)/size)
+		if err := binary.Read(bytes.NewReader(s), binary.LittleEndian, res.Interface()); err != nil {
+			return err
+		}
+		v.Set(res)
+	case isPOD(t):
+		if len(s) != int(t.Size()) {
+			return fmt.Errorf("portable storage: expected %d bytes for %s, got %d", t.Size(), t, len(s))
+		}
+		return binary.Read(bytes.NewReader(s), binary.LittleEndian, v.Addr().Interface())
+	default:
+		return fmt.Errorf("portable storage: cannot decode string into %s", t)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package zanoepee
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+)
+
+// Marshal encodes v as a portable storage document. v must be a struct, a pointer to a
+// struct, or a map with string keys such as Section.
+//
+// Struct fields are named using the "storage" tag, defaulting to the field name. The
+// omitempty option skips zero values, and the blob option stores a slice or an array of
+// fixed size values (such as hashes) as a single string, as KV_SERIALIZE_CONTAINER_POD_AS_BLOB
+// does. Nil pointers are always skipped, and embedded structs without a tag are
+// flattened.
+func Marshal(v any) ([]byte, error) {
+	buf := append(slices.Clone(header), Version)
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, errors.New("portable storage: cannot marshal nil")
+		}
+		rv = rv.Elem()
+	}
+	return appendSection(buf, rv, 0)
+}
+
+type entry struct {
+	name string
+	v    reflect.Value
+	blob bool
+}
+
+func appendSection(buf []byte, v reflect.Value, depth int) ([]byte, error) {
+	if depth > maxDepth {
+		return nil, errors.New("portable storage: maximum depth exceeded")
+	}
+	var entries []*entry
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("portable storage: unsupported map key type %s", v.Type().Key())
+		}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return cmp.Compare(a.String(), b.String())
+		})
+		for _, k := range keys {
+			entries = append(entries, &entry{name: k.String(), v: v.MapIndex(k)})
+		}
+	case reflect.Struct:
+		for _, f := range structFields(v.Type()) {
+			fv := v.FieldByIndex(f.index)
+			if f.omitEmpty && fv.IsZero() {
+				continue
+			}
+			entries = append(entries, &entry{name: f.name, v: fv, blob: f.blob})
+		}
+	default:
+		return nil, fmt.Errorf("portable storage: cannot store %s as an object", v.Type())
+	}
+
+	// nil pointers and interfaces are not stored
+	entries = slices.DeleteFunc(entries, func(e *entry) bool {
+		for e.v.Kind() == reflect.Interface || e.v.Kind() == reflect.Pointer {
+			if e.v.IsNil() {
+				return true
+			}
+			e.v = e.v.Elem()
+		}
+		return false
+	})
+
+	buf, err := appendVarint(buf, uint64(len(entries)))
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if len(e.name) > maxName {
+			return nil, fmt.Errorf("portable storage: name %s is too long", e.name)
+		}
+		buf = append(buf, byte(len(e.name)))
+		buf = append(buf, e.name...)
+		if buf, err = appendEntry(buf, e.v, e.blob, depth); err != nil {
+			return nil, fmt.Errorf("%s: %w", e.name, err)
+		}
+	}
+	return buf, nil
+}
+
+// appendEntry appends the type of v followed by its value
+func appendEntry(buf []byte, v reflect.Value, blob bool, depth int) ([]byte, error) {
+	if blob {
+		b, err := podBlob(v)
+		if err != nil {
+			return nil, err
+		}
+		return appendString(append(buf, byte(TypeString)), b)
+	}
+	if isArray(v.Type()) {
+		var elems []reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			e := v.Index(i)
+			for e.Kind() == reflect.Interface || e.Kind() == reflect.Pointer {
+				if e.IsNil() {
+					return nil, errors.New("portable storage: nil value in array")
+				}
+				e = e.Elem()
+			}
+			elems = append(elems, e)
+		}
+		typ := TypeObject // arbitrary type for empty generic arrays
+		if len(elems) > 0 {
+			var err error
+			if typ, err = typeOf(elems[0].Type()); err != nil {
+				return nil, err
+			}
+		} else if t, err := typeOf(v.Type().Elem()); err == nil {
+			typ = t
+		}
+		if typ&TypeArrayFlag != 0 {
+			return nil, errors.New("portable storage: nested arrays are not supported")
+		}
+		buf = append(buf, byte(typ|TypeArrayFlag))
+		buf, err := appendVarint(buf, uint64(len(elems)))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range elems {
+			if t, err := typeOf(e.Type()); err != nil || t != typ {
+				return nil, errors.New("portable storage: array elements must all have the same type")
+			}
+			if buf, err = appendValue(buf, e, depth); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+
+	typ, err := typeOf(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	return appendValue(append(buf, byte(typ)), v, depth)
+}
+
+// appendValue appends the value of v without its type
+func appendValue(buf []byte, v reflect.Value, depth int) ([]byte, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int64:
+		return binary.LittleEndian.AppendUint64(buf, uint64(v.Int())), nil
+	case reflect.Int32:
+		return binary.LittleEndian.AppendUint32(buf, uint32(v.Int())), nil
+	case reflect.Int16:
+		return binary.LittleEndian.AppendUint16(buf, uint16(v.Int())), nil
+	case reflect.Int8:
+		return append(buf, byte(v.Int())), nil
+	case reflect.Uint, reflect.Uint64:
+		return binary.LittleEndian.AppendUint64(buf, v.Uint()), nil
+	case reflect.Uint32:
+		return binary.LittleEndian.AppendUint32(buf, uint32(v.Uint())), nil
+	case reflect.Uint16:
+		return binary.LittleEndian.AppendUint16(buf, uint16(v.Uint())), nil
+	case reflect.Uint8:
+		return append(buf, byte(v.Uint())), nil
+	case reflect.Float64:
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.Float())), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return append(buf, 1), nil
+		}
+		return append(buf, 0), nil
+	case reflect.String:
+		return appendString(buf, []byte(v.String()))
+	case reflect.Slice, reflect.Array:
+		// only byte slices reach here, see typeOf
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return appendString(buf, b)
+	case reflect.Struct, reflect.Map:
+		return appendSection(buf, v, depth+1)
+	}
+	return nil, fmt.Errorf("portable storage: unsupported type %s", v.Type())
+}
+
+func appendString(buf, s []byte) ([]byte, error) {
+	buf, err := appendVarint(buf, uint64(len(s)))
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, s...), nil
+}
+
+// typeOf returns the storage type of values of type t. Arrays are handled by appendEntry.
+func typeOf(t reflect.Type) (Type, error) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64:
+		return TypeInt64, nil
+	case reflect.Int32:
+		return TypeInt32, nil
+	case reflect.Int16:
+		return TypeInt16, nil
+	case reflect.Int8:
+		return TypeInt8, nil
+	case reflect.Uint, reflect.Uint64:
+		return TypeUint64, nil
+	case reflect.Uint32:
+		return TypeUint32, nil
+	case reflect.Uint16:
+		return TypeUint16, nil
+	case reflect.Uint8:
+		return TypeUint8, nil
+	case reflect.Float64:
+		return TypeDouble, nil
+	case reflect.Bool:
+		return TypeBool, nil
+	case reflect.String:
+		return TypeString, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return TypeString, nil
+		}
+		et, err := typeOf(t.Elem())
+		if err != nil {
+			return 0, err
+		}
+		return et | TypeArrayFlag, nil
+	case reflect.Struct, reflect.Map:
+		return TypeObject, nil
+	case reflect.Pointer:
+		return typeOf(t.Elem())
+	}
+	return 0, fmt.Errorf("portable storage: unsupported type %s", t)
+}
+
+// isArray returns true if t is stored as an array (slices other than byte slices)
+func isArray(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8
+}
+
+// podBlob returns the little endian representation of a fixed size value, or of a slice
+// or array of such values
+func podBlob(v reflect.Value) ([]byte, error) {
+	t := v.Type()
+	if !isPOD(t) && !((t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && isPOD(t.Elem())) {
+		return nil, fmt.Errorf("portable storage: cannot store %s as a blob", t)
+	}
+	buf := &bytes.Buffer{}
+	if err := binary.Write(buf, binary.LittleEndian, v.Interface()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
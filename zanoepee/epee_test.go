@@ -0,0 +1,143 @@
+package zanoepee_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/ModChain/zanolib/zanoepee"
+)
+
+type testEntry struct {
+	Block string   `storage:"block"`
+	Txs   []string `storage:"txs"`
+}
+
+type testDoc struct {
+	Height  uint64       `storage:"height"`
+	Delta   int32        `storage:"delta"`
+	Ok      bool         `storage:"ok"`
+	Ratio   float64      `storage:"ratio"`
+	Name    string       `storage:"name"`
+	Ids     [][32]byte   `storage:"ids,blob"`
+	Indexes []uint64     `storage:"indexes"`
+	Entries []*testEntry `storage:"entries"`
+	Missing *testEntry   `storage:"missing"`
+	Empty   string       `storage:"empty,omitempty"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	doc := &testDoc{
+		Height:  1234567,
+		Delta:   -5,
+		Ok:      true,
+		Ratio:   0.5,
+		Name:    "zano",
+		Ids:     [][32]byte{{1}, {2, 3}},
+		Indexes: []uint64{1, 1 << 40},
+		Entries: []*testEntry{{Block: "b1", Txs: []string{"t1", "t2"}}, {Block: "b2", Txs: []string{}}},
+	}
+	buf, err := zanoepee.Marshal(doc)
+	if err != nil {
+		t.Fatalf("failed to marshal: %s", err)
+	}
+	if !bytes.HasPrefix(buf, []byte{0x01, 0x11, 0x01, 0x01, 0x01, 0x01, 0x02, 0x01, 0x01}) {
+		t.Errorf("invalid header %x", buf[:9])
+	}
+
+	var res testDoc
+	if err := zanoepee.Unmarshal(buf, &res); err != nil {
+		t.Fatalf("failed to unmarshal: %s", err)
+	}
+	if !reflect.DeepEqual(&res, doc) {
+		t.Errorf("round trip mismatch: %+v", res)
+	}
+
+	var s zanoepee.Section
+	if err := zanoepee.Unmarshal(buf, &s); err != nil {
+		t.Fatalf("failed to unmarshal section: %s", err)
+	}
+	if s["height"] != uint64(1234567) || s["delta"] != int32(-5) || s["name"] != "zano" {
+		t.Errorf("unexpected section values: %v", s)
+	}
+	if _, ok := s["missing"]; ok {
+		t.Errorf("nil pointer should not be stored")
+	}
+	if ids := s["ids"].(string); len(ids) != 64 || ids[32:34] != "\x02\x03" {
+		t.Errorf("unexpected blob %x", ids)
+	}
+	entries := s["entries"].([]any)
+	if len(entries) != 2 || entries[0].(zanoepee.Section)["block"] != "b1" {
+		t.Errorf("unexpected entries %v", entries)
+	}
+
+	// sections encode back to an equivalent document
+	var res2 testDoc
+	if err := zanoepee.Unmarshal(must(zanoepee.Marshal(s)), &res2); err != nil {
+		t.Errorf("failed to decode marshaled section: %s", err)
+	} else if !reflect.DeepEqual(&res2, doc) {
+		t.Errorf("section round trip mismatch: %+v", res2)
+	}
+}
+
+func TestKnownEncoding(t *testing.T) {
+	// {"txid": blob of 2 bytes, "n": uint32 7}
+	buf, err := zanoepee.Marshal(zanoepee.Section{"txid": []byte{0xaa, 0xbb}, "n": uint32(7)})
+	if err != nil {
+		t.Fatalf("failed to marshal: %s", err)
+	}
+	expect := "0111010101010201" + "01" + "08" + "016e" + "06" + "07000000" + "0474786964" + "0a" + "08" + "aabb"
+	if hex.EncodeToString(buf) != expect {
+		t.Errorf("unexpected encoding %x", buf)
+	}
+}
+
+func TestVarintSizes(t *testing.T) {
+	for _, n := range []int{0, 63, 64, 16383, 16384} {
+		buf, err := zanoepee.Marshal(zanoepee.Section{"s": string(make([]byte, n))})
+		if err != nil {
+			t.Fatalf("failed to marshal: %s", err)
+		}
+		var s zanoepee.Section
+		if err := zanoepee.Unmarshal(buf, &s); err != nil {
+			t.Fatalf("size %d: failed to unmarshal: %s", n, err)
+		}
+		if len(s["s"].(string)) != n {
+			t.Errorf("size %d: got %d", n, len(s["s"].(string)))
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	buf := must(zanoepee.Marshal(&testDoc{Name: "zano", Indexes: []uint64{1, 2, 3}}))
+
+	var res testDoc
+	for i := 0; i < len(buf); i++ {
+		if err := zanoepee.Unmarshal(buf[:i], &res); err == nil {
+			t.Errorf("truncated data of %d bytes accepted", i)
+		}
+	}
+	if err := zanoepee.Unmarshal(append(buf, 0), &res); err == nil {
+		t.Errorf("trailing data accepted")
+	}
+	bad := bytes.Clone(buf)
+	bad[0] ^= 1
+	if err := zanoepee.Unmarshal(bad, &res); err == nil {
+		t.Errorf("invalid signature accepted")
+	}
+
+	var small struct {
+		Height uint8 `storage:"height"`
+	}
+	if err := zanoepee.Unmarshal(must(zanoepee.Marshal(zanoepee.Section{"height": uint64(256)})), &small); err == nil {
+		t.Errorf("integer overflow accepted")
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
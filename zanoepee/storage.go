@@ -0,0 +1,128 @@
+package zanoepee
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Portable storage header: two signatures and a version
+const (
+	SignatureA = 0x01011101
+	SignatureB = 0x01020101
+	Version    = 1
+)
+
+// Type of a value in portable storage
+type Type uint8
+
+const (
+	TypeInt64  Type = 1
+	TypeInt32  Type = 2
+	TypeInt16  Type = 3
+	TypeInt8   Type = 4
+	TypeUint64 Type = 5
+	TypeUint32 Type = 6
+	TypeUint16 Type = 7
+	TypeUint8  Type = 8
+	TypeDouble Type = 9
+	TypeString Type = 10
+	TypeBool   Type = 11
+	TypeObject Type = 12
+	TypeArray  Type = 13
+
+	TypeArrayFlag Type = 0x80 // ORed with the element type for arrays
+)
+
+const (
+	maxDepth = 64
+	maxName  = 255
+)
+
+// Section is the generic representation of a portable storage object. Values are int64,
+// int32, int16, int8, uint64, uint32, uint16, uint8, float64, string, bool, Section or
+// []any for arrays.
+type Section map[string]any
+
+var (
+	errTruncated = errors.New("portable storage: truncated data")
+	header       = binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, SignatureA), SignatureB)
+)
+
+// appendVarint appends v using epee's varint encoding, where the 2 lowest bits of the
+// first byte give the size of the value
+func appendVarint(buf []byte, v uint64) ([]byte, error) {
+	switch {
+	case v <= 0x3f:
+		return append(buf, byte(v<<2)), nil
+	case v <= 0x3fff:
+		return binary.LittleEndian.AppendUint16(buf, uint16(v<<2|1)), nil
+	case v <= 0x3fffffff:
+		return binary.LittleEndian.AppendUint32(buf, uint32(v<<2|2)), nil
+	case v <= 0x3fffffffffffffff:
+		return binary.LittleEndian.AppendUint64(buf, v<<2|3), nil
+	}
+	return nil, fmt.Errorf("portable storage: value %d too large for varint", v)
+}
+
+// field describes how a struct field is stored
+type field struct {
+	index     []int
+	name      string
+	omitEmpty bool
+	blob      bool // store a slice or array of fixed size values as a single string
+}
+
+func structFields(t reflect.Type) []*field {
+	var res []*field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("storage")
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			// embedded structs are flattened, as KV_CHAIN_BASE does
+			for _, sub := range structFields(f.Type) {
+				sub.index = append([]int{i}, sub.index...)
+				res = append(res, sub)
+			}
+			continue
+		}
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		res = append(res, &field{
+			index:     []int{i},
+			name:      name,
+			omitEmpty: hasOpt(opts, "omitempty"),
+			blob:      hasOpt(opts, "blob"),
+		})
+	}
+	return res
+}
+
+func hasOpt(opts, opt string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// isPOD returns true if values of t have a fixed size and can be stored in a blob
+func isPOD(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	case reflect.Array:
+		return isPOD(t.Elem())
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package zanop2p
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanoepee"
+)
+
+// Config configures a p2p connection
+type Config struct {
+	NetworkId [16]byte      // network id of the chain, see P2P_NETWORK_ID in currency_config.h
+	PeerId    uint64        // random if zero
+	MyPort    uint32        // port we accept connections on, 0 if none
+	Sync      *CoreSyncData // chain state advertised to the peer, empty if nil
+
+	// OnNotify, if set, is called from the connection's read loop for notifications not
+	// consumed by a pending call, such as NOTIFY_NEW_BLOCK or NOTIFY_NEW_TRANSACTIONS
+	OnNotify func(command uint32, body []byte)
+}
+
+// Conn is a levin connection to a Zano node that completed the handshake
+type Conn struct {
+	Peer     *NodeData     // peer's node data received during handshake
+	PeerSync *CoreSyncData // peer's chain state received during handshake
+	Peers    []*PeerEntry  // peer list received during handshake
+
+	conn net.Conn
+	cfg  *Config
+	wmu  sync.Mutex
+
+	mu      sync.Mutex
+	pending map[uint32][]chan *packet // waiters for responses or notifications, by command
+	closed  chan struct{}
+	err     error
+}
+
+type packet struct {
+	h    *Header
+	body []byte
+}
+
+// Dial connects to the node at address (host:port) and performs the handshake
+func Dial(ctx context.Context, address string, cfg *Config) (*Conn, error) {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	res, err := NewConn(ctx, c, cfg)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+// NewConn performs the handshake on an established connection. The connection is closed
+// if the handshake fails.
+func NewConn(ctx context.Context, c net.Conn, cfg *Config) (*Conn, error) {
+	cfgCopy := *cfg
+	if cfgCopy.PeerId == 0 {
+		var buf [8]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			return nil, err
+		}
+		cfgCopy.PeerId = binary.LittleEndian.Uint64(buf[:])
+	}
+	if cfgCopy.Sync == nil {
+		cfgCopy.Sync = &CoreSyncData{}
+	}
+	res := &Conn{
+		conn:    c,
+		cfg:     &cfgCopy,
+		pending: make(map[uint32][]chan *packet),
+		closed:  make(chan struct{}),
+	}
+	go res.readLoop()
+
+	req := &HandshakeRequest{
+		NodeData: &NodeData{
+			NetworkId: cfgCopy.NetworkId,
+			PeerId:    cfgCopy.PeerId,
+			LocalTime: time.Now().Unix(),
+			MyPort:    cfgCopy.MyPort,
+		},
+		PayloadData: cfgCopy.Sync,
+	}
+	var resp HandshakeResponse
+	if err := res.Invoke(ctx, CommandHandshake, req, &resp); err != nil {
+		res.Close()
+		return nil, fmt.Errorf("handshake failed: %w", err)
+	}
+	if resp.NodeData == nil || resp.NodeData.NetworkId != cfgCopy.NetworkId {
+		res.Close()
+		return nil, errors.New("handshake failed: peer is on a different network")
+	}
+	res.Peer = resp.NodeData
+	res.PeerSync = resp.PayloadData
+	res.Peers = ParsePeerlist(resp.LocalPeerlist)
+	return res, nil
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	err := c.conn.Close()
+	<-c.closed
+	return err
+}
+
+// Invoke sends a request and decodes its response into res
+func (c *Conn) Invoke(ctx context.Context, command uint32, req, res any) error {
+	body, err := zanoepee.Marshal(req)
+	if err != nil {
+		return err
+	}
+	ch := c.wait(command)
+	if err := c.write(&Header{HaveToReturn: true, Command: command, Flags: LevinPacketRequest}, body); err != nil {
+		c.forget(command, ch)
+		return err
+	}
+	p, err := c.receive(ctx, command, ch)
+	if err != nil {
+		return err
+	}
+	if p.h.ReturnCode < 0 {
+		return fmt.Errorf("peer returned error code %d for command %d", p.h.ReturnCode, command)
+	}
+	return zanoepee.Unmarshal(p.body, res)
+}
+
+// Notify sends a notification, which has no response
+func (c *Conn) Notify(command uint32, req any) error {
+	body, err := zanoepee.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return c.write(&Header{Command: command, Flags: LevinPacketRequest}, body)
+}
+
+// TimedSync exchanges chain states and peer lists with the peer
+func (c *Conn) TimedSync(ctx context.Context) (*TimedSyncResponse, error) {
+	res := new(TimedSyncResponse)
+	if err := c.Invoke(ctx, CommandTimedSync, &TimedSyncRequest{PayloadData: c.cfg.Sync}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetObjects requests blocks and transactions by id, and waits for the peer's answer.
+// Ids unknown to the peer are returned in MissedIds.
+func (c *Conn) GetObjects(ctx context.Context, blocks, txs []zanobase.Value256) (*Objects, error) {
+	ch := c.wait(NotifyResponseGetObjects)
+	if err := c.Notify(NotifyRequestGetObjects, &RequestGetObjects{Txs: txs, Blocks: blocks}); err != nil {
+		c.forget(NotifyResponseGetObjects, ch)
+		return nil, err
+	}
+	p, err := c.receive(ctx, NotifyResponseGetObjects, ch)
+	if err != nil {
+		return nil, err
+	}
+	var res ResponseGetObjects
+	if err := zanoepee.Unmarshal(p.body, &res); err != nil {
+		return nil, err
+	}
+	return res.Parse()
+}
+
+// RelayTransactions sends transactions to the peer for inclusion in its pool and relay
+func (c *Conn) RelayTransactions(txs ...*zanobase.Transaction) error {
+	req := &NewTransactions{}
+	for _, tx := range txs {
+		buf, err := tx.Bytes()
+		if err != nil {
+			return err
+		}
+		req.Txs = append(req.Txs, buf)
+	}
+	return c.Notify(NotifyNewTransactions, req)
+}
+
+func (c *Conn) write(h *Header, body []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return WritePacket(c.conn, h, body)
+}
+
+// wait registers a waiter for the next response or notification with the given command
+func (c *Conn) wait(command uint32) chan *packet {
+	ch := make(chan *packet, 1)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[command] = append(c.pending[command], ch)
+	return ch
+}
+
+// receive waits for the packet delivered to ch, registered by wait for command. If ctx
+// ends or the connection is closed first, the waiter is removed so that later packets go
+// to the next caller.
+func (c *Conn) receive(ctx context.Context, command uint32, ch chan *packet) (*packet, error) {
+	select {
+	case p := <-ch:
+		return p, nil
+	case <-ctx.Done():
+		c.forget(command, ch)
+		return nil, ctx.Err()
+	case <-c.closed:
+		c.forget(command, ch)
+		return nil, fmt.Errorf("connection closed: %w", c.err)
+	}
+}
+
+// forget removes the waiter ch for command, if it has not received a packet yet
+func (c *Conn) forget(command uint32, ch chan *packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiters := c.pending[command]
+	if n := slices.Index(waiters, ch); n >= 0 {
+		c.pending[command] = slices.Delete(waiters, n, n+1)
+	}
+}
+
+// dispatch delivers p to the first waiter for its command, if any
+func (c *Conn) dispatch(p *packet) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiters := c.pending[p.h.Command]
+	if len(waiters) == 0 {
+		return false
+	}
+	waiters[0] <- p
+	c.pending[p.h.Command] = waiters[1:]
+	return true
+}
+
+func (c *Conn) readLoop() {
+	var err error
+	defer func() {
+		c.err = err
+		close(c.closed)
+	}()
+	for {
+		var p packet
+		if p.h, p.body, err = ReadPacket(c.conn); err != nil {
+			return
+		}
+		switch {
+		case p.h.IsResponse():
+			c.dispatch(&p)
+		case p.h.HaveToReturn:
+			// answer asynchronously so the read loop never blocks on writes
+			go func(command uint32) {
+				if err := c.answer(command); err != nil {
+					c.conn.Close()
+				}
+			}(p.h.Command)
+		default:
+			if !c.dispatch(&p) && c.cfg.OnNotify != nil {
+				c.cfg.OnNotify(p.h.Command, p.body)
+			}
+		}
+	}
+}
+
+// answer responds to requests sent by the peer
+func (c *Conn) answer(command uint32) error {
+	var res any
+	switch command {
+	case CommandTimedSync:
+		res = &TimedSyncResponse{LocalTime: time.Now().Unix(), PayloadData: c.cfg.Sync}
+	case CommandPing:
+		res = &PingResponse{Status: "OK", PeerId: c.cfg.PeerId}
+	default:
+		return c.write(&Header{Command: command, ReturnCode: LevinErrorHandlerNotDefined, Flags: LevinPacketResponse}, nil)
+	}
+	body, err := zanoepee.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return c.write(&Header{Command: command, ReturnCode: LevinOk, Flags: LevinPacketResponse}, body)
+}
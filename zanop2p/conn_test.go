@@ -0,0 +1,235 @@
+package zanop2p_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanoepee"
+	"github.com/ModChain/zanolib/zanop2p"
+)
+
+var testNetworkId = [16]byte{0x11, 0x10, 0x01, 0x11, 0x01, 0x01, 0x11, 0x01, 0x10, 0x11, 0x00, 0x11, 0x01, 0x11, 0x21, 0x01}
+
+// fakePeer is an in-process node answering the client's requests
+type fakePeer struct {
+	t       *testing.T
+	conn    net.Conn
+	blocks  map[zanobase.Value256][]byte
+	relayed chan [][]byte
+	pong    chan *zanop2p.PingResponse
+	ignore  int // number of get objects requests left unanswered
+}
+
+func (p *fakePeer) send(h *zanop2p.Header, v any) {
+	body, err := zanoepee.Marshal(v)
+	if err != nil {
+		p.t.Errorf("fake peer: failed to encode: %s", err)
+		return
+	}
+	if err := zanop2p.WritePacket(p.conn, h, body); err != nil {
+		p.t.Errorf("fake peer: failed to write: %s", err)
+	}
+}
+
+func (p *fakePeer) run() {
+	sync := &zanop2p.CoreSyncData{CurrentHeight: 1000, TopId: zanobase.Value256{7}, ClientVersion: "fake"}
+	resp := func(cmd uint32) *zanop2p.Header {
+		return &zanop2p.Header{Command: cmd, Flags: zanop2p.LevinPacketResponse}
+	}
+	for {
+		h, body, err := zanop2p.ReadPacket(p.conn)
+		if err != nil {
+			return
+		}
+		switch h.Command {
+		case zanop2p.CommandHandshake:
+			var req zanop2p.HandshakeRequest
+			if err := zanoepee.Unmarshal(body, &req); err != nil {
+				p.t.Errorf("invalid handshake: %s", err)
+				return
+			}
+			peer := &zanop2p.PeerEntry{IP: net.IPv4(10, 0, 0, 1), Port: 11121, Id: 99, LastSeen: 1700000000}
+			p.send(resp(h.Command), &zanop2p.HandshakeResponse{
+				NodeData:      &zanop2p.NodeData{NetworkId: req.NodeData.NetworkId, PeerId: 42},
+				PayloadData:   sync,
+				LocalPeerlist: [][zanop2p.PeerlistEntrySize]byte{peer.Bytes()},
+			})
+		case zanop2p.CommandTimedSync:
+			p.send(resp(h.Command), &zanop2p.TimedSyncResponse{LocalTime: 123, PayloadData: sync})
+		case zanop2p.CommandPing:
+			var res zanop2p.PingResponse
+			if err := zanoepee.Unmarshal(body, &res); err != nil {
+				p.t.Errorf("invalid ping response: %s", err)
+			}
+			p.pong <- &res
+		case zanop2p.NotifyRequestGetObjects:
+			var req zanop2p.RequestGetObjects
+			if err := zanoepee.Unmarshal(body, &req); err != nil {
+				p.t.Errorf("invalid get objects request: %s", err)
+				return
+			}
+			if p.ignore > 0 {
+				p.ignore--
+				continue
+			}
+			// ping the client first, which it must answer by itself
+			p.send(&zanop2p.Header{HaveToReturn: true, Command: zanop2p.CommandPing, Flags: zanop2p.LevinPacketRequest}, zanoepee.Section{})
+			res := &zanop2p.ResponseGetObjects{CurrentBlockchainHeight: 1000}
+			for _, id := range req.Blocks {
+				if blk, ok := p.blocks[id]; ok {
+					res.Blocks = append(res.Blocks, &zanop2p.BlockCompleteEntry{Block: blk})
+				} else {
+					res.MissedIds = append(res.MissedIds, id)
+				}
+			}
+			p.send(&zanop2p.Header{Command: zanop2p.NotifyResponseGetObjects, Flags: zanop2p.LevinPacketRequest}, res)
+		case zanop2p.NotifyNewTransactions:
+			var req zanop2p.NewTransactions
+			if err := zanoepee.Unmarshal(body, &req); err != nil {
+				p.t.Errorf("invalid new transactions: %s", err)
+			}
+			p.relayed <- req.Txs
+		}
+	}
+}
+
+func TestConn(t *testing.T) {
+	blk := &zanobase.Block{
+		BlockHeader: zanobase.BlockHeader{MajorVersion: 3, Timestamp: 1700000000, Nonce: 42},
+		MinerTx:     &zanobase.Transaction{Version: 2},
+	}
+	blkBlob, err := blk.Bytes()
+	if err != nil {
+		t.Fatalf("failed to serialize block: %s", err)
+	}
+
+	client, server := net.Pipe()
+	peer := &fakePeer{
+		t:       t,
+		conn:    server,
+		blocks:  map[zanobase.Value256][]byte{{1}: blkBlob},
+		relayed: make(chan [][]byte, 1),
+		pong:    make(chan *zanop2p.PingResponse, 1),
+	}
+	go peer.run()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c, err := zanop2p.NewConn(ctx, client, &zanop2p.Config{NetworkId: testNetworkId, PeerId: 5})
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	defer c.Close()
+
+	if c.Peer.PeerId != 42 || c.PeerSync.CurrentHeight != 1000 || c.PeerSync.TopId != (zanobase.Value256{7}) {
+		t.Errorf("unexpected peer data %+v %+v", c.Peer, c.PeerSync)
+	}
+	if len(c.Peers) != 1 || !c.Peers[0].IP.Equal(net.IPv4(10, 0, 0, 1)) || c.Peers[0].Port != 11121 || c.Peers[0].Id != 99 {
+		t.Errorf("unexpected peer list %+v", c.Peers)
+	}
+
+	ts, err := c.TimedSync(ctx)
+	if err != nil {
+		t.Fatalf("timed sync failed: %s", err)
+	}
+	if ts.LocalTime != 123 || ts.PayloadData.ClientVersion != "fake" {
+		t.Errorf("unexpected timed sync response %+v", ts)
+	}
+
+	objs, err := c.GetObjects(ctx, []zanobase.Value256{{1}, {2}}, nil)
+	if err != nil {
+		t.Fatalf("get objects failed: %s", err)
+	}
+	if len(objs.Blocks) != 1 || objs.Blocks[0].Nonce != 42 || objs.Blocks[0].MinerTx.Version != 2 {
+		t.Errorf("unexpected blocks %+v", objs.Blocks)
+	}
+	if len(objs.MissedIds) != 1 || objs.MissedIds[0] != (zanobase.Value256{2}) || objs.CurrentHeight != 1000 {
+		t.Errorf("unexpected missed ids %v", objs.MissedIds)
+	}
+	if pong := <-peer.pong; pong.Status != "OK" || pong.PeerId != 5 {
+		t.Errorf("unexpected ping response %+v", pong)
+	}
+
+	tx := &zanobase.Transaction{Version: 2}
+	if err := c.RelayTransactions(tx); err != nil {
+		t.Fatalf("relay failed: %s", err)
+	}
+	expect, _ := tx.Bytes()
+	if txs := <-peer.relayed; len(txs) != 1 || !bytes.Equal(txs[0], expect) {
+		t.Errorf("unexpected relayed transactions")
+	}
+}
+
+func TestGetObjectsTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	peer := &fakePeer{t: t, conn: server, pong: make(chan *zanop2p.PingResponse, 1), ignore: 1}
+	go peer.run()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := zanop2p.NewConn(ctx, client, &zanop2p.Config{NetworkId: testNetworkId, PeerId: 5})
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	defer c.Close()
+
+	short, cancelShort := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelShort()
+	if _, err := c.GetObjects(short, []zanobase.Value256{{1}}, nil); err == nil {
+		t.Fatalf("unanswered request should time out")
+	}
+
+	// the response must go to this call, not to the timed out one
+	objs, err := c.GetObjects(ctx, []zanobase.Value256{{1}}, nil)
+	if err != nil {
+		t.Fatalf("get objects failed: %s", err)
+	}
+	if len(objs.MissedIds) != 1 || objs.MissedIds[0] != (zanobase.Value256{1}) {
+		t.Errorf("unexpected missed ids %v", objs.MissedIds)
+	}
+}
+
+func TestHandshakeWrongNetwork(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		if _, _, err := zanop2p.ReadPacket(server); err != nil {
+			return
+		}
+		body, _ := zanoepee.Marshal(&zanop2p.HandshakeResponse{NodeData: &zanop2p.NodeData{NetworkId: [16]byte{1}}})
+		zanop2p.WritePacket(server, &zanop2p.Header{Command: zanop2p.CommandHandshake, Flags: zanop2p.LevinPacketResponse}, body)
+	}()
+	if _, err := zanop2p.NewConn(context.Background(), client, &zanop2p.Config{NetworkId: testNetworkId}); err == nil {
+		t.Errorf("handshake with a peer on another network should fail")
+	}
+}
+
+func TestPacket(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := zanop2p.WritePacket(buf, &zanop2p.Header{HaveToReturn: true, Command: 1001, ReturnCode: -1, Flags: 1}, []byte("abc")); err != nil {
+		t.Fatalf("failed to write: %s", err)
+	}
+	if buf.Len() != zanop2p.LevinHeaderSize+3 || buf.Bytes()[0] != 0x01 || buf.Bytes()[1] != 0x21 {
+		t.Errorf("unexpected packet %x", buf.Bytes())
+	}
+	h, body, err := zanop2p.ReadPacket(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read: %s", err)
+	}
+	if !h.HaveToReturn || h.Command != 1001 || h.ReturnCode != -1 || h.Flags != 1 || h.ProtocolVersion != 1 || string(body) != "abc" {
+		t.Errorf("unexpected packet %+v %q", h, body)
+	}
+
+	bad := buf.Bytes()
+	bad[0] = 0
+	if _, _, err := zanop2p.ReadPacket(bytes.NewReader(bad)); err == nil {
+		t.Errorf("invalid signature accepted")
+	}
+}
@@ -0,0 +1,105 @@
+package zanop2p
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Levin packet header constants
+const (
+	LevinSignature       = 0x0101010101012101
+	LevinProtocolVersion = 1
+	LevinHeaderSize      = 33
+
+	LevinPacketRequest  = 1 // flag set on requests and notifications
+	LevinPacketResponse = 2 // flag set on responses
+
+	// MaxPacketSize is the maximum accepted body size, as LEVIN_DEFAULT_MAX_PACKET_SIZE
+	MaxPacketSize = 100000000
+)
+
+// Levin return codes
+const (
+	LevinOk                     = 0
+	LevinErrorHandlerNotDefined = -6
+	LevinErrorFormat            = -7
+)
+
+// Command ids of the p2p (P2P_COMMANDS_POOL_BASE) and core protocol (BC_COMMANDS_POOL_BASE)
+const (
+	CommandHandshake = 1001
+	CommandTimedSync = 1002
+	CommandPing      = 1003
+
+	NotifyNewBlock           = 2001
+	NotifyNewTransactions    = 2002
+	NotifyRequestGetObjects  = 2003
+	NotifyResponseGetObjects = 2004
+	NotifyRequestChain       = 2006
+	NotifyResponseChainEntry = 2007
+)
+
+// Header is a levin packet header (bucket_head2)
+type Header struct {
+	Size            uint64 // size of the body following the header
+	HaveToReturn    bool   // true for requests expecting a response
+	Command         uint32
+	ReturnCode      int32
+	Flags           uint32
+	ProtocolVersion uint32
+}
+
+// IsResponse returns true if the packet is a response to a request
+func (h *Header) IsResponse() bool {
+	return h.Flags&LevinPacketResponse != 0
+}
+
+// ReadPacket reads a levin packet from r and returns its header and body
+func ReadPacket(r io.Reader) (*Header, []byte, error) {
+	var buf [LevinHeaderSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return nil, nil, err
+	}
+	if binary.LittleEndian.Uint64(buf[:8]) != LevinSignature {
+		return nil, nil, errors.New("levin: invalid signature")
+	}
+	h := &Header{
+		Size:            binary.LittleEndian.Uint64(buf[8:16]),
+		HaveToReturn:    buf[16] != 0,
+		Command:         binary.LittleEndian.Uint32(buf[17:21]),
+		ReturnCode:      int32(binary.LittleEndian.Uint32(buf[21:25])),
+		Flags:           binary.LittleEndian.Uint32(buf[25:29]),
+		ProtocolVersion: binary.LittleEndian.Uint32(buf[29:33]),
+	}
+	if h.Size > MaxPacketSize {
+		return nil, nil, fmt.Errorf("levin: packet of %d bytes is too large", h.Size)
+	}
+	body := make([]byte, h.Size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, nil, err
+	}
+	return h, body, nil
+}
+
+// WritePacket writes a levin packet with the given header and body to w. Size is set
+// from body, and ProtocolVersion defaults to LevinProtocolVersion.
+func WritePacket(w io.Writer, h *Header, body []byte) error {
+	version := h.ProtocolVersion
+	if version == 0 {
+		version = LevinProtocolVersion
+	}
+	buf := make([]byte, LevinHeaderSize, LevinHeaderSize+len(body))
+	binary.LittleEndian.PutUint64(buf[:8], LevinSignature)
+	binary.LittleEndian.PutUint64(buf[8:16], uint64(len(body)))
+	if h.HaveToReturn {
+		buf[16] = 1
+	}
+	binary.LittleEndian.PutUint32(buf[17:21], h.Command)
+	binary.LittleEndian.PutUint32(buf[21:25], uint32(h.ReturnCode))
+	binary.LittleEndian.PutUint32(buf[25:29], h.Flags)
+	binary.LittleEndian.PutUint32(buf[29:33], version)
+	_, err := w.Write(append(buf, body...))
+	return err
+}
@@ -0,0 +1,167 @@
+package zanop2p
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+// NodeData identifies a node during handshake (basic_node_data)
+type NodeData struct {
+	NetworkId [16]byte `storage:"network_id"`
+	PeerId    uint64   `storage:"peer_id"`
+	LocalTime int64    `storage:"local_time"`
+	MyPort    uint32   `storage:"my_port"`
+}
+
+// CoreSyncData describes the chain state of a node (CORE_SYNC_DATA)
+type CoreSyncData struct {
+	CurrentHeight         uint64            `storage:"current_height"`
+	TopId                 zanobase.Value256 `storage:"top_id"`
+	LastCheckpointHeight  uint64            `storage:"last_checkpoint_height"`
+	CoreTime              uint64            `storage:"core_time"`
+	ClientVersion         string            `storage:"client_version"`
+	NonPruningModeEnabled bool              `storage:"non_pruning_mode_enabled"`
+}
+
+// PeerEntry is an entry of a peer list exchanged during handshake and timed sync
+type PeerEntry struct {
+	IP       net.IP
+	Port     uint32
+	Id       uint64
+	LastSeen int64
+}
+
+// PeerlistEntrySize is the size of a packed peerlist_entry
+const PeerlistEntrySize = 24
+
+// ParsePeerlist parses packed peerlist entries
+func ParsePeerlist(list [][PeerlistEntrySize]byte) []*PeerEntry {
+	res := make([]*PeerEntry, len(list))
+	for i, e := range list {
+		res[i] = &PeerEntry{
+			IP:       net.IPv4(e[0], e[1], e[2], e[3]),
+			Port:     binary.LittleEndian.Uint32(e[4:8]),
+			Id:       binary.LittleEndian.Uint64(e[8:16]),
+			LastSeen: int64(binary.LittleEndian.Uint64(e[16:24])),
+		}
+	}
+	return res
+}
+
+// Bytes returns the packed representation of p
+func (p *PeerEntry) Bytes() (res [PeerlistEntrySize]byte) {
+	copy(res[:4], p.IP.To4())
+	binary.LittleEndian.PutUint32(res[4:8], p.Port)
+	binary.LittleEndian.PutUint64(res[8:16], p.Id)
+	binary.LittleEndian.PutUint64(res[16:24], uint64(p.LastSeen))
+	return
+}
+
+// HandshakeRequest is the request of COMMAND_HANDSHAKE
+type HandshakeRequest struct {
+	NodeData    *NodeData     `storage:"node_data"`
+	PayloadData *CoreSyncData `storage:"payload_data"`
+}
+
+// HandshakeResponse is the response of COMMAND_HANDSHAKE
+type HandshakeResponse struct {
+	NodeData      *NodeData                 `storage:"node_data"`
+	PayloadData   *CoreSyncData             `storage:"payload_data"`
+	LocalPeerlist [][PeerlistEntrySize]byte `storage:"local_peerlist,blob"`
+}
+
+// TimedSyncRequest is the request of COMMAND_TIMED_SYNC
+type TimedSyncRequest struct {
+	PayloadData *CoreSyncData `storage:"payload_data"`
+}
+
+// TimedSyncResponse is the response of COMMAND_TIMED_SYNC
+type TimedSyncResponse struct {
+	LocalTime     int64                     `storage:"local_time"`
+	PayloadData   *CoreSyncData             `storage:"payload_data"`
+	LocalPeerlist [][PeerlistEntrySize]byte `storage:"local_peerlist,blob"`
+}
+
+// PingResponse is the response of COMMAND_PING
+type PingResponse struct {
+	Status string `storage:"status"`
+	PeerId uint64 `storage:"peer_id"`
+}
+
+// RequestGetObjects is the body of NOTIFY_REQUEST_GET_OBJECTS
+type RequestGetObjects struct {
+	Txs    []zanobase.Value256 `storage:"txs,blob"`
+	Blocks []zanobase.Value256 `storage:"blocks,blob"`
+}
+
+// BlockCompleteEntry is a block and its transactions in their binary form
+// (block_complete_entry)
+type BlockCompleteEntry struct {
+	Block []byte   `storage:"block"`
+	Txs   [][]byte `storage:"txs"`
+}
+
+// ResponseGetObjects is the body of NOTIFY_RESPONSE_GET_OBJECTS
+type ResponseGetObjects struct {
+	Txs                     [][]byte              `storage:"txs"`
+	Blocks                  []*BlockCompleteEntry `storage:"blocks"`
+	MissedIds               []zanobase.Value256   `storage:"missed_ids,blob"`
+	CurrentBlockchainHeight uint64                `storage:"current_blockchain_height"`
+}
+
+// NewTransactions is the body of NOTIFY_NEW_TRANSACTIONS
+type NewTransactions struct {
+	Txs [][]byte `storage:"txs"`
+}
+
+// Block is a parsed block and its transactions
+type Block struct {
+	*zanobase.Block
+	Txs []*zanobase.Transaction
+}
+
+// Parse parses the block and its transactions
+func (e *BlockCompleteEntry) Parse() (*Block, error) {
+	blk, err := zanobase.ParseBlock(e.Block)
+	if err != nil {
+		return nil, fmt.Errorf("while parsing block: %w", err)
+	}
+	res := &Block{Block: blk, Txs: make([]*zanobase.Transaction, len(e.Txs))}
+	for i, buf := range e.Txs {
+		if res.Txs[i], err = zanobase.ParseTransaction(buf); err != nil {
+			return nil, fmt.Errorf("while parsing transaction %d: %w", i, err)
+		}
+	}
+	return res, nil
+}
+
+// Objects is the parsed result of GetObjects
+type Objects struct {
+	Blocks        []*Block
+	Txs           []*zanobase.Transaction
+	MissedIds     []zanobase.Value256
+	CurrentHeight uint64
+}
+
+// Parse parses the blocks and transactions of the response
+func (r *ResponseGetObjects) Parse() (*Objects, error) {
+	res := &Objects{MissedIds: r.MissedIds, CurrentHeight: r.CurrentBlockchainHeight}
+	for i, e := range r.Blocks {
+		blk, err := e.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		res.Blocks = append(res.Blocks, blk)
+	}
+	for i, buf := range r.Txs {
+		tx, err := zanobase.ParseTransaction(buf)
+		if err != nil {
+			return nil, fmt.Errorf("while parsing transaction %d: %w", i, err)
+		}
+		res.Txs = append(res.Txs, tx)
+	}
+	return res, nil
+}
@@ -26,7 +26,8 @@ func GenerateTxBalanceProof(rnd io.Reader, tx *zanobase.Transaction, contextHash
 	}
 
 	fee, ok := tx.GetFee()
-	if !ok {
+	if !ok && !tx.IsCoinbase() {
+		// miner transactions have no fee
 		return errors.New("unable to get tx fee")
 	}
 
@@ -0,0 +1,69 @@
+package zanorpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+// GetOutputIndexes returns the global indexes of the outputs of the given transaction,
+// using the get_o_indexes.bin endpoint
+func (c *Client) GetOutputIndexes(ctx context.Context, txId zanobase.Value256) ([]uint64, error) {
+	req := struct {
+		TxId zanobase.Value256 `storage:"txid"`
+	}{txId}
+	var res struct {
+		Response
+		Indexes []uint64 `storage:"o_indexes"`
+	}
+	if err := c.CallBinary(ctx, "/get_o_indexes.bin", &req, &res); err != nil {
+		return nil, err
+	}
+	return res.Indexes, nil
+}
+
+// BlockEntry is a block and its transactions as returned by getblocks.bin
+// (block_complete_entry), in their binary form
+type BlockEntry struct {
+	Block []byte   `storage:"block"`
+	Txs   [][]byte `storage:"txs"`
+}
+
+// Parse parses the block and its transactions
+func (e *BlockEntry) Parse() (*zanobase.Block, []*zanobase.Transaction, error) {
+	blk, err := zanobase.ParseBlock(e.Block)
+	if err != nil {
+		return nil, nil, fmt.Errorf("while parsing block: %w", err)
+	}
+	txs := make([]*zanobase.Transaction, len(e.Txs))
+	for i, buf := range e.Txs {
+		if txs[i], err = zanobase.ParseTransaction(buf); err != nil {
+			return nil, nil, fmt.Errorf("while parsing transaction %d: %w", i, err)
+		}
+	}
+	return blk, txs, nil
+}
+
+// Blocks is the result of GetBlocks
+type Blocks struct {
+	Response
+	Blocks        []*BlockEntry `storage:"blocks"`
+	StartHeight   uint64        `storage:"start_height"`
+	CurrentHeight uint64        `storage:"current_height"`
+}
+
+// GetBlocks returns the blocks following the most recent of blockIds known to the daemon,
+// using the getblocks.bin endpoint. blockIds lists known block ids, from the most recent
+// to the genesis block, as in a wallet's short chain history.
+func (c *Client) GetBlocks(ctx context.Context, blockIds []zanobase.Value256, minimumHeight uint64) (*Blocks, error) {
+	req := struct {
+		BlockIds      []zanobase.Value256 `storage:"block_ids,blob"`
+		MinimumHeight uint64              `storage:"minimum_height"`
+	}{blockIds, minimumHeight}
+	res := &Blocks{}
+	if err := c.CallBinary(ctx, "/getblocks.bin", &req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
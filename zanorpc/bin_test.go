@@ -0,0 +1,102 @@
+package zanorpc_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanoepee"
+	"github.com/ModChain/zanolib/zanorpc"
+)
+
+// fakeBinDaemon answers binary requests locally using handler, which receives the uri and
+// the decoded request
+func fakeBinDaemon(t *testing.T, handler func(path string, req zanoepee.Section) any) *zanorpc.Client {
+	c := zanorpc.New("http://zanod.local")
+	c.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		buf, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		var body zanoepee.Section
+		if err := zanoepee.Unmarshal(buf, &body); err != nil {
+			t.Fatalf("invalid request: %s", err)
+		}
+		res, err := zanoepee.Marshal(handler(req.URL.Path, body))
+		if err != nil {
+			t.Fatalf("failed to encode response: %s", err)
+		}
+		rec := httptest.NewRecorder()
+		rec.Write(res)
+		return rec.Result(), nil
+	})
+	return c
+}
+
+func TestBinaryEndpoints(t *testing.T) {
+	blk := &zanobase.Block{
+		BlockHeader: zanobase.BlockHeader{MajorVersion: 3, Timestamp: 1700000000, Nonce: 42},
+		MinerTx:     &zanobase.Transaction{Version: 2},
+	}
+	blkBlob, err := blk.Bytes()
+	if err != nil {
+		t.Fatalf("failed to serialize block: %s", err)
+	}
+	txBlob, err := (&zanobase.Transaction{Version: 2}).Bytes()
+	if err != nil {
+		t.Fatalf("failed to serialize tx: %s", err)
+	}
+	txId := zanobase.Value256{1, 2, 3}
+
+	c := fakeBinDaemon(t, func(path string, req zanoepee.Section) any {
+		switch path {
+		case "/get_o_indexes.bin":
+			if req["txid"] != string(txId[:]) {
+				t.Errorf("unexpected txid %x", req["txid"])
+			}
+			return zanoepee.Section{"o_indexes": []uint64{10, 11}, "status": "OK"}
+		case "/getblocks.bin":
+			if ids := req["block_ids"].(string); len(ids) != 64 || ids[32] != 9 {
+				t.Errorf("unexpected block ids %x", ids)
+			}
+			return zanoepee.Section{
+				"blocks":         []zanoepee.Section{{"block": blkBlob, "txs": [][]byte{txBlob}}},
+				"start_height":   uint64(100),
+				"current_height": uint64(200),
+				"status":         "OK",
+			}
+		}
+		return zanoepee.Section{"status": "BUSY"}
+	})
+	ctx := context.Background()
+
+	idx, err := c.GetOutputIndexes(ctx, txId)
+	if err != nil {
+		t.Fatalf("GetOutputIndexes failed: %s", err)
+	}
+	if len(idx) != 2 || idx[0] != 10 || idx[1] != 11 {
+		t.Errorf("unexpected output indexes %v", idx)
+	}
+
+	res, err := c.GetBlocks(ctx, []zanobase.Value256{{1}, {9}}, 0)
+	if err != nil {
+		t.Fatalf("GetBlocks failed: %s", err)
+	}
+	if res.StartHeight != 100 || res.CurrentHeight != 200 || len(res.Blocks) != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	b, txs, err := res.Blocks[0].Parse()
+	if err != nil {
+		t.Fatalf("failed to parse block: %s", err)
+	}
+	if b.Nonce != 42 || b.Timestamp != 1700000000 || len(txs) != 1 || txs[0].Version != 2 {
+		t.Errorf("unexpected block %+v", b)
+	}
+
+	if err := c.CallBinary(ctx, "/other.bin", zanoepee.Section{}, &zanorpc.Response{}); err == nil {
+		t.Errorf("non OK status should be an error")
+	}
+}
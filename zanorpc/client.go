@@ -0,0 +1,165 @@
+package zanorpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ModChain/zanolib/zanoepee"
+)
+
+// MaxResponseSize is the maximum size of a response read from the daemon
+const MaxResponseSize = 256 << 20
+
+// Client is a client for zanod's JSON-RPC and HTTP API
+type Client struct {
+	URL       string            // base url of the daemon, for example http://127.0.0.1:11211
+	Transport http.RoundTripper // if nil, http.DefaultTransport is used
+
+	id atomic.Uint64
+}
+
+// New returns a Client for the daemon at the given base url
+func New(url string) *Client {
+	return &Client{URL: strings.TrimSuffix(url, "/")}
+}
+
+// Error is an error returned by the daemon
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// Response holds the status field found in all daemon responses
+type Response struct {
+	Status string `json:"status" storage:"status"`
+}
+
+func (r *Response) checkStatus() error {
+	if r.Status != "" && r.Status != "OK" {
+		return fmt.Errorf("daemon returned status %s", r.Status)
+	}
+	return nil
+}
+
+type statusChecker interface {
+	checkStatus() error
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	Id      uint64 `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// Call performs a JSON-RPC call to the json_rpc endpoint and decodes its result into res
+func (c *Client) Call(ctx context.Context, method string, params, res any) error {
+	req := &rpcRequest{JSONRPC: "2.0", Id: c.id.Add(1), Method: method, Params: params}
+	var resp rpcResponse
+	if err := c.post(ctx, "/json_rpc", req, &resp); err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return decodeResult(resp.Result, res)
+}
+
+// CallURI performs a call to one of the daemon's plain JSON endpoints, such as
+// /sendrawtransaction, and decodes the response into res
+func (c *Client) CallURI(ctx context.Context, path string, params, res any) error {
+	var raw json.RawMessage
+	if err := c.post(ctx, path, params, &raw); err != nil {
+		return err
+	}
+	return decodeResult(raw, res)
+}
+
+func decodeResult(raw json.RawMessage, res any) error {
+	if res == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, res); err != nil {
+		return fmt.Errorf("while decoding response: %w", err)
+	}
+	if s, ok := res.(statusChecker); ok {
+		return s.checkStatus()
+	}
+	return nil
+}
+
+// CallBinary performs a call to one of the daemon's binary endpoints, such as
+// /getblocks.bin, with req and res encoded in epee portable storage
+func (c *Client) CallBinary(ctx context.Context, path string, req, res any) error {
+	buf, err := zanoepee.Marshal(req)
+	if err != nil {
+		return err
+	}
+	buf, err = c.do(ctx, path, "application/octet-stream", buf)
+	if err != nil {
+		return err
+	}
+	if err := zanoepee.Unmarshal(buf, res); err != nil {
+		return fmt.Errorf("while decoding response: %w", err)
+	}
+	if s, ok := res.(statusChecker); ok {
+		return s.checkStatus()
+	}
+	return nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body, res any) error {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	buf, err = c.do(ctx, path, "application/json", buf)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, res)
+}
+
+// do posts body to the given path and returns the response body
+func (c *Client) do(ctx context.Context, path, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	hc := &http.Client{Transport: c.Transport}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("http error %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, MaxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) > MaxResponseSize {
+		return nil, fmt.Errorf("response exceeds %d bytes", MaxResponseSize)
+	}
+	return buf, nil
+}
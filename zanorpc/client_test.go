@@ -0,0 +1,200 @@
+package zanorpc_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+	"github.com/ModChain/zanolib/zanorpc"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// fakeDaemon answers requests locally using handler, which receives the json-rpc method
+// (or the uri for plain endpoints) and the params
+func fakeDaemon(t *testing.T, handler func(method string, params map[string]any) any) *zanorpc.Client {
+	c := zanorpc.New("http://zanod.local/")
+	c.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		var body map[string]any
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Fatalf("invalid request: %s", err)
+		}
+		var res any
+		if req.URL.Path == "/json_rpc" {
+			params, _ := body["params"].(map[string]any)
+			res = map[string]any{"jsonrpc": "2.0", "id": body["id"], "result": handler(body["method"].(string), params)}
+			if e, ok := res.(map[string]any)["result"].(*zanorpc.Error); ok {
+				res = map[string]any{"jsonrpc": "2.0", "id": body["id"], "error": e}
+			}
+		} else {
+			res = handler(req.URL.Path, body)
+		}
+		rec := httptest.NewRecorder()
+		json.NewEncoder(rec).Encode(res)
+		return rec.Result(), nil
+	})
+	return c
+}
+
+func TestDaemonClient(t *testing.T) {
+	tx := &zanobase.Transaction{Version: 2}
+	blob, err := tx.Bytes()
+	if err != nil {
+		t.Fatalf("failed to serialize tx: %s", err)
+	}
+	var sent string
+	c := fakeDaemon(t, func(method string, params map[string]any) any {
+		switch method {
+		case "getinfo":
+			return map[string]any{"status": "OK", "height": 1234, "is_hardfok_active": []bool{true, true}}
+		case "get_tx_details":
+			return map[string]any{"status": "OK", "tx_info": map[string]any{"blob": blob, "id": params["tx_hash"], "outs": []any{map[string]any{"global_index": 42}}}}
+		case "get_asset_info":
+			return map[string]any{"status": "NOT_FOUND"}
+		case "getblockheaderbyheight":
+			return &zanorpc.Error{Code: -2, Message: "too big height"}
+		case "/sendrawtransaction":
+			sent = params["tx_as_hex"].(string)
+			return map[string]any{"status": "OK"}
+		}
+		return &zanorpc.Error{Code: -32601, Message: "method not found"}
+	})
+	ctx := context.Background()
+
+	info, err := c.GetInfo(ctx)
+	if err != nil {
+		t.Fatalf("getinfo failed: %s", err)
+	}
+	if info.Height != 1234 || len(info.IsHardforkActive) != 2 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	txId := zanobase.Value256{1, 2, 3}
+	txi, err := c.GetTxDetails(ctx, txId)
+	if err != nil {
+		t.Fatalf("get_tx_details failed: %s", err)
+	}
+	if txi.Id != txId || txi.GlobalIndexes()[0] != 42 {
+		t.Errorf("unexpected tx info: %+v", txi)
+	}
+	if got, err := txi.Transaction(); err != nil || got.Version != 2 {
+		t.Errorf("failed to decode transaction: %v", err)
+	}
+
+	if _, err := c.GetAssetInfo(ctx, zanolib.NativeCoinAssetId); err == nil {
+		t.Errorf("non OK status should fail")
+	}
+	var rpcErr *zanorpc.Error
+	if _, err := c.GetBlockHeaderByHeight(ctx, 1<<40); !errors.As(err, &rpcErr) || rpcErr.Code != -2 {
+		t.Errorf("expected rpc error, got %v", err)
+	}
+
+	if err := c.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("send failed: %s", err)
+	}
+	if sent != hex.EncodeToString(blob) {
+		t.Errorf("unexpected sent blob %s", sent)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.GetInfo(cctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled context error, got %v", err)
+	}
+}
+
+func TestDecoys(t *testing.T) {
+	pt := hex.EncodeToString(new(edwards25519.Point).ScalarBaseMult(zanocrypto.ScalarInt(3)).Bytes())
+	const hf4Time = 1710000000
+
+	// 5000 zarcanum outputs over 50000 seconds, every 7th output is omitted by the daemon
+	c := fakeDaemon(t, func(method string, params map[string]any) any {
+		switch method {
+		case "getinfo":
+			return map[string]any{"status": "OK", "height": 2560001}
+		case "get_blocks_details":
+			if params["height_start"] != 2560000.0 || params["ignore_transactions"] != false {
+				t.Errorf("unexpected get_blocks_details params %v", params)
+			}
+			outs := []any{map[string]any{"amount": 0, "global_index": 4998}, map[string]any{"amount": 0, "global_index": 4999}}
+			return map[string]any{"status": "OK", "blocks": []any{map[string]any{"height": 2560000, "timestamp": hf4Time + 50000, "transactions_details": []any{map[string]any{"outs": outs}}}}}
+		case "getblockheaderbyheight":
+			if params["height"] != float64(zanolib.HF4Height) {
+				t.Errorf("time span should start at HF4, got height %v", params["height"])
+			}
+			return map[string]any{"status": "OK", "block_header": map[string]any{"height": zanolib.HF4Height, "timestamp": hf4Time}}
+		case "getrandom_outs3":
+			if params["height_upper_limit"] != 2559990.0 || params["use_forced_mix_outs"] != false || params["coinbase_percents"] != 0.0 {
+				t.Errorf("unexpected getrandom_outs3 params %v", params)
+			}
+			amounts := params["amounts"].([]any)
+			req := amounts[0].(map[string]any)
+			if len(amounts) != 1 || req["amount"] != 0.0 {
+				t.Errorf("unexpected getrandom_outs3 amounts %v", amounts)
+			}
+			outs := []any{}
+			for _, o := range req["global_offsets"].([]any) {
+				if idx := uint64(o.(float64)); idx%7 != 0 {
+					outs = append(outs, map[string]any{"global_amount_index": idx, "stealth_address": pt, "concealing_point": pt, "amount_commitment": pt, "blinded_asset_id": pt, "flags": 0})
+				}
+			}
+			return map[string]any{"status": "OK", "outs": []any{map[string]any{"amount": 0, "outs": outs}}}
+		}
+		return &zanorpc.Error{Code: -32601, Message: "method not found"}
+	})
+
+	d := &zanorpc.Decoys{Client: c, HeightLimit: 2559990}
+	if n, err := d.OutputCount(); err != nil || n != 5000 {
+		t.Errorf("output count = %d, err = %v", n, err)
+	}
+	if span, err := d.TimeSpan(); err != nil || span != 50000*time.Second {
+		t.Errorf("time span = %s, err = %v", span, err)
+	}
+
+	owned := &zanolib.OwnedOutput{GlobalIndex: 4990}
+	for _, v := range []*zanobase.Value256{&owned.TxPubKey, &owned.StealthAddress, &owned.ConcealingPoint, &owned.AmountCommitment, &owned.BlindedAssetId} {
+		hex.Decode(v[:], []byte(pt))
+	}
+	ring, realOut, err := zanolib.BuildRing(d, owned, zanolib.DefaultRingSize)
+	if err != nil {
+		t.Fatalf("failed to build ring: %s", err)
+	}
+	if len(ring) != zanolib.DefaultRingSize || zanobase.VariantAs[uint64](ring[realOut].OutReference) != 4990 {
+		t.Fatalf("invalid ring of %d members", len(ring))
+	}
+	for n, e := range ring {
+		if idx := zanobase.VariantAs[uint64](e.OutReference); n != realOut && (idx%7 == 0 || idx >= 5000) {
+			t.Errorf("unusable decoy %d selected", idx)
+		}
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	c := zanorpc.New("http://zanod.local")
+	c.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(http.StatusForbidden)
+		io.WriteString(rec, "forbidden")
+		return rec.Result(), nil
+	})
+	if _, err := c.GetInfo(context.Background()); err == nil {
+		t.Errorf("http errors should be reported")
+	}
+}
@@ -0,0 +1,280 @@
+package zanorpc
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/ModChain/zanolib/zanobase"
+)
+
+// KeyImageUnspent is the status returned by CheckKeyImages for key images not found on chain
+const KeyImageUnspent = 0
+
+// Info is the result of getinfo
+type Info struct {
+	Response
+	Height                   uint64            `json:"height"`
+	TxCount                  uint64            `json:"tx_count"`
+	TxPoolSize               uint64            `json:"tx_pool_size"`
+	AltBlocksCount           uint64            `json:"alt_blocks_count"`
+	OutgoingConnectionsCount uint64            `json:"outgoing_connections_count"`
+	IncomingConnectionsCount uint64            `json:"incoming_connections_count"`
+	DaemonNetworkState       uint64            `json:"daemon_network_state"`
+	MaxNetSeenHeight         uint64            `json:"max_net_seen_height"`
+	PowDifficulty            uint64            `json:"pow_difficulty"`
+	PosDifficulty            string            `json:"pos_difficulty"`
+	DefaultFee               uint64            `json:"default_fee"`
+	MinimumFee               uint64            `json:"minimum_fee"`
+	LastBlockHash            zanobase.Value256 `json:"last_block_hash"`
+	LastBlockTimestamp       uint64            `json:"last_block_timestamp"`
+	IsHardforkActive         []bool            `json:"is_hardfok_active"` // sic
+	TotalCoins               string            `json:"total_coins"`
+}
+
+// GetInfo returns general information about the daemon and the chain
+func (c *Client) GetInfo(ctx context.Context) (*Info, error) {
+	res := new(Info)
+	return res, c.Call(ctx, "getinfo", map[string]any{"flags": 0}, res)
+}
+
+// BlockHeader is a block header as returned by the daemon
+type BlockHeader struct {
+	MajorVersion uint8             `json:"major_version"`
+	MinorVersion uint8             `json:"minor_version"`
+	Timestamp    uint64            `json:"timestamp"`
+	PrevHash     zanobase.Value256 `json:"prev_hash"`
+	Nonce        uint64            `json:"nonce"`
+	OrphanStatus bool              `json:"orphan_status"`
+	Height       uint64            `json:"height"`
+	Depth        uint64            `json:"depth"`
+	Hash         zanobase.Value256 `json:"hash"`
+	Difficulty   string            `json:"difficulty"`
+	Reward       uint64            `json:"reward"`
+}
+
+// GetBlockHeaderByHeight returns the header of the block at the given height
+func (c *Client) GetBlockHeaderByHeight(ctx context.Context, height uint64) (*BlockHeader, error) {
+	var res struct {
+		Response
+		BlockHeader *BlockHeader `json:"block_header"`
+	}
+	if err := c.Call(ctx, "getblockheaderbyheight", map[string]any{"height": height}, &res); err != nil {
+		return nil, err
+	}
+	if res.BlockHeader == nil {
+		return nil, errors.New("missing block header in response")
+	}
+	return res.BlockHeader, nil
+}
+
+// TxOutInfo is an output in TxInfo
+type TxOutInfo struct {
+	Amount      uint64 `json:"amount"`
+	GlobalIndex uint64 `json:"global_index"`
+	IsSpent     bool   `json:"is_spent"`
+}
+
+// TxInfo holds the details of a transaction (tx_rpc_extended_info)
+type TxInfo struct {
+	Blob        []byte            `json:"blob"` // base64 in json
+	BlobSize    uint64            `json:"blob_size"`
+	Fee         uint64            `json:"fee"`
+	Id          zanobase.Value256 `json:"id"`
+	Timestamp   uint64            `json:"timestamp"`
+	KeeperBlock int64             `json:"keeper_block"` // height of the block holding the transaction, -1 if in the pool
+	Amount      uint64            `json:"amount"`
+	PubKey      string            `json:"pub_key"`
+	Outs        []*TxOutInfo      `json:"outs"`
+}
+
+// Transaction decodes the transaction blob
+func (t *TxInfo) Transaction() (*zanobase.Transaction, error) {
+	if len(t.Blob) == 0 {
+		return nil, errors.New("transaction has no blob")
+	}
+	return zanobase.ParseTransaction(t.Blob)
+}
+
+// GlobalIndexes returns the global index of each output of the transaction
+func (t *TxInfo) GlobalIndexes() []uint64 {
+	res := make([]uint64, len(t.Outs))
+	for n, o := range t.Outs {
+		res[n] = o.GlobalIndex
+	}
+	return res
+}
+
+// GetTxDetails returns the details of a transaction, including its blob
+func (c *Client) GetTxDetails(ctx context.Context, txId zanobase.Value256) (*TxInfo, error) {
+	var res struct {
+		Response
+		TxInfo *TxInfo `json:"tx_info"`
+	}
+	if err := c.Call(ctx, "get_tx_details", map[string]any{"tx_hash": txId}, &res); err != nil {
+		return nil, err
+	}
+	if res.TxInfo == nil {
+		return nil, errors.New("missing transaction in response")
+	}
+	return res.TxInfo, nil
+}
+
+// GetTransaction returns the transaction with the given id
+func (c *Client) GetTransaction(ctx context.Context, txId zanobase.Value256) (*zanobase.Transaction, error) {
+	info, err := c.GetTxDetails(ctx, txId)
+	if err != nil {
+		return nil, err
+	}
+	return info.Transaction()
+}
+
+// BlockDetails holds the details of a block (block_rpc_extended_info)
+type BlockDetails struct {
+	Height              uint64            `json:"height"`
+	Id                  zanobase.Value256 `json:"id"`
+	PrevId              zanobase.Value256 `json:"prev_id"`
+	Timestamp           uint64            `json:"timestamp"`
+	ActualTimestamp     uint64            `json:"actual_timestamp"`
+	Type                uint64            `json:"type"` // 0 = PoS, 1 = PoW
+	IsOrphan            bool              `json:"is_orphan"`
+	BaseReward          uint64            `json:"base_reward"`
+	SummaryReward       uint64            `json:"summary_reward"`
+	TotalFee            uint64            `json:"total_fee"`
+	BlockCumulativeSize uint64            `json:"block_cumulative_size"`
+	Difficulty          string            `json:"difficulty"`
+	TransactionsDetails []*TxInfo         `json:"transactions_details"`
+}
+
+// GetBlocksDetails returns the details of count blocks starting at the given height. If
+// withTransactions is false, TransactionsDetails is not filled.
+func (c *Client) GetBlocksDetails(ctx context.Context, start, count uint64, withTransactions bool) ([]*BlockDetails, error) {
+	var res struct {
+		Response
+		Blocks []*BlockDetails `json:"blocks"`
+	}
+	params := map[string]any{"height_start": start, "count": count, "ignore_transactions": !withTransactions}
+	if err := c.Call(ctx, "get_blocks_details", params, &res); err != nil {
+		return nil, err
+	}
+	return res.Blocks, nil
+}
+
+// RandomOut is an output returned by GetRandomOuts, usable as a decoy
+type RandomOut struct {
+	GlobalAmountIndex uint64            `json:"global_amount_index"`
+	StealthAddress    zanobase.Value256 `json:"stealth_address"`
+	ConcealingPoint   zanobase.Value256 `json:"concealing_point"`
+	AmountCommitment  zanobase.Value256 `json:"amount_commitment"`
+	BlindedAssetId    zanobase.Value256 `json:"blinded_asset_id"`
+	Flags             uint8             `json:"flags"`
+}
+
+// RandomOutsForAmount lists the outputs returned for a given amount (0 for zarcanum outputs)
+type RandomOutsForAmount struct {
+	Amount uint64       `json:"amount"`
+	Outs   []*RandomOut `json:"outs"`
+}
+
+// OffsetsDistribution lists the global indices of the outputs requested for an amount in
+// GetRandomOuts (offsets_distribution)
+type OffsetsDistribution struct {
+	Amount        uint64   `json:"amount"`
+	GlobalOffsets []uint64 `json:"global_offsets"`
+}
+
+// GetRandomOuts calls getrandom_outs3, which returns the outputs at the global indices
+// chosen by the caller for each amount, among outputs below heightLimit. The daemon may
+// omit outputs that cannot be used as decoys.
+func (c *Client) GetRandomOuts(ctx context.Context, amounts []*OffsetsDistribution, heightLimit uint64) ([]*RandomOutsForAmount, error) {
+	var res struct {
+		Response
+		Outs []*RandomOutsForAmount `json:"outs"`
+	}
+	params := map[string]any{
+		"amounts":             amounts,
+		"height_upper_limit":  heightLimit,
+		"use_forced_mix_outs": false,
+		"coinbase_percents":   0,
+	}
+	if err := c.Call(ctx, "getrandom_outs3", params, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Outs) != len(amounts) {
+		return nil, fmt.Errorf("daemon returned outputs for %d amounts, expected %d", len(res.Outs), len(amounts))
+	}
+	return res.Outs, nil
+}
+
+// OutInfo locates an output by its global index
+type OutInfo struct {
+	Response
+	TxId  zanobase.Value256 `json:"tx_id"`
+	OutNo uint64            `json:"out_no"`
+}
+
+// GetOutInfo returns the transaction and output index of the output with the given amount
+// (0 for zarcanum outputs) and global index
+func (c *Client) GetOutInfo(ctx context.Context, amount, globalIndex uint64) (*OutInfo, error) {
+	res := new(OutInfo)
+	return res, c.Call(ctx, "get_out_info", map[string]any{"amount": amount, "i": globalIndex}, res)
+}
+
+// CheckKeyImages returns the status of each key image: KeyImageUnspent if it was not
+// found on chain, otherwise the height at which it was spent
+func (c *Client) CheckKeyImages(ctx context.Context, images []zanobase.Value256) ([]uint64, error) {
+	var res struct {
+		Response
+		ImagesStat []uint64 `json:"images_stat"`
+	}
+	if err := c.Call(ctx, "check_keyimages", map[string]any{"images": images}, &res); err != nil {
+		return nil, err
+	}
+	if len(res.ImagesStat) != len(images) {
+		return nil, fmt.Errorf("daemon returned %d statuses, expected %d", len(res.ImagesStat), len(images))
+	}
+	return res.ImagesStat, nil
+}
+
+// SendRawTransaction submits a signed transaction blob to the network
+func (c *Client) SendRawTransaction(ctx context.Context, blob []byte) error {
+	var res Response
+	return c.CallURI(ctx, "/sendrawtransaction", map[string]any{"tx_as_hex": hex.EncodeToString(blob)}, &res)
+}
+
+// SendTransaction serializes and submits a signed transaction
+func (c *Client) SendTransaction(ctx context.Context, tx *zanobase.Transaction) error {
+	blob, err := tx.Bytes()
+	if err != nil {
+		return err
+	}
+	return c.SendRawTransaction(ctx, blob)
+}
+
+// AssetDescriptor describes an asset (asset_descriptor_base)
+type AssetDescriptor struct {
+	TotalMaxSupply uint64            `json:"total_max_supply"`
+	CurrentSupply  uint64            `json:"current_supply"`
+	DecimalPoint   uint8             `json:"decimal_point"`
+	Ticker         string            `json:"ticker"`
+	FullName       string            `json:"full_name"`
+	MetaInfo       string            `json:"meta_info"`
+	Owner          zanobase.Value256 `json:"owner"`
+	HiddenSupply   bool              `json:"hidden_supply"`
+}
+
+// GetAssetInfo returns the descriptor of the asset with the given id (hex)
+func (c *Client) GetAssetInfo(ctx context.Context, assetId string) (*AssetDescriptor, error) {
+	var res struct {
+		Response
+		AssetDescriptor *AssetDescriptor `json:"asset_descriptor"`
+	}
+	if err := c.Call(ctx, "get_asset_info", map[string]any{"asset_id": assetId}, &res); err != nil {
+		return nil, err
+	}
+	if res.AssetDescriptor == nil {
+		return nil, errors.New("missing asset descriptor in response")
+	}
+	return res.AssetDescriptor, nil
+}
@@ -0,0 +1,126 @@
+package zanorpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ModChain/zanolib"
+)
+
+// Decoys is a zanolib.RingProvider picking decoys with zanolib.DecoySelector, using the
+// daemon as its zanolib.OutputIndexSource
+type Decoys struct {
+	Client      *Client
+	Context     context.Context // defaults to context.Background()
+	HeightLimit uint64          // only pick outputs below this height, 0 for no limit
+	HF4Height   uint64          // defaults to zanolib.HF4Height (mainnet)
+}
+
+func (d *Decoys) ctx() context.Context {
+	if d.Context == nil {
+		return context.Background()
+	}
+	return d.Context
+}
+
+// GetDecoys implements zanolib.RingProvider
+func (d *Decoys) GetDecoys(out *zanolib.OwnedOutput, count int) ([]*zanolib.TxSourceOutputEntry, error) {
+	return (&zanolib.DecoySelector{Source: d}).GetDecoys(out, count)
+}
+
+// topBlock returns the details of the last block, including its transactions
+func (d *Decoys) topBlock() (*BlockDetails, error) {
+	info, err := d.Client.GetInfo(d.ctx())
+	if err != nil {
+		return nil, err
+	}
+	if info.Height == 0 {
+		return nil, errors.New("daemon has no blocks")
+	}
+	blocks, err := d.Client.GetBlocksDetails(d.ctx(), info.Height-1, 1, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) != 1 {
+		return nil, errors.New("missing top block in response")
+	}
+	return blocks[0], nil
+}
+
+// OutputCount implements zanolib.OutputIndexSource, using the highest global index among
+// the outputs of the top block
+func (d *Decoys) OutputCount() (uint64, error) {
+	top, err := d.topBlock()
+	if err != nil {
+		return 0, err
+	}
+	var res uint64
+	for _, tx := range top.TransactionsDetails {
+		for _, o := range tx.Outs {
+			if o.Amount == 0 && o.GlobalIndex >= res {
+				res = o.GlobalIndex + 1
+			}
+		}
+	}
+	if res == 0 {
+		return 0, errors.New("top block has no zarcanum output")
+	}
+	return res, nil
+}
+
+// TimeSpan implements zanolib.OutputIndexSource
+func (d *Decoys) TimeSpan() (time.Duration, error) {
+	top, err := d.topBlock()
+	if err != nil {
+		return 0, err
+	}
+	hf4 := d.HF4Height
+	if hf4 == 0 {
+		hf4 = zanolib.HF4Height
+	}
+	first, err := d.Client.GetBlockHeaderByHeight(d.ctx(), hf4)
+	if err != nil {
+		return 0, err
+	}
+	if top.Timestamp < first.Timestamp {
+		return 0, errors.New("top block is older than HF4")
+	}
+	return time.Duration(top.Timestamp-first.Timestamp) * time.Second, nil
+}
+
+// GetOutputs implements zanolib.OutputIndexSource using getrandom_outs3. Outputs omitted
+// by the daemon are returned as nil entries.
+func (d *Decoys) GetOutputs(indices []uint64) ([]*zanolib.TxSourceOutputEntry, error) {
+	outs, err := d.Client.GetRandomOuts(d.ctx(), []*OffsetsDistribution{{Amount: 0, GlobalOffsets: indices}}, d.HeightLimit)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[uint64]*RandomOut)
+	for _, o := range outs[0].Outs {
+		found[o.GlobalAmountIndex] = o
+	}
+	res := make([]*zanolib.TxSourceOutputEntry, len(indices))
+	for n, idx := range indices {
+		o, ok := found[idx]
+		if !ok {
+			continue
+		}
+		e := &zanolib.OwnedOutput{
+			GlobalIndex:      o.GlobalAmountIndex,
+			StealthAddress:   o.StealthAddress,
+			ConcealingPoint:  o.ConcealingPoint,
+			AmountCommitment: o.AmountCommitment,
+			BlindedAssetId:   o.BlindedAssetId,
+		}
+		if res[n], err = e.Entry(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+var (
+	_ zanolib.RingProvider      = (*Decoys)(nil)
+	_ zanolib.OutputIndexSource = (*Decoys)(nil)
+)
@@ -0,0 +1,213 @@
+package zanorpc
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ModChain/zanolib"
+)
+
+// WalletClient is a client for simplewallet's JSON-RPC API, typically a view-only wallet
+// preparing unsigned transactions to be signed with zanolib
+type WalletClient struct {
+	RPC *Client
+}
+
+// NewWallet returns a WalletClient for the wallet RPC at the given base url, for example
+// http://127.0.0.1:11212
+func NewWallet(url string) *WalletClient {
+	return &WalletClient{RPC: New(url)}
+}
+
+// AssetInfo describes an asset in a wallet balance
+type AssetInfo struct {
+	AssetId      string `json:"asset_id"`
+	Ticker       string `json:"ticker"`
+	FullName     string `json:"full_name"`
+	DecimalPoint uint8  `json:"decimal_point"`
+}
+
+// AssetBalance is the balance of a single asset
+type AssetBalance struct {
+	AssetInfo   *AssetInfo `json:"asset_info"`
+	Total       uint64     `json:"total"`
+	Unlocked    uint64     `json:"unlocked"`
+	AwaitingIn  uint64     `json:"awaiting_in"`
+	AwaitingOut uint64     `json:"awaiting_out"`
+}
+
+// Balance is the result of getbalance. Balance and UnlockedBalance are in native coin.
+type Balance struct {
+	Response
+	Balance         uint64          `json:"balance"`
+	UnlockedBalance uint64          `json:"unlocked_balance"`
+	Balances        []*AssetBalance `json:"balances"`
+}
+
+// GetBalance returns the wallet balance
+func (wc *WalletClient) GetBalance(ctx context.Context) (*Balance, error) {
+	res := new(Balance)
+	return res, wc.RPC.Call(ctx, "getbalance", nil, res)
+}
+
+// GetAddress returns the wallet's address
+func (wc *WalletClient) GetAddress(ctx context.Context) (*zanolib.Address, error) {
+	var res struct {
+		Response
+		Address string `json:"address"`
+	}
+	if err := wc.RPC.Call(ctx, "getaddress", nil, &res); err != nil {
+		return nil, err
+	}
+	return zanolib.ParseAddress(res.Address)
+}
+
+// TransferDestination is a destination of a transfer
+type TransferDestination struct {
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+	AssetId string `json:"asset_id,omitempty"` // hex, empty for the native coin
+}
+
+// TransferRequest are the parameters of transfer
+type TransferRequest struct {
+	Destinations []*TransferDestination `json:"destinations"`
+	Fee          uint64                 `json:"fee"`
+	Mixin        uint64                 `json:"mixin"`
+	PaymentId    string                 `json:"payment_id,omitempty"` // hex
+	Comment      string                 `json:"comment,omitempty"`
+	PushPayer    bool                   `json:"push_payer,omitempty"`
+	HideReceiver bool                   `json:"hide_receiver,omitempty"`
+}
+
+// TransferResult is the result of transfer. A view-only wallet does not send the
+// transaction, but returns it unsigned in TxUnsignedHex.
+type TransferResult struct {
+	Response
+	TxHash        string `json:"tx_hash"`
+	TxSize        uint64 `json:"tx_size"`
+	TxUnsignedHex string `json:"tx_unsigned_hex"`
+}
+
+// Transfer asks the wallet to prepare a transaction
+func (wc *WalletClient) Transfer(ctx context.Context, req *TransferRequest) (*TransferResult, error) {
+	res := new(TransferResult)
+	return res, wc.RPC.Call(ctx, "transfer", req, res)
+}
+
+// SubmitTransfer sends a signed transaction blob, as produced by Wallet.Encrypt on a
+// FinalizedTx, through the wallet and returns its hash
+func (wc *WalletClient) SubmitTransfer(ctx context.Context, signed []byte) (string, error) {
+	var res struct {
+		Response
+		TxHash string `json:"tx_hash"`
+	}
+	if err := wc.RPC.Call(ctx, "submit_transfer", map[string]any{"tx_signed_hex": hex.EncodeToString(signed)}, &res); err != nil {
+		return "", err
+	}
+	return res.TxHash, nil
+}
+
+// SubTransfer is the part of a transfer concerning a single asset
+type SubTransfer struct {
+	AssetId  string `json:"asset_id"`
+	Amount   uint64 `json:"amount"`
+	IsIncome bool   `json:"is_income"`
+}
+
+// TransferInfo describes a transaction of the wallet (wallet_transfer_info)
+type TransferInfo struct {
+	TxHash          string         `json:"tx_hash"`
+	Height          uint64         `json:"height"`
+	Timestamp       uint64         `json:"timestamp"`
+	Fee             uint64         `json:"fee"`
+	PaymentId       string         `json:"payment_id"`
+	Comment         string         `json:"comment"`
+	UnlockTime      uint64         `json:"unlock_time"`
+	TxBlobSize      uint64         `json:"tx_blob_size"`
+	TxType          uint64         `json:"tx_type"`
+	IsMining        bool           `json:"is_mining"`
+	RemoteAddresses []string       `json:"remote_addresses"`
+	Subtransfers    []*SubTransfer `json:"subtransfers"`
+}
+
+// RecentTransfers is the result of get_recent_txs_and_info
+type RecentTransfers struct {
+	Response
+	Transfers      []*TransferInfo `json:"transfers"`
+	TotalTransfers uint64          `json:"total_transfers"`
+	LastItemIndex  uint64          `json:"last_item_index"`
+}
+
+// GetRecentTransfers returns up to count transfers starting at offset, most recent first
+func (wc *WalletClient) GetRecentTransfers(ctx context.Context, offset, count uint64) (*RecentTransfers, error) {
+	res := new(RecentTransfers)
+	params := map[string]any{"offset": offset, "count": count, "update_provision_info": true, "order": "FROM_END_TO_BEGIN"}
+	return res, wc.RPC.Call(ctx, "get_recent_txs_and_info", params, res)
+}
+
+// SearchRequest are the parameters of search_for_transactions
+type SearchRequest struct {
+	TxId           string `json:"tx_id,omitempty"` // hex, empty for any
+	In             bool   `json:"in"`
+	Out            bool   `json:"out"`
+	Pool           bool   `json:"pool"`
+	FilterByHeight bool   `json:"filter_by_height"`
+	MinHeight      uint64 `json:"min_height"`
+	MaxHeight      uint64 `json:"max_height"`
+}
+
+// SearchResult is the result of search_for_transactions
+type SearchResult struct {
+	Response
+	In   []*TransferInfo `json:"in"`
+	Out  []*TransferInfo `json:"out"`
+	Pool []*TransferInfo `json:"pool"`
+}
+
+// SearchTransactions looks for transactions of the wallet
+func (wc *WalletClient) SearchTransactions(ctx context.Context, req *SearchRequest) (*SearchResult, error) {
+	res := new(SearchResult)
+	return res, wc.RPC.Call(ctx, "search_for_transactions", req, res)
+}
+
+// SignTransfer decodes the unsigned transaction of a transfer result, signs it with w and
+// returns the encrypted signed blob ready for SubmitTransfer. If check is not nil, it is
+// called before signing and can refuse the transaction by returning an error, for example
+// after evaluating a zanolib.Policy.
+func SignTransfer(rnd io.Reader, w *zanolib.Wallet, res *TransferResult, check func(*zanolib.FinalizeTxParam) error) ([]byte, error) {
+	if res.TxUnsignedHex == "" {
+		return nil, errors.New("transfer result has no unsigned transaction")
+	}
+	buf, err := hex.DecodeString(res.TxUnsignedHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unsigned transaction: %w", err)
+	}
+	ftp, err := w.ParseFTP(buf)
+	if err != nil {
+		return nil, err
+	}
+	if check != nil {
+		if err := check(ftp); err != nil {
+			return nil, err
+		}
+	}
+	finalized, err := w.Sign(rnd, ftp, nil)
+	if err != nil {
+		return nil, err
+	}
+	return w.Encrypt(finalized)
+}
+
+// SignAndSubmit signs the unsigned transaction of res using SignTransfer and submits it
+// back to the wallet, returning the transaction hash
+func (wc *WalletClient) SignAndSubmit(ctx context.Context, rnd io.Reader, w *zanolib.Wallet, res *TransferResult, check func(*zanolib.FinalizeTxParam) error) (string, error) {
+	signed, err := SignTransfer(rnd, w, res, check)
+	if err != nil {
+		return "", err
+	}
+	return wc.SubmitTransfer(ctx, signed)
+}
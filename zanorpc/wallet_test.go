@@ -0,0 +1,124 @@
+package zanorpc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+	"github.com/ModChain/zanolib/zanorpc"
+)
+
+// testUnsigned returns an encrypted unsigned transaction spending an output of 1000
+// received by w in a transaction with secret key 7
+func testUnsigned(t *testing.T, w *zanolib.Wallet) []byte {
+	txKey := new(edwards25519.Point).ScalarBaseMult(zanocrypto.ScalarInt(7))
+	derivation, err := zanocrypto.GenerateKeyDerivation(txKey, w.ViewPrivKey)
+	if err != nil {
+		t.Fatalf("failed to derive: %s", err)
+	}
+	stealth, err := zanocrypto.DerivePublicKey(derivation.Bytes(), 0, w.SpendPubKey)
+	if err != nil {
+		t.Fatalf("failed to derive: %s", err)
+	}
+	blindedAssetId := new(edwards25519.Point).Add(zanocrypto.NativeCoinAssetIdPt, new(edwards25519.Point).ScalarMult(zanocrypto.ScalarInt(6), zanocrypto.C_point_X))
+	commitment := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(zanocrypto.ScalarInt(1000), blindedAssetId, zanocrypto.ScalarInt(5))
+	div8 := func(p *edwards25519.Point) zanobase.Value256 {
+		return zanobase.Value256(new(edwards25519.Point).ScalarMult(zanocrypto.Sc1div8, p).Bytes())
+	}
+
+	b := &zanolib.TxBuilder{
+		Wallet: w,
+		Outputs: []*zanolib.OwnedOutput{{
+			GlobalIndex:         1234,
+			TxPubKey:            zanobase.Value256(txKey.Bytes()),
+			Amount:              1000,
+			AssetId:             zanolib.NativeCoinAssetId,
+			StealthAddress:      zanobase.Value256(stealth.Bytes()),
+			ConcealingPoint:     div8(txKey),
+			AmountCommitment:    div8(commitment),
+			BlindedAssetId:      div8(blindedAssetId),
+			AmountBlindingMask:  &zanobase.Scalar{zanocrypto.ScalarInt(5)},
+			AssetIdBlindingMask: &zanobase.Scalar{zanocrypto.ScalarInt(6)},
+		}},
+		RingSize:     1,
+		Destinations: []*zanolib.Destination{{Address: w.Address(), Amount: 600}},
+		Fee:          10,
+	}
+	ftp, err := b.Build()
+	if err != nil {
+		t.Fatalf("failed to build: %s", err)
+	}
+	buf, err := w.Encrypt(ftp)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %s", err)
+	}
+	return buf
+}
+
+func TestWalletSignAndSubmit(t *testing.T) {
+	secret := make([]byte, 32)
+	secret[0] = 1
+	w, err := zanolib.LoadSpendSecret(secret, 0)
+	if err != nil {
+		t.Fatalf("failed to load wallet: %s", err)
+	}
+	unsigned := testUnsigned(t, w)
+
+	var submitted []byte
+	c := fakeDaemon(t, func(method string, params map[string]any) any {
+		switch method {
+		case "getaddress":
+			return map[string]any{"address": w.Address().String()}
+		case "transfer":
+			return map[string]any{"tx_unsigned_hex": hex.EncodeToString(unsigned)}
+		case "submit_transfer":
+			submitted, _ = hex.DecodeString(params["tx_signed_hex"].(string))
+			return map[string]any{"tx_hash": "abcd"}
+		}
+		return &zanorpc.Error{Code: -32601, Message: "method not found"}
+	})
+	wc := &zanorpc.WalletClient{RPC: c}
+	ctx := context.Background()
+
+	addr, err := wc.GetAddress(ctx)
+	if err != nil {
+		t.Fatalf("getaddress failed: %s", err)
+	}
+	if !addr.SameKeys(w.Address()) {
+		t.Errorf("unexpected address %s", addr)
+	}
+
+	res, err := wc.Transfer(ctx, &zanorpc.TransferRequest{Destinations: []*zanorpc.TransferDestination{{Address: addr.String(), Amount: 600}}})
+	if err != nil {
+		t.Fatalf("transfer failed: %s", err)
+	}
+
+	refused := errors.New("refused")
+	if _, err := wc.SignAndSubmit(ctx, rand.Reader, w, res, func(*zanolib.FinalizeTxParam) error { return refused }); !errors.Is(err, refused) {
+		t.Errorf("check should have refused the transaction, got %v", err)
+	}
+	if submitted != nil {
+		t.Fatalf("refused transaction was submitted")
+	}
+
+	hash, err := wc.SignAndSubmit(ctx, rand.Reader, w, res, nil)
+	if err != nil {
+		t.Fatalf("sign and submit failed: %s", err)
+	}
+	if hash != "abcd" {
+		t.Errorf("unexpected hash %s", hash)
+	}
+	finalized, err := w.ParseFinalized(submitted)
+	if err != nil {
+		t.Fatalf("submitted blob is not a finalized transaction: %s", err)
+	}
+	if len(finalized.Tx.Signatures) != 1 {
+		t.Errorf("expected one signature, got %d", len(finalized.Tx.Signatures))
+	}
+}
@@ -0,0 +1,399 @@
+// Package zanosigner implements an HTTP signing service for unsigned transactions
+// produced by a view-only simplewallet. Submitted transactions are decoded and
+// summarized, then signed once enough operators approved them.
+package zanosigner
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ModChain/zanolib"
+)
+
+// MaxRequestSize is the maximum size of a submitted unsigned transaction
+const MaxRequestSize = 16 << 20
+
+const (
+	DefaultMaxRequests = 1000               // default value for Server.MaxRequests
+	DefaultRequestTTL  = 7 * 24 * time.Hour // default value for Server.RequestTTL
+)
+
+// Status is the status of a signing request
+type Status string
+
+const (
+	StatusPending  Status = "pending"  // waiting for approvals
+	StatusSigned   Status = "signed"   // signed, the signed blob can be fetched
+	StatusRejected Status = "rejected" // rejected by an operator or the policy
+	StatusFailed   Status = "failed"   // approved, but signing failed
+)
+
+// Request is a submitted unsigned transaction and its state
+type Request struct {
+	Id         string                  `json:"id"`
+	Status     Status                  `json:"status"`
+	Created    time.Time               `json:"created"`
+	Submitter  string                  `json:"submitter"`
+	Summary    *zanolib.FTPSummary     `json:"summary"`
+	Policy     *zanolib.PolicyDecision `json:"policy,omitempty"`
+	Approvals  []string                `json:"approvals"` // names of operators who approved
+	RejectedBy string                  `json:"rejected_by,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+	TxId       string                  `json:"txid,omitempty"`
+
+	ftp    *zanolib.FinalizeTxParam
+	signed []byte
+}
+
+// Server is an http.Handler exposing the signing service:
+//
+//	POST /requests                submit an unsigned transaction (raw body)
+//	GET  /requests                list requests
+//	GET  /requests/{id}           view a request and its summary
+//	POST /requests/{id}/approve   approve a request (operators only)
+//	POST /requests/{id}/reject    reject a request (operators only)
+//	GET  /requests/{id}/signed    fetch the encrypted signed transaction
+//
+// Clients authenticate with a bearer token. Requests are kept in memory, see MaxRequests
+// and RequestTTL.
+type Server struct {
+	Wallet *zanolib.Wallet
+
+	// Operators maps api tokens to operator names. Operators can approve and reject
+	// requests, as well as anything submitters can do.
+	Operators map[string]string
+	// Submitters maps api tokens to names of clients allowed to submit and fetch
+	// requests, typically the host running the view-only wallet.
+	Submitters map[string]string
+	// RequiredApprovals is the number of distinct operators that must approve a request
+	// before it gets signed. If zero, requests allowed by Policy are signed immediately.
+	RequiredApprovals int
+	// Policy, if set, is evaluated on submission. Requests it denies are rejected.
+	Policy *zanolib.Policy
+	// MaxRequests is the maximum number of requests kept in memory. When reached, the
+	// oldest signed, rejected or failed request is dropped, and submissions are refused if
+	// all requests are pending. Defaults to DefaultMaxRequests.
+	MaxRequests int
+	// RequestTTL is how long requests are kept, whatever their status. Defaults to
+	// DefaultRequestTTL.
+	RequestTTL time.Duration
+
+	Rand io.Reader        // defaults to crypto/rand.Reader
+	Now  func() time.Time // defaults to time.Now
+
+	mu       sync.Mutex
+	requests map[string]*Request
+	mux      *http.ServeMux
+	once     sync.Once
+}
+
+// New returns a Server signing with w
+func New(w *zanolib.Wallet) *Server {
+	return &Server{Wallet: w}
+}
+
+func (s *Server) init() {
+	s.requests = make(map[string]*Request)
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST /requests", s.auth(false, s.submit))
+	s.mux.HandleFunc("GET /requests", s.auth(false, s.list))
+	s.mux.HandleFunc("GET /requests/{id}", s.auth(false, s.get))
+	s.mux.HandleFunc("POST /requests/{id}/approve", s.auth(true, s.approve))
+	s.mux.HandleFunc("POST /requests/{id}/reject", s.auth(true, s.reject))
+	s.mux.HandleFunc("GET /requests/{id}/signed", s.auth(false, s.getSigned))
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.once.Do(s.init)
+	s.mux.ServeHTTP(rw, req)
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Server) rand() io.Reader {
+	if s.Rand != nil {
+		return s.Rand
+	}
+	return rand.Reader
+}
+
+type handlerFunc func(rw http.ResponseWriter, req *http.Request, name string)
+
+// auth checks the bearer token of the request, and passes the name of the caller to h
+func (s *Server) auth(operator bool, h handlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeError(rw, http.StatusUnauthorized, errors.New("missing bearer token"))
+			return
+		}
+		name, ok := lookupToken(s.Operators, token)
+		if !ok {
+			var submitter bool
+			name, submitter = lookupToken(s.Submitters, token)
+			switch {
+			case !submitter:
+				writeError(rw, http.StatusUnauthorized, errors.New("invalid token"))
+				return
+			case operator:
+				writeError(rw, http.StatusForbidden, errors.New("operator token required"))
+				return
+			}
+		}
+		h(rw, req, name)
+	}
+}
+
+// lookupToken finds token in tokens in constant time relative to the token values
+func lookupToken(tokens map[string]string, token string) (string, bool) {
+	var res string
+	found := false
+	for t, name := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			res, found = name, true
+		}
+	}
+	return res, found
+}
+
+func (s *Server) submit(rw http.ResponseWriter, req *http.Request, name string) {
+	buf, err := io.ReadAll(http.MaxBytesReader(rw, req.Body, MaxRequestSize))
+	if err != nil {
+		writeError(rw, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	if s.RequiredApprovals <= 0 && s.Policy == nil {
+		// never sign without any check
+		writeError(rw, http.StatusInternalServerError, errors.New("server requires either approvals or a policy"))
+		return
+	}
+	ftp, err := s.Wallet.ParseFTP(buf)
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, fmt.Errorf("invalid unsigned transaction: %w", err))
+		return
+	}
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		writeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+	r := &Request{
+		Id:        hex.EncodeToString(id),
+		Status:    StatusPending,
+		Created:   s.now(),
+		Submitter: name,
+		Summary:   ftp.Summary(s.Wallet),
+		Approvals: []string{},
+		ftp:       ftp,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.prune(r.Created) {
+		writeError(rw, http.StatusServiceUnavailable, errors.New("too many pending requests"))
+		return
+	}
+
+	if s.Policy != nil {
+		if r.Policy, err = s.Policy.Evaluate(s.Wallet, ftp, r.Created); err != nil {
+			writeError(rw, http.StatusInternalServerError, fmt.Errorf("while evaluating policy: %w", err))
+			return
+		}
+		if !r.Policy.Allowed {
+			r.Status = StatusRejected
+			r.RejectedBy = "policy"
+			r.ftp = nil
+		}
+	}
+	if r.Status == StatusPending && s.RequiredApprovals <= 0 {
+		s.sign(r)
+	}
+	s.requests[r.Id] = r
+	writeJSON(rw, http.StatusCreated, r)
+}
+
+// prune drops expired requests and makes room for a new one, s.mu must be held. It
+// returns false if the limit is reached with only pending requests.
+func (s *Server) prune(now time.Time) bool {
+	ttl := s.RequestTTL
+	if ttl <= 0 {
+		ttl = DefaultRequestTTL
+	}
+	max := s.MaxRequests
+	if max <= 0 {
+		max = DefaultMaxRequests
+	}
+	var oldest *Request
+	for id, r := range s.requests {
+		if now.Sub(r.Created) > ttl {
+			delete(s.requests, id)
+			continue
+		}
+		if r.Status != StatusPending && (oldest == nil || r.Created.Before(oldest.Created)) {
+			oldest = r
+		}
+	}
+	if len(s.requests) < max {
+		return true
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(s.requests, oldest.Id)
+	return true
+}
+
+func (s *Server) list(rw http.ResponseWriter, req *http.Request, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]*Request, 0, len(s.requests))
+	for _, r := range s.requests {
+		res = append(res, r)
+	}
+	slices.SortFunc(res, func(a, b *Request) int {
+		return a.Created.Compare(b.Created)
+	})
+	writeJSON(rw, http.StatusOK, res)
+}
+
+// request returns the request with the id found in the url, and locks s
+func (s *Server) request(rw http.ResponseWriter, req *http.Request) (*Request, bool) {
+	s.mu.Lock()
+	r, ok := s.requests[req.PathValue("id")]
+	if !ok {
+		s.mu.Unlock()
+		writeError(rw, http.StatusNotFound, errors.New("request not found"))
+	}
+	return r, ok
+}
+
+func (s *Server) get(rw http.ResponseWriter, req *http.Request, name string) {
+	r, ok := s.request(rw, req)
+	if !ok {
+		return
+	}
+	defer s.mu.Unlock()
+	writeJSON(rw, http.StatusOK, r)
+}
+
+func (s *Server) approve(rw http.ResponseWriter, req *http.Request, name string) {
+	r, ok := s.request(rw, req)
+	if !ok {
+		return
+	}
+	defer s.mu.Unlock()
+	if r.Status != StatusPending {
+		writeError(rw, http.StatusConflict, fmt.Errorf("request is %s", r.Status))
+		return
+	}
+	if slices.Contains(r.Approvals, name) {
+		writeError(rw, http.StatusConflict, errors.New("already approved by this operator"))
+		return
+	}
+	r.Approvals = append(r.Approvals, name)
+	if len(r.Approvals) >= s.RequiredApprovals {
+		s.sign(r)
+	}
+	writeJSON(rw, http.StatusOK, r)
+}
+
+func (s *Server) reject(rw http.ResponseWriter, req *http.Request, name string) {
+	r, ok := s.request(rw, req)
+	if !ok {
+		return
+	}
+	defer s.mu.Unlock()
+	if r.Status != StatusPending {
+		writeError(rw, http.StatusConflict, fmt.Errorf("request is %s", r.Status))
+		return
+	}
+	r.Status = StatusRejected
+	r.RejectedBy = name
+	r.ftp = nil
+	writeJSON(rw, http.StatusOK, r)
+}
+
+func (s *Server) getSigned(rw http.ResponseWriter, req *http.Request, name string) {
+	r, ok := s.request(rw, req)
+	if !ok {
+		return
+	}
+	defer s.mu.Unlock()
+	if r.Status != StatusSigned {
+		writeError(rw, http.StatusConflict, fmt.Errorf("request is %s", r.Status))
+		return
+	}
+	rw.Header().Set("Content-Type", "application/octet-stream")
+	rw.Write(r.signed)
+}
+
+// sign signs an approved request, s.mu must be held
+func (s *Server) sign(r *Request) {
+	err := s.signRequest(r)
+	if err != nil {
+		r.Status = StatusFailed
+		r.Error = err.Error()
+	}
+	r.ftp = nil
+}
+
+func (s *Server) signRequest(r *Request) error {
+	if s.Policy != nil {
+		// evaluate again as the ledger may have changed since submission
+		decision, err := s.Policy.Evaluate(s.Wallet, r.ftp, s.now())
+		if err != nil {
+			return fmt.Errorf("while evaluating policy: %w", err)
+		}
+		if r.Policy = decision; !decision.Allowed {
+			return errors.New("transaction denied by policy")
+		}
+	}
+	finalized, err := s.Wallet.Sign(s.rand(), r.ftp, nil)
+	if err != nil {
+		return fmt.Errorf("failed to sign: %w", err)
+	}
+	signed, err := s.Wallet.Encrypt(finalized)
+	if err != nil {
+		return err
+	}
+	txId, err := finalized.Tx.Hash()
+	if err != nil {
+		return err
+	}
+	if s.Policy != nil {
+		if err := s.Policy.Record(s.Wallet, r.ftp, s.now()); err != nil {
+			return fmt.Errorf("while recording spend: %w", err)
+		}
+	}
+	r.Status = StatusSigned
+	r.TxId = hex.EncodeToString(txId)
+	r.signed = signed
+	return nil
+}
+
+func writeJSON(rw http.ResponseWriter, code int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	json.NewEncoder(rw).Encode(v)
+}
+
+func writeError(rw http.ResponseWriter, code int, err error) {
+	writeJSON(rw, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,226 @@
+package zanosigner_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"filippo.io/edwards25519"
+	"github.com/ModChain/zanolib"
+	"github.com/ModChain/zanolib/zanobase"
+	"github.com/ModChain/zanolib/zanocrypto"
+	"github.com/ModChain/zanolib/zanosigner"
+)
+
+// testUnsigned returns an encrypted unsigned transaction spending an output of w
+func testUnsigned(t *testing.T, w *zanolib.Wallet, amount uint64) []byte {
+	txKey := new(edwards25519.Point).ScalarBaseMult(zanocrypto.ScalarInt(7))
+	derivation, err := zanocrypto.GenerateKeyDerivation(txKey, w.ViewPrivKey)
+	if err != nil {
+		t.Fatalf("failed to derive: %s", err)
+	}
+	stealth, err := zanocrypto.DerivePublicKey(derivation.Bytes(), 0, w.SpendPubKey)
+	if err != nil {
+		t.Fatalf("failed to derive: %s", err)
+	}
+	blindedAssetId := new(edwards25519.Point).Add(zanocrypto.NativeCoinAssetIdPt, new(edwards25519.Point).ScalarMult(zanocrypto.ScalarInt(6), zanocrypto.C_point_X))
+	commitment := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(zanocrypto.ScalarInt(1000), blindedAssetId, zanocrypto.ScalarInt(5))
+	div8 := func(p *edwards25519.Point) zanobase.Value256 {
+		return zanobase.Value256(new(edwards25519.Point).ScalarMult(zanocrypto.Sc1div8, p).Bytes())
+	}
+	other, err := zanolib.ParseAddress("ZxD5aoLDPTdcaRx4uCpyW4XiLfEXejepAVz8cSY2fwHNEiJNu6NmpBBDLGTJzCsUvn3acCVDVDPMV8yQXdPooAp338Se7AxeH")
+	if err != nil {
+		t.Fatalf("failed to parse address: %s", err)
+	}
+
+	b := &zanolib.TxBuilder{
+		Wallet: w,
+		Outputs: []*zanolib.OwnedOutput{{
+			GlobalIndex:         1234,
+			TxPubKey:            zanobase.Value256(txKey.Bytes()),
+			Amount:              1000,
+			AssetId:             zanolib.NativeCoinAssetId,
+			StealthAddress:      zanobase.Value256(stealth.Bytes()),
+			ConcealingPoint:     div8(txKey),
+			AmountCommitment:    div8(commitment),
+			BlindedAssetId:      div8(blindedAssetId),
+			AmountBlindingMask:  &zanobase.Scalar{zanocrypto.ScalarInt(5)},
+			AssetIdBlindingMask: &zanobase.Scalar{zanocrypto.ScalarInt(6)},
+		}},
+		RingSize:     1,
+		Destinations: []*zanolib.Destination{{Address: other, Amount: amount}},
+		Fee:          10,
+	}
+	ftp, err := b.Build()
+	if err != nil {
+		t.Fatalf("failed to build: %s", err)
+	}
+	buf, err := w.Encrypt(ftp)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %s", err)
+	}
+	return buf
+}
+
+func testWallet(t *testing.T) *zanolib.Wallet {
+	secret := make([]byte, 32)
+	secret[0] = 1
+	w, err := zanolib.LoadSpendSecret(secret, 0)
+	if err != nil {
+		t.Fatalf("failed to load wallet: %s", err)
+	}
+	return w
+}
+
+// do performs a request against h and decodes the json response into res if not nil
+func do(t *testing.T, h http.Handler, method, path, token string, body []byte, res any) int {
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if res != nil && rec.Code < 300 {
+		if err := json.NewDecoder(rec.Body).Decode(res); err != nil {
+			t.Fatalf("%s %s: invalid response: %s", method, path, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestApprovals(t *testing.T) {
+	w := testWallet(t)
+	s := zanosigner.New(w)
+	s.Operators = map[string]string{"tok-alice": "alice", "tok-bob": "bob", "tok-carol": "carol"}
+	s.Submitters = map[string]string{"tok-wallet": "wallet"}
+	s.RequiredApprovals = 2
+
+	if code := do(t, s, "POST", "/requests", "", testUnsigned(t, w, 600), nil); code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated submit returned %d", code)
+	}
+	if code := do(t, s, "POST", "/requests", "tok-wallet", []byte("garbage"), nil); code != http.StatusBadRequest {
+		t.Errorf("invalid submit returned %d", code)
+	}
+
+	var r zanosigner.Request
+	if code := do(t, s, "POST", "/requests", "tok-wallet", testUnsigned(t, w, 600), &r); code != http.StatusCreated {
+		t.Fatalf("submit returned %d", code)
+	}
+	if r.Status != zanosigner.StatusPending || r.Summary == nil || r.Summary.Sent[zanolib.NativeCoinAssetId] != 600 {
+		t.Errorf("unexpected request %+v", r)
+	}
+	base := "/requests/" + r.Id
+
+	if code := do(t, s, "POST", base+"/approve", "tok-wallet", nil, nil); code != http.StatusForbidden {
+		t.Errorf("submitter approval returned %d", code)
+	}
+	if code := do(t, s, "GET", base+"/signed", "tok-wallet", nil, nil); code != http.StatusConflict {
+		t.Errorf("fetching unsigned request returned %d", code)
+	}
+	if code := do(t, s, "POST", base+"/approve", "tok-alice", nil, &r); code != http.StatusOK || r.Status != zanosigner.StatusPending {
+		t.Errorf("first approval returned %d, status %s", code, r.Status)
+	}
+	if code := do(t, s, "POST", base+"/approve", "tok-alice", nil, nil); code != http.StatusConflict {
+		t.Errorf("duplicate approval returned %d", code)
+	}
+	if code := do(t, s, "POST", base+"/approve", "tok-bob", nil, &r); code != http.StatusOK || r.Status != zanosigner.StatusSigned {
+		t.Fatalf("second approval returned %d, status %s (%s)", code, r.Status, r.Error)
+	}
+
+	req := httptest.NewRequest("GET", base+"/signed", nil)
+	req.Header.Set("Authorization", "Bearer tok-wallet")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	signed, _ := io.ReadAll(rec.Body)
+	finalized, err := w.ParseFinalized(signed)
+	if err != nil {
+		t.Fatalf("failed to parse signed blob: %s", err)
+	}
+	if txId, _ := finalized.Tx.Hash(); r.TxId != hex.EncodeToString(txId) {
+		t.Errorf("unexpected txid %s", r.TxId)
+	}
+
+	// rejection
+	var r2 zanosigner.Request
+	do(t, s, "POST", "/requests", "tok-wallet", testUnsigned(t, w, 500), &r2)
+	if code := do(t, s, "POST", "/requests/"+r2.Id+"/reject", "tok-carol", nil, &r2); code != http.StatusOK || r2.Status != zanosigner.StatusRejected || r2.RejectedBy != "carol" {
+		t.Errorf("reject returned %d, %+v", code, r2)
+	}
+	if code := do(t, s, "POST", "/requests/"+r2.Id+"/approve", "tok-alice", nil, nil); code != http.StatusConflict {
+		t.Errorf("approving a rejected request returned %d", code)
+	}
+
+	var list []*zanosigner.Request
+	if code := do(t, s, "GET", "/requests", "tok-wallet", nil, &list); code != http.StatusOK || len(list) != 2 {
+		t.Errorf("list returned %d with %d requests", code, len(list))
+	}
+	if code := do(t, s, "GET", "/requests/unknown", "tok-wallet", nil, nil); code != http.StatusNotFound {
+		t.Errorf("unknown request returned %d", code)
+	}
+}
+
+func TestPolicyOnly(t *testing.T) {
+	w := testWallet(t)
+	s := zanosigner.New(w)
+	s.Submitters = map[string]string{"tok-wallet": "wallet"}
+	s.Policy = &zanolib.Policy{MaxAmount: map[string]uint64{zanolib.NativeCoinAssetId: 700}}
+
+	var r zanosigner.Request
+	do(t, s, "POST", "/requests", "tok-wallet", testUnsigned(t, w, 600), &r)
+	if r.Status != zanosigner.StatusSigned {
+		t.Errorf("request allowed by policy has status %s (%s)", r.Status, r.Error)
+	}
+	do(t, s, "POST", "/requests", "tok-wallet", testUnsigned(t, w, 800), &r)
+	if r.Status != zanosigner.StatusRejected || r.RejectedBy != "policy" || r.Policy == nil || len(r.Policy.Reasons) == 0 {
+		t.Errorf("request denied by policy has status %s", r.Status)
+	}
+
+	s2 := zanosigner.New(w)
+	s2.Submitters = s.Submitters
+	if code := do(t, s2, "POST", "/requests", "tok-wallet", testUnsigned(t, w, 600), nil); code != http.StatusInternalServerError {
+		t.Errorf("server without approvals nor policy returned %d", code)
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	w := testWallet(t)
+	now := time.Unix(1700000000, 0)
+	s := zanosigner.New(w)
+	s.Operators = map[string]string{"tok-alice": "alice"}
+	s.Submitters = map[string]string{"tok-wallet": "wallet"}
+	s.RequiredApprovals = 1
+	s.MaxRequests = 2
+	s.RequestTTL = time.Hour
+	s.Now = func() time.Time { return now }
+
+	var r1, r2 zanosigner.Request
+	do(t, s, "POST", "/requests", "tok-wallet", testUnsigned(t, w, 600), &r1)
+	now = now.Add(time.Minute)
+	do(t, s, "POST", "/requests", "tok-wallet", testUnsigned(t, w, 500), &r2)
+	now = now.Add(time.Minute)
+	if code := do(t, s, "POST", "/requests", "tok-wallet", testUnsigned(t, w, 400), nil); code != http.StatusServiceUnavailable {
+		t.Errorf("submit with only pending requests returned %d", code)
+	}
+
+	// a finished request makes room for a new one
+	do(t, s, "POST", "/requests/"+r1.Id+"/reject", "tok-alice", nil, nil)
+	if code := do(t, s, "POST", "/requests", "tok-wallet", testUnsigned(t, w, 400), nil); code != http.StatusCreated {
+		t.Errorf("submit after rejection returned %d", code)
+	}
+	if code := do(t, s, "GET", "/requests/"+r1.Id, "tok-wallet", nil, nil); code != http.StatusNotFound {
+		t.Errorf("oldest finished request was not dropped, got %d", code)
+	}
+
+	// expired requests are dropped
+	now = now.Add(2 * time.Hour)
+	do(t, s, "POST", "/requests", "tok-wallet", testUnsigned(t, w, 300), nil)
+	var list []*zanosigner.Request
+	if do(t, s, "GET", "/requests", "tok-wallet", nil, &list); len(list) != 1 {
+		t.Errorf("expected 1 request after expiration, got %d", len(list))
+	}
+}